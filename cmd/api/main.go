@@ -1,46 +1,204 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/mmarias/golearn/cmd/gateway_consumer"
+	"github.com/mmarias/golearn/cmd/metrics_consumer"
 	"github.com/mmarias/golearn/cmd/notification_consumer"
 	"github.com/mmarias/golearn/cmd/orchestrator_consumer"
 	"github.com/mmarias/golearn/cmd/payment_consumer"
+	"github.com/mmarias/golearn/cmd/refund_consumer"
 	"github.com/mmarias/golearn/cmd/wallet_consumer"
+	gateway "github.com/mmarias/golearn/internal/app/gateway/v1"
+	metrics "github.com/mmarias/golearn/internal/app/metrics/v1"
+	orchestrator "github.com/mmarias/golearn/internal/app/orchestrator/v1"
 	v1 "github.com/mmarias/golearn/internal/app/payment/v1"
+	refund "github.com/mmarias/golearn/internal/app/refund/v1"
+	wallet "github.com/mmarias/golearn/internal/app/wallet/v1"
+	"github.com/mmarias/golearn/internal/domain"
+	eventbusEntrypoint "github.com/mmarias/golearn/internal/entrypoint/eventbus"
 	entrypoint "github.com/mmarias/golearn/internal/entrypoint/http"
 	"github.com/mmarias/golearn/internal/infraestructure/database"
 	"github.com/mmarias/golearn/internal/infraestructure/eventbus"
-	"github.com/mmarias/golearn/internal/infraestructure/memcache"
+	"github.com/mmarias/golearn/internal/infraestructure/fx"
+	"github.com/mmarias/golearn/internal/infraestructure/idempotency"
 	"github.com/mmarias/golearn/internal/infraestructure/publisher"
+	"github.com/mmarias/golearn/internal/infraestructure/schema"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
 	// load dependencies
-	cache := memcache.NewCache(5 * time.Second)
-	paymentRepository := database.NewPaymentRepository()
+	db, err := database.Open("data/db.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Every message claims and completes a processed key, so they are kept
+	// apart: a commit rewrites its whole file, and the payments file would be
+	// rewritten twice per message.
+	processedDB, err := database.Open("data/processed.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	idempotencyStore := idempotency.NewStore(db, 24*time.Hour)
+	paymentRepository := database.NewPaymentRepository(db)
+	sagaStateRepository := database.NewSagaStateRepository(db)
+	outboxRepository := database.NewOutboxRepository(db)
+	walletRepository := database.NewWalletRepository(db)
+	refundRepository := database.NewRefundRepository(db)
+	refundablePaymentRepository := database.NewRefundablePaymentRepository(db)
+	refundSagaStateRepository := database.NewRefundSagaStateRepository(db)
 
-	bus := eventbus.New()
-	publisher := publisher.New(bus)
+	walletService := wallet.NewWalletService(walletRepository)
+	if err := seedWallets(context.Background(), walletService); err != nil {
+		log.Fatal(err)
+	}
 
-	orchestrator_consumer.Setup(bus)
-	gateway_consumer.Setup(bus)
-	notification_consumer.Setup(bus)
-	wallet_consumer.Setup(bus)
-	payment_consumer.Setup(bus)
+	bus, err := eventbus.NewLogBus(eventbus.LogBusConfig{Dir: "data/eventbus"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer bus.Close()
 
-	paymentCreateService := v1.NewCreatePaymentUseCase(paymentRepository, publisher)
+	schemas, err := schema.NewEventRegistry()
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	paymentHandler := entrypoint.NewPaymentHandler(paymentCreateService, cache)
+	// Every service goes through the topology, which only publishes registered
+	// topics and checks the subscriptions once all consumers are set up, and the
+	// schema guard, which validates and upcasts the events.
+	topology := eventbusEntrypoint.NewTopology(bus)
+	events := eventbusEntrypoint.NewSchemaGuard(topology, schemas)
+	publisher := publisher.New(events)
+	dedup := eventbusEntrypoint.NewDeduplicator(database.NewProcessedMessageRepository(processedDB, 24*time.Hour))
+
+	// The metrics consumer aggregates the metric events of every service into
+	// this registry, served on /metrics with the runtime metrics of the process.
+	metricsRegistry := prometheus.NewRegistry()
+	metricsRegistry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	// Every payment method is served by a simulated provider, whose decline
+	// rules, latency and error rate are set in the file.
+	paymentProviders, err := gateway.LoadSimulatedProviders("config/payment_providers.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	sagaSweeper := orchestrator_consumer.Setup(events, sagaStateRepository, refundSagaStateRepository, dedup)
+	gateway_consumer.Setup(events, paymentProviders, dedup)
+	notification_consumer.Setup(events, dedup)
+	wallet_consumer.Setup(events, walletService, dedup)
+	payment_consumer.Setup(events, paymentRepository, dedup)
+	refund_consumer.Setup(events, refundablePaymentRepository, refundRepository, orchestrator.NewUpdatePaymentStatusCommand(publisher), dedup)
+	metrics_consumer.Setup(events, metrics.NewMetricsService(metricsRegistry), dedup)
+	if err := topology.Check(); err != nil {
+		log.Fatal(err)
+	}
+
+	rates, err := fx.NewStaticRateProvider("config/fx_rates.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	paymentCreateService := v1.NewCreatePaymentUseCase(paymentRepository, walletRepository, rates)
+
+	paymentGetService := v1.NewGetPaymentUseCase(paymentRepository, sagaStateRepository)
+	paymentListService := v1.NewListPaymentsUseCase(paymentRepository)
+	paymentCancelService := v1.NewCancelPaymentUseCase(paymentRepository, sagaStateRepository, outboxRepository)
+
+	paymentHandler := entrypoint.NewPaymentHandler(paymentCreateService, paymentGetService, paymentListService, paymentCancelService, idempotencyStore)
+
+	walletHandler := entrypoint.NewWalletHandler(walletService)
+
+	refundCreateService := refund.NewCreateRefundUseCase(refundablePaymentRepository, refundRepository)
+	refundGetService := refund.NewGetRefundUseCase(refundRepository)
+	refundHandler := entrypoint.NewRefundHandler(refundCreateService, refundGetService, idempotencyStore)
+
+	// Providers sign their callbacks with a secret shared out of band. The
+	// seen callbacks are kept with the processed messages for longer than
+	// gateway.CallbackTolerance, so a replay within it is dropped by every
+	// instance and across restarts.
+	gatewayCallbackService := gateway.NewHandleCallbackUseCase(map[string]gateway.CallbackProvider{
+		gateway.SimulatedProviderName: {Secret: callbackSecret(gateway.SimulatedProviderName), Parse: gateway.ParseSimulatedCallback},
+	}, paymentRepository, refundRepository, database.NewProcessedMessageRepository(processedDB, time.Hour), publisher)
+	gatewayCallbackHandler := entrypoint.NewGatewayCallbackHandler(gatewayCallbackService)
 
 	mux := http.NewServeMux()
-	entrypoint.RegisterRoutes(mux, paymentHandler)
+	metricsHandler := promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+	entrypoint.RegisterRoutes(mux, paymentHandler, walletHandler, refundHandler, gatewayCallbackHandler, metricsHandler)
+
+	server := &http.Server{Addr: ":8080", Handler: mux}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	outboxRelay := v1.NewOutboxRelay(outboxRepository, publisher, 100*time.Millisecond)
+	go outboxRelay.Run(ctx)
+	go sagaSweeper.Run(ctx)
+
+	go func() {
+		<-ctx.Done()
+		// Stop accepting requests; the deferred bus.Close lets consumers commit their offsets.
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
 
 	log.Println("Starting server on port 8080")
-	if err := http.ListenAndServe(":8080", mux); err != nil {
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal(err)
 	}
 }
+
+// callbackSecret reads the callback secret of provider from
+// GATEWAY_CALLBACK_SECRET_<PROVIDER>. There is no default: the development
+// secret lives in config/local.env.
+func callbackSecret(provider string) []byte {
+	env := "GATEWAY_CALLBACK_SECRET_" + strings.ToUpper(provider)
+	secret := os.Getenv(env)
+	if secret == "" {
+		log.Fatalf("%s is not set", env)
+	}
+	return []byte(secret)
+}
+
+type walletSeeder interface {
+	Open(ctx context.Context, walletId, currency string) (domain.Wallet, error)
+	Credit(ctx context.Context, walletId string, amount domain.Money, reference string) (domain.Wallet, error)
+}
+
+// seedWallets opens the demo wallets with an initial balance. The seed credit
+// is keyed by wallet, so restarts do not add funds again.
+func seedWallets(ctx context.Context, wallets walletSeeder) error {
+	for _, seed := range []struct {
+		walletId string
+		balance  domain.Money
+	}{
+		{walletId: "wal-1234", balance: domain.NewMoney(10000_00, "USD")},
+		// Accounts used by the stress scripts in cmd/scripts.
+		{walletId: "1", balance: domain.NewMoney(1000_00, "USD")},
+		{walletId: "2", balance: domain.NewMoney(0, "USD")},
+	} {
+		if _, err := wallets.Open(ctx, seed.walletId, seed.balance.Currency); err != nil {
+			return err
+		}
+		if seed.balance.IsZero() {
+			continue
+		}
+		if _, err := wallets.Credit(ctx, seed.walletId, seed.balance, "seed."+seed.walletId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mmarias/golearn/internal/infraestructure/eventbus"
+)
+
+const usage = `Inspect and resolve dead letters of the event bus.
+
+Usage:
+  go run ./cmd/dlq [-dir data/eventbus] <command> [args]
+
+Commands:
+  topics              list the dead letter topics
+  list [dlq-topic]    list unresolved dead letters, optionally of one topic
+  show <id>           print a dead letter with its payload
+  replay <id>         redeliver the payload to the subscriber that failed
+  replay -all <dlq-topic>
+  purge <id>          drop a dead letter without replaying it
+  purge -all <dlq-topic>
+`
+
+func main() {
+	dir := flag.String("dir", "data/eventbus", "event bus directory")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	bus, err := eventbus.NewLogBus(eventbus.LogBusConfig{Dir: *dir})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer bus.Close()
+
+	dlq := eventbus.NewDeadLetterQueue(bus)
+	args := flag.Args()[1:]
+
+	switch flag.Arg(0) {
+	case "topics":
+		err = topics(dlq)
+	case "list":
+		err = list(dlq, args)
+	case "show":
+		err = show(dlq, args)
+	case "replay":
+		err = resolve(dlq, args, func(id string) error {
+			return dlq.Replay(context.Background(), id)
+		}, "replayed")
+	case "purge":
+		err = resolve(dlq, args, dlq.Purge, "purged")
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		bus.Close()
+		log.Fatal(err)
+	}
+}
+
+func topics(dlq *eventbus.DeadLetterQueue) error {
+	topics, err := dlq.Topics()
+	if err != nil {
+		return err
+	}
+
+	for _, t := range topics {
+		fmt.Println(t)
+	}
+	return nil
+}
+
+func list(dlq *eventbus.DeadLetterQueue, args []string) error {
+	topic := ""
+	if len(args) > 0 {
+		topic = args[0]
+	}
+
+	letters, err := dlq.List(topic)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTOPIC\tSUBSCRIBER\tATTEMPTS\tLAST ATTEMPT\tERROR")
+	for _, l := range letters {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", l.ID, l.Topic, l.Subscriber, l.Attempts, l.LastAttemptAt.Format(time.RFC3339), l.Error)
+	}
+	return w.Flush()
+}
+
+func show(dlq *eventbus.DeadLetterQueue, args []string) error {
+	if len(args) != 1 {
+		return errors.New("show needs a dead letter id")
+	}
+
+	letter, err := dlq.Get(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Print the payload as JSON when it is JSON, so it is readable.
+	out := struct {
+		eventbus.DeadLetter
+		Payload any `json:"payload"`
+	}{DeadLetter: letter, Payload: string(letter.Payload)}
+
+	var payload json.RawMessage
+	if json.Unmarshal(letter.Payload, &payload) == nil {
+		out.Payload = payload
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func resolve(dlq *eventbus.DeadLetterQueue, args []string, action func(id string) error, done string) error {
+	if len(args) == 2 && args[0] == "-all" {
+		letters, err := dlq.List(args[1])
+		if err != nil {
+			return err
+		}
+		for _, l := range letters {
+			if err := action(l.ID); err != nil {
+				return err
+			}
+			fmt.Printf("%s %s\n", done, l.ID)
+		}
+		return nil
+	}
+
+	if len(args) != 1 {
+		return errors.New("expected a dead letter id or -all <dlq-topic>")
+	}
+
+	if err := action(args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("%s %s\n", done, args[0])
+	return nil
+}
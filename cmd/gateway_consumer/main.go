@@ -3,36 +3,59 @@ package gateway_consumer
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
-	"time"
 
 	"github.com/mmarias/golearn/internal/domain"
+	entrypoint "github.com/mmarias/golearn/internal/entrypoint/eventbus"
 	"github.com/mmarias/golearn/internal/infraestructure/eventbus"
 )
 
-func Setup(bus eventbus.Client) {
-	dispatcher := func(ctx context.Context, msg []byte) {
+const subscriber = domain.ServiceGateway
+
+func Setup(bus eventbus.Client, gateway domain.GatewayCommands, dedup *entrypoint.Deduplicator) {
+	dispatcher := func(ctx context.Context, msg []byte) error {
 		var genericEvent domain.CommandEvent
 		if err := json.Unmarshal(msg, &genericEvent); err != nil {
 			log.Printf("ERROR: could not unmarshal generic event: %v", err)
-			return
+			return eventbus.Permanent(fmt.Errorf("unmarshal generic event: %w", err))
 		}
 
-		if genericEvent.EventType == domain.AuthorizeGatewayEventType {
+		switch genericEvent.EventType {
+		case domain.AuthorizeGatewayEventType:
 			var ev domain.WalletCommandEvent
-			json.Unmarshal(msg, &ev)
+			if err := json.Unmarshal(msg, &ev); err != nil {
+				return eventbus.Permanent(fmt.Errorf("unmarshal WalletCommandEvent: %w", err))
+			}
 			log.Printf("[Gateway] Processing authorization for payment %s", ev.PaymentID)
 
-			// Simulate calling an external payment provider
-			time.Sleep(200 * time.Millisecond)
-			log.Printf("[Gateway] Payment %s authorized by external provider", ev.PaymentID)
+			provider, err := gateway.Provider(ev.Method)
+			if err == nil {
+				err = provider.Authorize(ctx, ev.PaymentID, ev.Amount, ev.Token)
+			}
+			switch {
+			case errors.Is(err, domain.ErrGatewayPending):
+				// The provider answers with a callback, see POST /gateway/callbacks.
+				log.Printf("[Gateway] Authorization of payment %s pending confirmation", ev.PaymentID)
+				return nil
+			case errors.Is(err, domain.ErrGatewayDeclined), errors.Is(err, domain.ErrUnsupportedPaymentMethod):
+				log.Printf("[Gateway] Authorization of payment %s declined: %v", ev.PaymentID, err)
+				if err := publishAuthorizationFailure(ctx, bus, domain.TopicGatewayAuthorizationFailed, ev, err.Error()); err != nil {
+					return err
+				}
+				entrypoint.PublishMetric(ctx, bus, domain.MetricGatewayUnauthorize, ev.PaymentID, map[string]string{domain.MetricLabelReason: err.Error()})
+				return nil
+			case err != nil:
+				log.Printf("ERROR: [Gateway] authorizing payment %s: %v", ev.PaymentID, err)
+				return err
+			}
 
 			// The gateway would publish this event upon success
 			gatewaySuccessEvent := domain.GatewayAuthorizedEvent{
 				PaymentID: ev.PaymentID,
 				WalletID:  ev.WalletID,
 				Amount:    ev.Amount,
-				Currency:  ev.Currency,
 			}
 			type eventForDispatch struct {
 				domain.GatewayAuthorizedEvent
@@ -41,11 +64,159 @@ func Setup(bus eventbus.Client) {
 			msgBody, _ := json.Marshal(eventForDispatch{
 				GatewayAuthorizedEvent: gatewaySuccessEvent,
 				CommandEvent: domain.CommandEvent{
-					EventType: "gateway.authorized",
+					EventType:    domain.TopicGatewayAuthorized,
+					EventVersion: "1",
 				},
 			})
-			bus.Publish(ctx, "gateway.authorized", msgBody)
+			return bus.Publish(ctx, domain.TopicGatewayAuthorized, msgBody)
+
+		case domain.CaptureAuthorizationEventType:
+			var ev domain.WalletCommandEvent
+			if err := json.Unmarshal(msg, &ev); err != nil {
+				return eventbus.Permanent(fmt.Errorf("unmarshal WalletCommandEvent: %w", err))
+			}
+			log.Printf("[Gateway] Capturing payment %s", ev.PaymentID)
+
+			// The saga debits the held funds once the capture is confirmed.
+			provider, err := gateway.Provider(ev.Method)
+			if err == nil {
+				err = provider.Capture(ctx, ev.PaymentID, ev.Amount)
+			}
+			switch {
+			case errors.Is(err, domain.ErrGatewayPending):
+				log.Printf("[Gateway] Capture of payment %s pending confirmation", ev.PaymentID)
+				return nil
+			case errors.Is(err, domain.ErrGatewayDeclined), errors.Is(err, domain.ErrUnsupportedPaymentMethod):
+				log.Printf("ERROR: [Gateway] Capture of payment %s declined: %v", ev.PaymentID, err)
+				return publishAuthorizationFailure(ctx, bus, domain.TopicGatewayCaptureFailed, ev, err.Error())
+			case err != nil:
+				log.Printf("ERROR: [Gateway] capturing payment %s: %v", ev.PaymentID, err)
+				return err
+			}
+			return publishAuthorizationAnswer(ctx, bus, domain.TopicGatewayCaptured, ev)
+
+		case domain.VoidAuthorizationEventType:
+			var ev domain.WalletCommandEvent
+			if err := json.Unmarshal(msg, &ev); err != nil {
+				return eventbus.Permanent(fmt.Errorf("unmarshal WalletCommandEvent: %w", err))
+			}
+			log.Printf("[Gateway] Voiding authorization of payment %s", ev.PaymentID)
+
+			// Nothing waits for the void: the saga goes on releasing the held funds.
+			provider, err := gateway.Provider(ev.Method)
+			if err != nil {
+				return eventbus.Permanent(err)
+			}
+			err = provider.Void(ctx, ev.PaymentID)
+			switch {
+			case errors.Is(err, domain.ErrGatewayPending):
+			case errors.Is(err, domain.ErrGatewayDeclined):
+				// The capture of the authorization was refunded before.
+				log.Printf("[Gateway] Nothing to void for payment %s: %v", ev.PaymentID, err)
+			case err != nil:
+				log.Printf("ERROR: [Gateway] voiding authorization of payment %s: %v", ev.PaymentID, err)
+				return err
+			}
+			return nil
+
+		case domain.RefundAuthorizationEventType:
+			var ev domain.WalletCommandEvent
+			if err := json.Unmarshal(msg, &ev); err != nil {
+				return eventbus.Permanent(fmt.Errorf("unmarshal WalletCommandEvent: %w", err))
+			}
+			log.Printf("[Gateway] Refunding authorization of payment %s", ev.PaymentID)
+
+			provider, err := gateway.Provider(ev.Method)
+			if err == nil {
+				err = provider.Refund(ctx, domain.AuthorizationRefundID(ev.PaymentID), ev.PaymentID, ev.Amount)
+			}
+			switch {
+			case errors.Is(err, domain.ErrGatewayPending):
+				log.Printf("[Gateway] Refund of the authorization of payment %s pending confirmation", ev.PaymentID)
+				return nil
+			case errors.Is(err, domain.ErrGatewayDeclined), errors.Is(err, domain.ErrUnsupportedPaymentMethod):
+				log.Printf("ERROR: [Gateway] Refund of the authorization of payment %s declined: %v", ev.PaymentID, err)
+				return publishAuthorizationFailure(ctx, bus, domain.TopicGatewayAuthorizationRefundFailed, ev, err.Error())
+			case err != nil:
+				log.Printf("ERROR: [Gateway] refunding authorization of payment %s: %v", ev.PaymentID, err)
+				return err
+			}
+			return publishAuthorizationAnswer(ctx, bus, domain.TopicGatewayAuthorizationRefunded, ev)
+
+		case domain.RefundGatewayEventType:
+			var ev domain.RefundCommandEvent
+			if err := json.Unmarshal(msg, &ev); err != nil {
+				return eventbus.Permanent(fmt.Errorf("unmarshal RefundCommandEvent: %w", err))
+			}
+			log.Printf("[Gateway] Processing refund %s for payment %s", ev.RefundID, ev.PaymentID)
+
+			provider, err := gateway.Provider(ev.Method)
+			if err == nil {
+				err = provider.Refund(ctx, ev.RefundID, ev.PaymentID, ev.Amount)
+			}
+			switch {
+			case errors.Is(err, domain.ErrGatewayPending):
+				log.Printf("[Gateway] Refund %s pending confirmation", ev.RefundID)
+				return nil
+			case errors.Is(err, domain.ErrGatewayDeclined), errors.Is(err, domain.ErrUnsupportedPaymentMethod):
+				log.Printf("[Gateway] Refund %s declined: %v", ev.RefundID, err)
+				ev.Reason = err.Error()
+				return publishRefund(ctx, bus, domain.TopicGatewayRefundFailed, ev)
+			case err != nil:
+				log.Printf("ERROR: [Gateway] refunding payment %s: %v", ev.PaymentID, err)
+				return err
+			}
+
+			return publishRefund(ctx, bus, domain.TopicGatewayRefunded, ev)
 		}
+
+		return nil
+	}
+	bus.Subscribe(domain.TopicOrchestratorGateway, subscriber, dedup.Wrap(subscriber, dispatcher))
+}
+
+// publishAuthorizationAnswer publishes the success of an operation on the
+// authorization of a payment.
+func publishAuthorizationAnswer(ctx context.Context, bus eventbus.Client, topic string, ev domain.WalletCommandEvent) error {
+	msgBody, err := json.Marshal(domain.GatewayAuthorizedEvent{
+		CommandEvent: domain.CommandEvent{
+			EventType:    topic,
+			EventVersion: "1",
+		},
+		PaymentID: ev.PaymentID,
+		WalletID:  ev.WalletID,
+		Amount:    ev.Amount,
+	})
+	if err != nil {
+		return eventbus.Permanent(err)
+	}
+	return bus.Publish(ctx, topic, msgBody)
+}
+
+// publishAuthorizationFailure publishes the failure of an operation on the
+// authorization of a payment, with its reason.
+func publishAuthorizationFailure(ctx context.Context, bus eventbus.Client, topic string, ev domain.WalletCommandEvent, reason string) error {
+	msgBody, err := json.Marshal(domain.GatewayAuthorizationFailedEvent{
+		CommandEvent: domain.CommandEvent{
+			EventType:    topic,
+			EventVersion: "1",
+		},
+		PaymentID: ev.PaymentID,
+		WalletID:  ev.WalletID,
+		Amount:    ev.Amount,
+		Reason:    reason,
+	})
+	if err != nil {
+		return eventbus.Permanent(err)
+	}
+	return bus.Publish(ctx, topic, msgBody)
+}
+
+func publishRefund(ctx context.Context, bus eventbus.Client, topic string, ev domain.RefundCommandEvent) error {
+	ev.EventType = topic
+	msgBody, err := json.Marshal(ev)
+	if err != nil {
+		return eventbus.Permanent(err)
 	}
-	bus.Subscribe(domain.TopicOrchestratorGateway, dispatcher)
+	return bus.Publish(ctx, topic, msgBody)
 }
@@ -0,0 +1,215 @@
+package gateway_consumer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	gateway "github.com/mmarias/golearn/internal/app/gateway/v1"
+	"github.com/mmarias/golearn/internal/domain"
+	entrypoint "github.com/mmarias/golearn/internal/entrypoint/eventbus"
+	"github.com/mmarias/golearn/internal/infraestructure/database"
+	"github.com/mmarias/golearn/internal/infraestructure/eventbus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingBus keeps the handlers subscribed and the messages published.
+type recordingBus struct {
+	handlers  map[string]eventbus.HandlerFunc
+	published map[string][][]byte
+}
+
+func (b *recordingBus) Publish(ctx context.Context, topic string, message []byte) error {
+	b.published[topic] = append(b.published[topic], message)
+	return nil
+}
+
+func (b *recordingBus) Subscribe(topic, subscriber string, handler eventbus.HandlerFunc) {
+	b.handlers[topic] = handler
+}
+
+func TestSetup_AuthorizationAnswers(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		token          string
+		expectedTopic  string
+		expectedReason string
+	}{
+		{
+			name:          "authorized",
+			method:        "card",
+			token:         "tok_visa",
+			expectedTopic: domain.TopicGatewayAuthorized,
+		},
+		{
+			name:           "declined by the provider",
+			method:         "card",
+			token:          "tok_insufficient_funds",
+			expectedTopic:  domain.TopicGatewayAuthorizationFailed,
+			expectedReason: "insufficient funds",
+		},
+		{
+			name:           "no provider for the method",
+			method:         "crypto",
+			token:          "tok_visa",
+			expectedTopic:  domain.TopicGatewayAuthorizationFailed,
+			expectedReason: "unsupported payment method",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &recordingBus{handlers: map[string]eventbus.HandlerFunc{}, published: map[string][][]byte{}}
+			providers := gateway.NewProviderRegistry("card", map[string]domain.PaymentProvider{
+				"card": gateway.NewSimulatedProvider("card", gateway.SimulatedProviderConfig{
+					Declines: []gateway.DeclineRule{{Token: "tok_insufficient_funds", Reason: "insufficient funds"}},
+				}),
+			})
+			dedup := entrypoint.NewDeduplicator(database.NewProcessedMessageRepository(database.OpenInMemory(), time.Minute))
+			Setup(bus, providers, dedup)
+
+			msg, err := json.Marshal(domain.WalletCommandEvent{
+				CommandEvent: domain.CommandEvent{EventType: domain.AuthorizeGatewayEventType, EventVersion: "2"},
+				WalletCommandEventPayload: domain.WalletCommandEventPayload{
+					PaymentID: "payment-123",
+					WalletID:  "wallet-456",
+					Amount:    domain.NewMoney(10_00, "USD"),
+					Method:    tt.method,
+					Token:     tt.token,
+				},
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, bus.handlers[domain.TopicOrchestratorGateway](context.Background(), msg))
+
+			require.Len(t, bus.published[tt.expectedTopic], 1)
+			if tt.expectedReason == "" {
+				assert.Empty(t, bus.published[domain.TopicMetrics])
+				return
+			}
+			var ev domain.GatewayAuthorizationFailedEvent
+			require.NoError(t, json.Unmarshal(bus.published[tt.expectedTopic][0], &ev))
+			assert.Equal(t, "payment-123", ev.PaymentID)
+			assert.Contains(t, ev.Reason, tt.expectedReason)
+			if tt.expectedReason == "" {
+				// Successes are published as GatewayAuthorizedEvent, without a reason.
+				assert.NotContains(t, string(bus.published[tt.expectedTopic][0]), `"reason"`)
+			}
+			assert.Len(t, bus.published[domain.TopicMetrics], 1)
+		})
+	}
+}
+
+func TestSetup_AuthorizationRefundAnswers(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		expectedTopic  string
+		expectedReason string
+	}{
+		{
+			name:          "refunded",
+			method:        "card",
+			expectedTopic: domain.TopicGatewayAuthorizationRefunded,
+		},
+		{
+			name:           "no provider for the method",
+			method:         "crypto",
+			expectedTopic:  domain.TopicGatewayAuthorizationRefundFailed,
+			expectedReason: "unsupported payment method",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &recordingBus{handlers: map[string]eventbus.HandlerFunc{}, published: map[string][][]byte{}}
+			providers := gateway.NewProviderRegistry("card", map[string]domain.PaymentProvider{
+				"card": gateway.NewSimulatedProvider("card", gateway.SimulatedProviderConfig{}),
+			})
+			dedup := entrypoint.NewDeduplicator(database.NewProcessedMessageRepository(database.OpenInMemory(), time.Minute))
+			Setup(bus, providers, dedup)
+
+			msg, err := json.Marshal(domain.WalletCommandEvent{
+				CommandEvent: domain.CommandEvent{EventType: domain.RefundAuthorizationEventType, EventVersion: "2"},
+				WalletCommandEventPayload: domain.WalletCommandEventPayload{
+					PaymentID: "payment-123",
+					WalletID:  "wallet-456",
+					Amount:    domain.NewMoney(10_00, "USD"),
+					Method:    tt.method,
+				},
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, bus.handlers[domain.TopicOrchestratorGateway](context.Background(), msg))
+
+			require.Len(t, bus.published[tt.expectedTopic], 1)
+			var ev domain.GatewayAuthorizationFailedEvent
+			require.NoError(t, json.Unmarshal(bus.published[tt.expectedTopic][0], &ev))
+			assert.Equal(t, "payment-123", ev.PaymentID)
+			assert.Contains(t, ev.Reason, tt.expectedReason)
+			if tt.expectedReason == "" {
+				// Successes are published as GatewayAuthorizedEvent, without a reason.
+				assert.NotContains(t, string(bus.published[tt.expectedTopic][0]), `"reason"`)
+			}
+		})
+	}
+}
+
+func TestSetup_CaptureAnswers(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		expectedTopic  string
+		expectedReason string
+	}{
+		{
+			name:          "captured",
+			method:        "card",
+			expectedTopic: domain.TopicGatewayCaptured,
+		},
+		{
+			name:           "no provider for the method",
+			method:         "crypto",
+			expectedTopic:  domain.TopicGatewayCaptureFailed,
+			expectedReason: "unsupported payment method",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := &recordingBus{handlers: map[string]eventbus.HandlerFunc{}, published: map[string][][]byte{}}
+			providers := gateway.NewProviderRegistry("card", map[string]domain.PaymentProvider{
+				"card": gateway.NewSimulatedProvider("card", gateway.SimulatedProviderConfig{}),
+			})
+			dedup := entrypoint.NewDeduplicator(database.NewProcessedMessageRepository(database.OpenInMemory(), time.Minute))
+			Setup(bus, providers, dedup)
+
+			msg, err := json.Marshal(domain.WalletCommandEvent{
+				CommandEvent: domain.CommandEvent{EventType: domain.CaptureAuthorizationEventType, EventVersion: "2"},
+				WalletCommandEventPayload: domain.WalletCommandEventPayload{
+					PaymentID: "payment-123",
+					WalletID:  "wallet-456",
+					Amount:    domain.NewMoney(10_00, "USD"),
+					Method:    tt.method,
+				},
+			})
+			require.NoError(t, err)
+
+			require.NoError(t, bus.handlers[domain.TopicOrchestratorGateway](context.Background(), msg))
+
+			require.Len(t, bus.published[tt.expectedTopic], 1)
+			var ev domain.GatewayAuthorizationFailedEvent
+			require.NoError(t, json.Unmarshal(bus.published[tt.expectedTopic][0], &ev))
+			assert.Equal(t, "payment-123", ev.PaymentID)
+			assert.Equal(t, domain.NewMoney(10_00, "USD"), ev.Amount)
+			assert.Contains(t, ev.Reason, tt.expectedReason)
+			if tt.expectedReason == "" {
+				// Successes are published as GatewayAuthorizedEvent, without a reason.
+				assert.NotContains(t, string(bus.published[tt.expectedTopic][0]), `"reason"`)
+			}
+		})
+	}
+}
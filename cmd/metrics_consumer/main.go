@@ -0,0 +1,27 @@
+package metrics_consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mmarias/golearn/internal/domain"
+	entrypoint "github.com/mmarias/golearn/internal/entrypoint/eventbus"
+	"github.com/mmarias/golearn/internal/infraestructure/eventbus"
+)
+
+const subscriber = domain.ServiceMetrics
+
+// Setup records the metric events published by the services. The events are
+// deduplicated, so a redelivered metric is counted once.
+func Setup(bus eventbus.Client, metrics domain.MetricsRecorder, dedup *entrypoint.Deduplicator) {
+	dispatcher := func(ctx context.Context, msg []byte) error {
+		var ev domain.MetricEvent
+		if err := json.Unmarshal(msg, &ev); err != nil {
+			return eventbus.Permanent(fmt.Errorf("unmarshal MetricEvent: %w", err))
+		}
+		metrics.Record(ev.MetricEventPayload)
+		return nil
+	}
+	bus.Subscribe(domain.TopicMetrics, subscriber, dedup.Wrap(subscriber, dispatcher))
+}
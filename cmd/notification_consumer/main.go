@@ -3,25 +3,53 @@ package notification_consumer
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 
 	"github.com/mmarias/golearn/internal/domain"
+	entrypoint "github.com/mmarias/golearn/internal/entrypoint/eventbus"
 	"github.com/mmarias/golearn/internal/infraestructure/eventbus"
 )
 
-func Setup(bus eventbus.Client) {
-	dispatcher := func(ctx context.Context, msg []byte) {
+const subscriber = domain.ServiceNotification
+
+func Setup(bus eventbus.Client, dedup *entrypoint.Deduplicator) {
+	dispatcher := func(ctx context.Context, msg []byte) error {
 		var genericEvent domain.CommandEvent
 		if err := json.Unmarshal(msg, &genericEvent); err != nil {
 			log.Printf("ERROR: could not unmarshal generic event: %v", err)
-			return
+			return eventbus.Permanent(fmt.Errorf("unmarshal generic event: %w", err))
 		}
 
 		if genericEvent.EventType == domain.NotifyUserEventType {
 			var ev domain.NotifyUserEvent
-			json.Unmarshal(msg, &ev)
+			if err := json.Unmarshal(msg, &ev); err != nil {
+				return eventbus.Permanent(fmt.Errorf("unmarshal NotifyUserEvent: %w", err))
+			}
+			if ev.RefundID != "" {
+				log.Printf("[Notification] Sending notification '%s' for refund %s of payment %s", ev.Notification, ev.RefundID, ev.PaymentID)
+				return nil
+			}
 			log.Printf("[Notification] Sending notification '%s' for payment %s", ev.Notification, ev.PaymentID)
 		}
+
+		return nil
+	}
+	bus.Subscribe(domain.TopicOrchestratorNotification, subscriber, dedup.Wrap(subscriber, dispatcher))
+
+	// Failed and canceled payments are notified once the payment service
+	// recorded the status, not when the orchestrator asked for it.
+	for topic, notification := range map[string]domain.Notification{
+		domain.TopicPaymentFailed:   domain.PaymentFailure,
+		domain.TopicPaymentCanceled: domain.PaymentCanceled,
+	} {
+		bus.Subscribe(topic, subscriber, dedup.Wrap(subscriber, func(ctx context.Context, msg []byte) error {
+			var ev domain.PaymentUpdateStatusEvent
+			if err := json.Unmarshal(msg, &ev); err != nil {
+				return eventbus.Permanent(fmt.Errorf("unmarshal PaymentUpdateStatusEvent: %w", err))
+			}
+			log.Printf("[Notification] Sending notification '%s' for payment %s", notification, ev.PaymentID)
+			return nil
+		}))
 	}
-	bus.Subscribe(domain.TopicOrchestratorNotification, dispatcher)
 }
@@ -1,30 +1,62 @@
 package orchestrator_consumer
 
 import (
+	"time"
+
+	"github.com/mmarias/golearn/internal/app/orchestrator/saga"
 	orchestrator "github.com/mmarias/golearn/internal/app/orchestrator/v1"
+	"github.com/mmarias/golearn/internal/domain"
 	"github.com/mmarias/golearn/internal/entrypoint/eventbus"
 	infraEventbus "github.com/mmarias/golearn/internal/infraestructure/eventbus"
 	"github.com/mmarias/golearn/internal/infraestructure/publisher"
 )
 
-func Setup(bus infraEventbus.Client) {
+// sweepInterval is how often the sweeper looks for saga steps that timed out.
+const sweepInterval = time.Second
+
+// Setup subscribes the sagas and returns the sweeper publishing their
+// timeouts, which the caller runs.
+func Setup(bus infraEventbus.Client, sagaStates domain.SagaStateRepository, refundSagaStates domain.RefundSagaStateRepository, dedup *eventbus.Deduplicator) *saga.Sweeper {
 	pub := publisher.New(bus)
 
 	holdFundsCmd := orchestrator.NewHoldFundsCommand(pub)
 	releaseFundsCmd := orchestrator.NewReleaseFundsCommand(pub)
 	debitFundsCmd := orchestrator.NewDebitFundsCommand(pub)
 	authorizeCmd := orchestrator.NewAuthorizeGatewayCommand(pub)
+	captureCmd := orchestrator.NewCaptureAuthorizationCommand(pub)
+	voidCmd := orchestrator.NewVoidAuthorizationCommand(pub)
+	refundAuthCmd := orchestrator.NewRefundAuthorizationCommand(pub)
 	updateStatusCmd := orchestrator.NewUpdatePaymentStatusCommand(pub)
 	notifyUserCmd := orchestrator.NewNotifyUserCommand(pub)
+	refundGatewayCmd := orchestrator.NewRefundGatewayCommand(pub)
+	creditFundsCmd := orchestrator.NewCreditFundsCommand(pub)
+	updateRefundStatusCmd := orchestrator.NewUpdateRefundStatusCommand(pub)
+	recordMetricCmd := orchestrator.NewRecordMetricCommand(pub)
 
-	sagaHandler := eventbus.NewOrchestratorSagaHandler(
+	paymentSaga := orchestrator.NewPaymentSaga(
+		sagaStates,
 		holdFundsCmd,
 		releaseFundsCmd,
 		debitFundsCmd,
 		authorizeCmd,
+		captureCmd,
+		voidCmd,
+		refundAuthCmd,
 		updateStatusCmd,
 		notifyUserCmd,
+		recordMetricCmd,
+	)
+
+	refundSaga := orchestrator.NewRefundSaga(
+		refundSagaStates,
+		refundGatewayCmd,
+		creditFundsCmd,
+		updateRefundStatusCmd,
+		notifyUserCmd,
+		recordMetricCmd,
 	)
 
-	eventbus.SetupSagaDispatcher(bus, sagaHandler)
+	eventbus.SetupSagaDispatcher(bus, []eventbus.Saga{paymentSaga, refundSaga}, dedup)
+
+	return saga.NewSweeper(pub, sweepInterval, paymentSaga, refundSaga)
 }
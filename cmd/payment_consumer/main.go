@@ -3,24 +3,26 @@ package payment_consumer
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
-	"time"
 
+	v1 "github.com/mmarias/golearn/internal/app/payment/v1"
 	"github.com/mmarias/golearn/internal/domain"
+	entrypoint "github.com/mmarias/golearn/internal/entrypoint/eventbus"
 	"github.com/mmarias/golearn/internal/infraestructure/eventbus"
 )
 
-const (
-	PaymentCompleted = "payment.completed"
-	PaymentFailed    = "payment.failed"
-)
+const subscriber = domain.ServicePayment
+
+func Setup(bus eventbus.Client, repository domain.PaymentRepository, dedup *entrypoint.Deduplicator) {
+	updateStatus := v1.NewUpdatePaymentStatusUseCase(repository)
 
-func Setup(bus eventbus.Client) {
-	dispatcher := func(ctx context.Context, msg []byte) {
+	dispatcher := func(ctx context.Context, msg []byte) error {
 		var genericEvent domain.CommandEvent
 		if err := json.Unmarshal(msg, &genericEvent); err != nil {
 			log.Printf("ERROR: could not unmarshal generic event: %v", err)
-			return
+			return eventbus.Permanent(fmt.Errorf("unmarshal generic event: %w", err))
 		}
 
 		switch genericEvent.EventType {
@@ -28,36 +30,68 @@ func Setup(bus eventbus.Client) {
 			var ev domain.PaymentUpdateStatusEvent
 			if err := json.Unmarshal(msg, &ev); err != nil {
 				log.Printf("ERROR: could not unmarshal PaymentUpdateStatusEvent: %v", err)
-				return
+				return eventbus.Permanent(fmt.Errorf("unmarshal PaymentUpdateStatusEvent: %w", err))
 			}
 
 			log.Printf("[PaymentConsumer] Received PaymentUpdateStatusEvent for PaymentID: %s with status: %s", ev.PaymentID, ev.Status)
 
+			if _, err := updateStatus.Execute(ctx, ev.PaymentID, ev.Status); err != nil {
+				log.Printf("ERROR: [PaymentConsumer] could not update status of PaymentID %s to %s: %v", ev.PaymentID, ev.Status, err)
+				if errors.Is(err, domain.ErrIllegalPaymentTransition) {
+					// The payment stays where it is and nothing is published, so
+					// the saga does not move on a status that was not applied.
+					return eventbus.Permanent(err)
+				}
+				return err
+			}
+
 			switch ev.PaymentUpdateStatusEventPayload.Status {
 			case domain.PaymentStatusCompleted:
 				log.Printf("[PaymentConsumer] Handling PaymentStatusCompleted for PaymentID: %s", ev.PaymentID)
-				time.Sleep(50 * time.Millisecond) // Simulate some work
 
 				// Publish payment.completed event
-				ev.EventType = PaymentCompleted
+				ev.EventType = domain.TopicPaymentCompleted
 				msgBody, _ := json.Marshal(ev)
-				bus.Publish(ctx, PaymentCompleted, msgBody)
+				return bus.Publish(ctx, domain.TopicPaymentCompleted, msgBody)
 
 			case domain.PaymentStatusFailed:
 				log.Printf("[PaymentConsumer] Handling PaymentStatusFailed for PaymentID: %s", ev.PaymentID)
-				time.Sleep(50 * time.Millisecond) // Simulate some work
 
-				// Publish payment.failed event
-				ev.EventType = PaymentFailed
+				// Publish payment.failed event, the user is notified from it
+				ev.EventType = domain.TopicPaymentFailed
 				msgBody, _ := json.Marshal(ev)
-				bus.Publish(ctx, PaymentFailed, msgBody)
+				return bus.Publish(ctx, domain.TopicPaymentFailed, msgBody)
+
+			case domain.PaymentStatusCanceled:
+				log.Printf("[PaymentConsumer] Handling PaymentStatusCanceled for PaymentID: %s", ev.PaymentID)
+
+				// Publish payment.canceled event, the user is notified from it
+				ev.EventType = domain.TopicPaymentCanceled
+				msgBody, _ := json.Marshal(ev)
+				return bus.Publish(ctx, domain.TopicPaymentCanceled, msgBody)
+
+			case domain.PaymentStatusFundsHeld, domain.PaymentStatusAuthorized, domain.PaymentStatusDebited:
+				// Intermediate statuses only follow the saga, nobody waits for them.
+
+			case domain.PaymentStatusPartiallyRefunded, domain.PaymentStatusRefunded:
+				// Sent by the refunds service, which publishes refund.completed itself.
+
+			case domain.PaymentStatusRequiresReview:
+				// The payment stays there until someone settles it by hand.
+				log.Printf("WARN: [PaymentConsumer] PaymentID %s requires review", ev.PaymentID)
 
 			default:
 				log.Printf("[PaymentConsumer] Unknown payment status received for PaymentID %s: %s", ev.PaymentID, ev.PaymentUpdateStatusEventPayload.Status)
 			}
+		case domain.RefundUpdateStatusEventType:
+			// Sent before refunds had their own service, which applies it now.
+			log.Printf("[PaymentConsumer] Forwarding RefundUpdateStatusEvent to %s", domain.TopicOrchestratorRefund)
+			return bus.Publish(ctx, domain.TopicOrchestratorRefund, msg)
 		default:
 			log.Printf("[PaymentConsumer] Unknown event type received: %s", genericEvent.EventType)
 		}
+
+		return nil
 	}
-	bus.Subscribe(domain.TopicOrchestratorPayment, dispatcher)
+	bus.Subscribe(domain.TopicOrchestratorPayment, subscriber, dedup.Wrap(subscriber, dispatcher))
 }
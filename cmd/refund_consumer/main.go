@@ -0,0 +1,91 @@
+package refund_consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	v1 "github.com/mmarias/golearn/internal/app/refund/v1"
+	"github.com/mmarias/golearn/internal/domain"
+	entrypoint "github.com/mmarias/golearn/internal/entrypoint/eventbus"
+	"github.com/mmarias/golearn/internal/infraestructure/eventbus"
+)
+
+const subscriber = domain.ServiceRefund
+
+// Setup subscribes the refunds service to its commands and to the payment
+// events it keeps its refundable payments from. It never writes the payments:
+// their refunded status goes to the payment service through paymentCmd.
+func Setup(bus eventbus.Client, payments domain.RefundablePaymentRepository, refunds domain.RefundRepository, paymentCmd domain.PaymentCommands, dedup *entrypoint.Deduplicator) {
+	updateRefundStatus := v1.NewUpdateRefundStatusUseCase(payments, refunds)
+	trackPayment := v1.NewTrackPaymentUseCase(payments)
+
+	dispatcher := func(ctx context.Context, msg []byte) error {
+		var genericEvent domain.CommandEvent
+		if err := json.Unmarshal(msg, &genericEvent); err != nil {
+			log.Printf("ERROR: could not unmarshal generic event: %v", err)
+			return eventbus.Permanent(fmt.Errorf("unmarshal generic event: %w", err))
+		}
+
+		switch genericEvent.EventType {
+		case domain.RefundUpdateStatusEventType:
+			var ev domain.RefundCommandEvent
+			if err := json.Unmarshal(msg, &ev); err != nil {
+				log.Printf("ERROR: could not unmarshal RefundCommandEvent: %v", err)
+				return eventbus.Permanent(fmt.Errorf("unmarshal RefundCommandEvent: %w", err))
+			}
+
+			log.Printf("[RefundConsumer] Received RefundUpdateStatusEvent for RefundID: %s with status: %s", ev.RefundID, ev.Status)
+
+			refund, paymentStatus, err := updateRefundStatus.Execute(ctx, ev.RefundID, ev.Status, ev.Reason)
+			if err != nil {
+				log.Printf("ERROR: [RefundConsumer] could not update status of RefundID %s to %s: %v", ev.RefundID, ev.Status, err)
+				return err
+			}
+
+			// Only a completed refund moves the payment; the saga already
+			// knows a failed one is over.
+			if refund.Status != domain.RefundStatusCompleted {
+				return nil
+			}
+
+			log.Printf("[RefundConsumer] Payment %s is %s after RefundID %s", refund.PaymentID, paymentStatus, refund.ID)
+			if err := paymentCmd.UpdateStatus(ctx, refund.PaymentID, paymentStatus); err != nil {
+				log.Printf("ERROR: [RefundConsumer] could not send status %s of PaymentID %s: %v", paymentStatus, refund.PaymentID, err)
+				return err
+			}
+
+			// Publish refund.completed event
+			ev.RefundCommandEventPayload = domain.NewRefundCommandEventPayload(refund)
+			ev.PaymentStatus = paymentStatus
+			ev.EventType = domain.TopicRefundCompleted
+			msgBody, _ := json.Marshal(ev)
+			return bus.Publish(ctx, domain.TopicRefundCompleted, msgBody)
+		default:
+			log.Printf("[RefundConsumer] Unknown event type received: %s", genericEvent.EventType)
+		}
+
+		return nil
+	}
+	bus.Subscribe(domain.TopicOrchestratorRefund, subscriber, dedup.Wrap(subscriber, dispatcher))
+
+	bus.Subscribe(domain.TopicPaymentCreated, subscriber, dedup.Wrap(subscriber, func(ctx context.Context, msg []byte) error {
+		var ev domain.PaymentCreatedEvent
+		if err := json.Unmarshal(msg, &ev); err != nil {
+			return eventbus.Permanent(fmt.Errorf("unmarshal PaymentCreatedEvent: %w", err))
+		}
+		return trackPayment.Created(ctx, ev)
+	}))
+	bus.Subscribe(domain.TopicPaymentCompleted, subscriber, dedup.Wrap(subscriber, func(ctx context.Context, msg []byte) error {
+		var ev domain.PaymentUpdateStatusEvent
+		if err := json.Unmarshal(msg, &ev); err != nil {
+			return eventbus.Permanent(fmt.Errorf("unmarshal PaymentUpdateStatusEvent: %w", err))
+		}
+		if err := trackPayment.Completed(ctx, ev.PaymentID); err != nil {
+			log.Printf("WARN: [RefundConsumer] could not make PaymentID %s refundable: %v", ev.PaymentID, err)
+			return err
+		}
+		return nil
+	}))
+}
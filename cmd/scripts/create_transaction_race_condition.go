@@ -9,10 +9,10 @@ import (
 )
 
 type Transaction struct {
-	ID                   string  `json:"id"`
-	OriginAccountID      string  `json:"origin_account_id"`
-	DestinationAccountID string  `json:"destination_account_id"`
-	Amount               float64 `json:"amount"`
+	ID                   string `json:"id"`
+	OriginAccountID      string `json:"origin_account_id"`
+	DestinationAccountID string `json:"destination_account_id"`
+	Amount               string `json:"amount"`
 }
 
 const ucCreateTransactionRaceCondition = "createTransactionRaceCondition"
@@ -39,6 +39,11 @@ func createTransactionRaceCondition() {
 				panic(err)
 			}
 
+			if resp.StatusCode != http.StatusCreated {
+				println("Status:", resp.Status, "error:", string(res))
+				return
+			}
+
 			var transaction Transaction
 			if err = json.Unmarshal(res, &transaction); err != nil {
 				panic(err)
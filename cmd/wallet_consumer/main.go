@@ -3,61 +3,163 @@ package wallet_consumer
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
-	"time"
 
 	"github.com/mmarias/golearn/internal/domain"
+	entrypoint "github.com/mmarias/golearn/internal/entrypoint/eventbus"
 	"github.com/mmarias/golearn/internal/infraestructure/eventbus"
 )
 
-const (
-	HoldFunds    = "wallet.hold_funds"
-	ReleaseFunds = "wallet.release_funds"
-	DebitFunds   = "wallet.debit_funds"
-)
+const subscriber = domain.ServiceWallet
 
-func Setup(bus eventbus.Client) {
-	dispatcher := func(ctx context.Context, msg []byte) {
+func Setup(bus eventbus.Client, wallets domain.WalletCommands, dedup *entrypoint.Deduplicator) {
+	dispatcher := func(ctx context.Context, msg []byte) error {
 		var genericEvent domain.CommandEvent
 		if err := json.Unmarshal(msg, &genericEvent); err != nil {
 			log.Printf("ERROR: could not unmarshal generic event: %v", err)
-			return
+			return eventbus.Permanent(fmt.Errorf("unmarshal generic event: %w", err))
+		}
+
+		if genericEvent.EventType == domain.CreditFundsEventType {
+			var ev domain.RefundCommandEvent
+			if err := json.Unmarshal(msg, &ev); err != nil {
+				return eventbus.Permanent(fmt.Errorf("unmarshal RefundCommandEvent: %w", err))
+			}
+			return credit(ctx, bus, wallets, ev)
+		}
+
+		var ev domain.WalletCommandEvent
+		if err := json.Unmarshal(msg, &ev); err != nil {
+			return eventbus.Permanent(fmt.Errorf("unmarshal WalletCommandEvent: %w", err))
 		}
 
 		switch genericEvent.EventType {
 		case domain.HoldFundsEventType:
-			var ev domain.WalletCommandEvent
-			json.Unmarshal(msg, &ev)
 			log.Printf("[Wallet] Holding funds for payment %s", ev.PaymentID)
-			time.Sleep(100 * time.Millisecond)
-			log.Printf("[Wallet] Funds held for payment %s", ev.PaymentID)
 
-			ev.EventType = HoldFunds
-			msgBody, _ := json.Marshal(ev)
-			bus.Publish(ctx, HoldFunds, msgBody)
+			_, err := wallets.Hold(ctx, ev.PaymentID, ev.WalletID, ev.Amount)
+			switch {
+			case isRejected(err):
+				log.Printf("[Wallet] Could not hold funds for payment %s: %v", ev.PaymentID, err)
+				ev.Reason = err.Error()
+				return publishFailure(ctx, bus, domain.TopicWalletHoldFundsFailed, domain.MetricWalletHoldFundsFailure, ev)
+			case err != nil:
+				log.Printf("ERROR: [Wallet] holding funds for payment %s: %v", ev.PaymentID, err)
+				return err
+			}
+
+			log.Printf("[Wallet] Funds held for payment %s", ev.PaymentID)
+			return publish(ctx, bus, domain.TopicWalletFunds, ev)
 
 		case domain.ReleaseFundsEventType:
-			var ev domain.WalletCommandEvent
-			json.Unmarshal(msg, &ev)
 			log.Printf("[Wallet] Releasing funds for payment %s", ev.PaymentID)
-			time.Sleep(100 * time.Millisecond)
-			log.Printf("[Wallet] Funds released for payment %s", ev.PaymentID)
 
-			ev.EventType = ReleaseFunds
-			msgBody, _ := json.Marshal(ev)
-			bus.Publish(ctx, ReleaseFunds, msgBody)
+			if _, err := wallets.Release(ctx, ev.PaymentID); err != nil {
+				log.Printf("ERROR: [Wallet] releasing funds for payment %s: %v", ev.PaymentID, err)
+				return permanentIfRejected(err)
+			}
+
+			log.Printf("[Wallet] Funds released for payment %s", ev.PaymentID)
+			return publish(ctx, bus, domain.TopicWalletFundsReleased, ev)
 
 		case domain.DebitFundsEventType:
-			var ev domain.WalletCommandEvent
-			json.Unmarshal(msg, &ev)
 			log.Printf("[Wallet] Debiting funds for payment %s", ev.PaymentID)
-			time.Sleep(100 * time.Millisecond)
+
+			_, err := wallets.Debit(ctx, ev.PaymentID)
+			switch {
+			case isRejected(err):
+				log.Printf("[Wallet] Could not debit funds for payment %s: %v", ev.PaymentID, err)
+				ev.Reason = err.Error()
+				return publishFailure(ctx, bus, domain.TopicWalletDebitFailed, domain.MetricWalletDebitFailure, ev)
+			case err != nil:
+				log.Printf("ERROR: [Wallet] debiting funds for payment %s: %v", ev.PaymentID, err)
+				return err
+			}
+
 			log.Printf("[Wallet] Funds debited for payment %s", ev.PaymentID)
+			return publish(ctx, bus, domain.TopicWalletDebitFunds, ev)
+		}
+
+		return nil
+	}
+	bus.Subscribe(domain.TopicOrchestratorWallet, subscriber, dedup.Wrap(subscriber, dispatcher))
+}
+
+// credit gives a refund back to the wallet. The refund id keys the credit, so
+// a redelivered command does not credit twice.
+func credit(ctx context.Context, bus eventbus.Client, wallets domain.WalletCommands, ev domain.RefundCommandEvent) error {
+	log.Printf("[Wallet] Crediting refund %s to wallet %s", ev.RefundID, ev.WalletID)
 
-			ev.EventType = DebitFunds
-			msgBody, _ := json.Marshal(ev)
-			bus.Publish(ctx, DebitFunds, msgBody)
+	_, err := wallets.Credit(ctx, ev.WalletID, ev.WalletAmount, "refund."+ev.RefundID)
+	switch {
+	case isRejected(err):
+		log.Printf("[Wallet] Could not credit refund %s: %v", ev.RefundID, err)
+		ev.Reason = err.Error()
+		if err := publishRefund(ctx, bus, domain.TopicWalletCreditFailed, ev); err != nil {
+			return err
 		}
+		entrypoint.PublishMetric(ctx, bus, domain.MetricWalletCreditFailure, ev.RefundID, map[string]string{domain.MetricLabelReason: ev.Reason})
+		return nil
+	case err != nil:
+		log.Printf("ERROR: [Wallet] crediting refund %s: %v", ev.RefundID, err)
+		return err
+	}
+
+	log.Printf("[Wallet] Refund %s credited to wallet %s", ev.RefundID, ev.WalletID)
+	return publishRefund(ctx, bus, domain.TopicWalletFundsCredited, ev)
+}
+
+func publish(ctx context.Context, bus eventbus.Client, topic string, ev domain.WalletCommandEvent) error {
+	ev.EventType = topic
+	msgBody, err := json.Marshal(ev)
+	if err != nil {
+		return eventbus.Permanent(err)
+	}
+	return bus.Publish(ctx, topic, msgBody)
+}
+
+// publishFailure answers a rejected command and reports it as metric.
+func publishFailure(ctx context.Context, bus eventbus.Client, topic, metric string, ev domain.WalletCommandEvent) error {
+	if err := publish(ctx, bus, topic, ev); err != nil {
+		return err
+	}
+	entrypoint.PublishMetric(ctx, bus, metric, ev.PaymentID, map[string]string{domain.MetricLabelReason: ev.Reason})
+	return nil
+}
+
+func publishRefund(ctx context.Context, bus eventbus.Client, topic string, ev domain.RefundCommandEvent) error {
+	ev.EventType = topic
+	msgBody, err := json.Marshal(ev)
+	if err != nil {
+		return eventbus.Permanent(err)
+	}
+	return bus.Publish(ctx, topic, msgBody)
+}
+
+// isRejected reports whether the wallet refused the operation; retrying it
+// would give the same answer.
+func isRejected(err error) bool {
+	for _, rejected := range []error{
+		domain.ErrWalletNotFound,
+		domain.ErrInsufficientFunds,
+		domain.ErrInvalidAmount,
+		domain.ErrCurrencyMismatch,
+		domain.ErrHoldNotFound,
+		domain.ErrHoldAlreadyExists,
+		domain.ErrHoldNotActive,
+	} {
+		if errors.Is(err, rejected) {
+			return true
+		}
+	}
+	return false
+}
+
+func permanentIfRejected(err error) error {
+	if isRejected(err) {
+		return eventbus.Permanent(err)
 	}
-	bus.Subscribe(domain.TopicOrchestratorWallet, dispatcher)
+	return err
 }
@@ -0,0 +1,282 @@
+package v1
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/publisher"
+)
+
+// CallbackTolerance is how far the timestamp of a callback may be from now.
+// Older callbacks are rejected, so a captured request cannot be replayed once
+// its id left the store of seen callbacks.
+const CallbackTolerance = 5 * time.Minute
+
+// SimulatedProviderName is the provider whose callbacks ParseSimulatedCallback
+// reads.
+const SimulatedProviderName = "simulated"
+
+// CallbackProvider is a payment provider sending callbacks: the secret signing
+// them and how its payloads map to a GatewayCallback.
+type CallbackProvider struct {
+	Secret []byte
+	Parse  func(body []byte) (domain.GatewayCallback, error)
+}
+
+type handleCallbackUseCase struct {
+	providers map[string]CallbackProvider
+	payments  domain.PaymentRepository
+	refunds   domain.RefundRepository
+	seen      domain.ProcessedMessageStore
+	publisher publisher.Client
+	now       func() time.Time
+}
+
+// NewHandleCallbackUseCase verifies the callbacks of providers and publishes
+// the gateway events they confirm, once checked against the payments and
+// refunds they refer to. seen keeps the callbacks already handled; its TTL
+// must outlast CallbackTolerance.
+func NewHandleCallbackUseCase(
+	providers map[string]CallbackProvider,
+	payments domain.PaymentRepository,
+	refunds domain.RefundRepository,
+	seen domain.ProcessedMessageStore,
+	publisher publisher.Client,
+) *handleCallbackUseCase {
+	return &handleCallbackUseCase{
+		providers,
+		payments,
+		refunds,
+		seen,
+		publisher,
+		time.Now,
+	}
+}
+
+// Execute handles the callback of provider. timestamp is in Unix seconds and
+// signature is the hex HMAC-SHA256 of SignedCallbackPayload. A callback already
+// handled is acknowledged without publishing again.
+func (uc *handleCallbackUseCase) Execute(ctx context.Context, provider, timestamp, signature string, body []byte) error {
+	p, ok := uc.providers[provider]
+	if !ok {
+		return fmt.Errorf("%w: %s", domain.ErrUnknownGatewayProvider, provider)
+	}
+	if err := uc.verify(p.Secret, timestamp, signature, body); err != nil {
+		return err
+	}
+
+	callback, err := p.Parse(body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidCallback, err)
+	}
+	if err := callback.Validate(); err != nil {
+		return err
+	}
+	if err := uc.match(callback); err != nil {
+		return err
+	}
+
+	key := "gateway_callback|" + provider + "|" + callback.ID
+	switch err := uc.seen.Claim(key); {
+	case errors.Is(err, domain.ErrAlreadyProcessed):
+		log.Printf("[Gateway] Dropped duplicate callback %s of %s", callback.ID, provider)
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if err := uc.publish(ctx, provider, callback); err != nil {
+		// The provider retries a failed callback, so it must not look seen.
+		if releaseErr := uc.seen.Release(key); releaseErr != nil {
+			log.Printf("ERROR: [Gateway] could not release callback %s of %s: %v", callback.ID, provider, releaseErr)
+		}
+		return err
+	}
+	if err := uc.seen.Complete(key); err != nil {
+		// The claim keeps the callback seen until it expires.
+		log.Printf("WARN: [Gateway] could not complete callback %s of %s: %v", callback.ID, provider, err)
+	}
+	log.Printf("[Gateway] Callback %s of %s: payment %s %s", callback.ID, provider, callback.PaymentID, callback.Type)
+	return nil
+}
+
+// match checks the callback refers to a payment or refund of this system,
+// for the amount the gateway was asked for. The refund of a capture is
+// checked against its payment. Callbacks without an amount, like most
+// declines, only need the payment or refund to exist.
+func (uc *handleCallbackUseCase) match(callback domain.GatewayCallback) error {
+	refundCallback := callback.Type == domain.GatewayCallbackRefunded || callback.Type == domain.GatewayCallbackRefundFailed
+
+	var expected domain.Money
+	switch {
+	case refundCallback && !callback.AuthorizationRefund():
+		refund, err := uc.refunds.GetByID(callback.RefundID)
+		if errors.Is(err, domain.ErrRefundNotFound) || (err == nil && refund.PaymentID != callback.PaymentID) {
+			return fmt.Errorf("%w: unknown refund %s of payment %s", domain.ErrInvalidCallback, callback.RefundID, callback.PaymentID)
+		}
+		if err != nil {
+			return err
+		}
+		expected = refund.Amount
+	default:
+		pay, err := uc.payments.GetByID(callback.PaymentID)
+		if errors.Is(err, domain.ErrPaymentNotFound) {
+			return fmt.Errorf("%w: unknown payment %s", domain.ErrInvalidCallback, callback.PaymentID)
+		}
+		if err != nil {
+			return err
+		}
+		expected = pay.Amount
+	}
+
+	if !callback.Amount.IsZero() && callback.Amount != expected {
+		return fmt.Errorf("%w: amount %s, expected %s", domain.ErrInvalidCallback, callback.Amount, expected)
+	}
+	return nil
+}
+
+func (uc *handleCallbackUseCase) verify(secret []byte, timestamp, signature string, body []byte) error {
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp", domain.ErrInvalidCallbackSignature)
+	}
+	if age := uc.now().Sub(time.Unix(sent, 0)); age > CallbackTolerance || age < -CallbackTolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", domain.ErrInvalidCallbackSignature)
+	}
+
+	got, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(got, sign(secret, timestamp, body)) {
+		return domain.ErrInvalidCallbackSignature
+	}
+	return nil
+}
+
+func (uc *handleCallbackUseCase) publish(ctx context.Context, provider string, callback domain.GatewayCallback) error {
+	metadata := domain.CommandEventMetadata{
+		MessageGroupID:         callback.PaymentID,
+		MessageDeduplicationId: domain.BuildDeduplicationId("gateway_callback", provider, callback.ID),
+	}
+	timestamp := uc.now().UTC().Format(time.RFC3339)
+
+	var topic string
+	var event any
+	// The answers to the payment saga carry the payment at the top level.
+	answer := func(answerTopic string, failed bool) {
+		topic = answerTopic
+		command := domain.CommandEvent{EventType: topic, EventVersion: "1", Timestamp: timestamp, CommandEventMetadata: metadata}
+		if !failed {
+			event = domain.GatewayAuthorizedEvent{CommandEvent: command, PaymentID: callback.PaymentID, Amount: callback.Amount}
+			return
+		}
+		event = domain.GatewayAuthorizationFailedEvent{
+			CommandEvent: command,
+			PaymentID:    callback.PaymentID,
+			Amount:       callback.Amount,
+			Reason:       callback.Reason,
+		}
+	}
+
+	switch {
+	case callback.Type == domain.GatewayCallbackAuthorized:
+		answer(domain.TopicGatewayAuthorized, false)
+	case callback.Type == domain.GatewayCallbackAuthorizationFailed:
+		answer(domain.TopicGatewayAuthorizationFailed, true)
+	case callback.Type == domain.GatewayCallbackCaptured:
+		answer(domain.TopicGatewayCaptured, false)
+	case callback.Type == domain.GatewayCallbackCaptureFailed:
+		answer(domain.TopicGatewayCaptureFailed, true)
+	case callback.AuthorizationRefund() && callback.Type == domain.GatewayCallbackRefunded:
+		answer(domain.TopicGatewayAuthorizationRefunded, false)
+	case callback.AuthorizationRefund() && callback.Type == domain.GatewayCallbackRefundFailed:
+		answer(domain.TopicGatewayAuthorizationRefundFailed, true)
+	case callback.Type == domain.GatewayCallbackRefunded, callback.Type == domain.GatewayCallbackRefundFailed:
+		topic = domain.TopicGatewayRefunded
+		if callback.Type == domain.GatewayCallbackRefundFailed {
+			topic = domain.TopicGatewayRefundFailed
+		}
+		metadata.MessageGroupID = callback.RefundID
+		event = domain.RefundCommandEvent{
+			CommandEvent: domain.CommandEvent{EventType: topic, EventVersion: "1", Timestamp: timestamp, CommandEventMetadata: metadata},
+			RefundCommandEventPayload: domain.RefundCommandEventPayload{
+				RefundID:  callback.RefundID,
+				PaymentID: callback.PaymentID,
+				Amount:    callback.Amount,
+				Reason:    callback.Reason,
+			},
+		}
+	default:
+		return domain.ErrInvalidCallback
+	}
+
+	msg, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return uc.publisher.Publish(ctx, topic, msg)
+}
+
+// SignedCallbackPayload is what a provider signs: the timestamp it sends along
+// and the raw body, so neither can be changed on their own.
+func SignedCallbackPayload(timestamp string, body []byte) []byte {
+	return append([]byte(timestamp+"."), body...)
+}
+
+// SignCallback returns the signature a provider sends with body, as the
+// simulated provider and the tests do.
+func SignCallback(secret []byte, timestamp string, body []byte) string {
+	return hex.EncodeToString(sign(secret, timestamp, body))
+}
+
+func sign(secret []byte, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(SignedCallbackPayload(timestamp, body))
+	return mac.Sum(nil)
+}
+
+// simulatedCallback is the payload of the callbacks of the simulated provider.
+type simulatedCallback struct {
+	ID        string       `json:"id"`
+	Event     string       `json:"event"`
+	PaymentID string       `json:"payment_id"`
+	RefundID  string       `json:"refund_id"`
+	Amount    domain.Money `json:"amount"`
+	Reason    string       `json:"reason"`
+}
+
+var simulatedCallbackTypes = map[string]domain.GatewayCallbackType{
+	"payment.authorized": domain.GatewayCallbackAuthorized,
+	"payment.declined":   domain.GatewayCallbackAuthorizationFailed,
+	"payment.captured":   domain.GatewayCallbackCaptured,
+	"capture.declined":   domain.GatewayCallbackCaptureFailed,
+	"refund.succeeded":   domain.GatewayCallbackRefunded,
+	"refund.declined":    domain.GatewayCallbackRefundFailed,
+}
+
+// ParseSimulatedCallback maps a callback of the simulated provider.
+func ParseSimulatedCallback(body []byte) (domain.GatewayCallback, error) {
+	var payload simulatedCallback
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return domain.GatewayCallback{}, err
+	}
+	callbackType, ok := simulatedCallbackTypes[payload.Event]
+	if !ok {
+		return domain.GatewayCallback{}, errors.New("unknown event " + payload.Event)
+	}
+	return domain.GatewayCallback{
+		ID:        payload.ID,
+		Type:      callbackType,
+		PaymentID: payload.PaymentID,
+		RefundID:  payload.RefundID,
+		Amount:    payload.Amount,
+		Reason:    payload.Reason,
+	}, nil
+}
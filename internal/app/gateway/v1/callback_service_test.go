@@ -0,0 +1,347 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockPublisher struct {
+	mock.Mock
+}
+
+func (m *MockPublisher) Publish(ctx context.Context, topic string, message []byte) error {
+	args := m.Called(ctx, topic, message)
+	return args.Error(0)
+}
+
+var callbackSecret = []byte("secret")
+
+// newCallbackDB holds payment-123, a completed payment of 10.00 USD, and
+// refund-1, a refund of 4.00 USD of it.
+func newCallbackDB(t *testing.T) *database.DB {
+	db := database.OpenInMemory()
+	amount := domain.NewMoney(10_00, "USD")
+	require.NoError(t, database.NewPaymentRepository(db).Create(domain.Payment{
+		ID:       "payment-123",
+		WalletID: "user-123",
+		Amount:   amount,
+		Method:   "card",
+		Status:   domain.PaymentStatusCompleted,
+	}))
+	require.NoError(t, database.NewRefundablePaymentRepository(db).Add(domain.RefundablePayment{
+		ID:           "payment-123",
+		WalletID:     "user-123",
+		Method:       "card",
+		Amount:       amount,
+		WalletAmount: amount,
+		Status:       domain.PaymentStatusCompleted,
+	}))
+	require.NoError(t, database.NewRefundRepository(db).Create(domain.Refund{
+		ID:           "refund-1",
+		PaymentID:    "payment-123",
+		WalletID:     "user-123",
+		Amount:       domain.NewMoney(4_00, "USD"),
+		WalletAmount: domain.NewMoney(4_00, "USD"),
+		Status:       domain.RefundStatusPending,
+	}))
+	return db
+}
+
+func newCallbackUseCase(db *database.DB, pub *MockPublisher, now time.Time) *handleCallbackUseCase {
+	uc := NewHandleCallbackUseCase(map[string]CallbackProvider{
+		SimulatedProviderName: {Secret: callbackSecret, Parse: ParseSimulatedCallback},
+	}, database.NewPaymentRepository(db), database.NewRefundRepository(db), database.NewProcessedMessageRepository(db, time.Hour), pub)
+	uc.now = func() time.Time { return now }
+	return uc
+}
+
+func TestHandleCallbackUseCase_PublishesGatewayEvents(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		expectedTopic string
+		assertEvent   func(t *testing.T, msg []byte)
+	}{
+		{
+			name:          "authorized",
+			body:          `{"id":"evt-1","event":"payment.authorized","payment_id":"payment-123","amount":{"amount":"10.00","currency":"USD"}}`,
+			expectedTopic: domain.TopicGatewayAuthorized,
+			assertEvent: func(t *testing.T, msg []byte) {
+				var ev domain.GatewayAuthorizedEvent
+				require.NoError(t, json.Unmarshal(msg, &ev))
+				assert.Equal(t, "payment-123", ev.PaymentID)
+				assert.Equal(t, domain.NewMoney(10_00, "USD"), ev.Amount)
+				assert.Equal(t, "gateway_callback.simulated.evt-1", ev.MessageDeduplicationId)
+			},
+		},
+		{
+			name:          "authorization failed",
+			body:          `{"id":"evt-2","event":"payment.declined","payment_id":"payment-123","reason":"insufficient funds"}`,
+			expectedTopic: domain.TopicGatewayAuthorizationFailed,
+			assertEvent: func(t *testing.T, msg []byte) {
+				var ev domain.GatewayAuthorizationFailedEvent
+				require.NoError(t, json.Unmarshal(msg, &ev))
+				assert.Equal(t, "payment-123", ev.PaymentID)
+				assert.Equal(t, "insufficient funds", ev.Reason)
+			},
+		},
+		{
+			name:          "captured",
+			body:          `{"id":"evt-5","event":"payment.captured","payment_id":"payment-123","amount":{"amount":"10.00","currency":"USD"}}`,
+			expectedTopic: domain.TopicGatewayCaptured,
+			assertEvent: func(t *testing.T, msg []byte) {
+				var ev domain.GatewayAuthorizedEvent
+				require.NoError(t, json.Unmarshal(msg, &ev))
+				assert.Equal(t, domain.TopicGatewayCaptured, ev.EventType)
+				assert.Equal(t, "payment-123", ev.PaymentID)
+			},
+		},
+		{
+			name:          "capture failed",
+			body:          `{"id":"evt-6","event":"capture.declined","payment_id":"payment-123","reason":"authorization expired"}`,
+			expectedTopic: domain.TopicGatewayCaptureFailed,
+			assertEvent: func(t *testing.T, msg []byte) {
+				var ev domain.GatewayAuthorizationFailedEvent
+				require.NoError(t, json.Unmarshal(msg, &ev))
+				assert.Equal(t, "payment-123", ev.PaymentID)
+				assert.Equal(t, "authorization expired", ev.Reason)
+			},
+		},
+		{
+			name:          "capture refunded",
+			body:          `{"id":"evt-7","event":"refund.succeeded","payment_id":"payment-123","refund_id":"authorization.payment-123","amount":{"amount":"10.00","currency":"USD"}}`,
+			expectedTopic: domain.TopicGatewayAuthorizationRefunded,
+			assertEvent: func(t *testing.T, msg []byte) {
+				var ev domain.GatewayAuthorizedEvent
+				require.NoError(t, json.Unmarshal(msg, &ev))
+				assert.Equal(t, domain.TopicGatewayAuthorizationRefunded, ev.EventType)
+				assert.Equal(t, "payment-123", ev.PaymentID)
+			},
+		},
+		{
+			name:          "capture refund failed",
+			body:          `{"id":"evt-8","event":"refund.declined","payment_id":"payment-123","refund_id":"authorization.payment-123","reason":"closed card"}`,
+			expectedTopic: domain.TopicGatewayAuthorizationRefundFailed,
+			assertEvent: func(t *testing.T, msg []byte) {
+				var ev domain.GatewayAuthorizationFailedEvent
+				require.NoError(t, json.Unmarshal(msg, &ev))
+				assert.Equal(t, "payment-123", ev.PaymentID)
+				assert.Equal(t, "closed card", ev.Reason)
+			},
+		},
+		{
+			name:          "refunded",
+			body:          `{"id":"evt-3","event":"refund.succeeded","payment_id":"payment-123","refund_id":"refund-1"}`,
+			expectedTopic: domain.TopicGatewayRefunded,
+			assertEvent: func(t *testing.T, msg []byte) {
+				var ev domain.RefundCommandEvent
+				require.NoError(t, json.Unmarshal(msg, &ev))
+				assert.Equal(t, domain.TopicGatewayRefunded, ev.EventType)
+				assert.Equal(t, "refund-1", ev.RefundID)
+				assert.Equal(t, "payment-123", ev.PaymentID)
+			},
+		},
+		{
+			name:          "refund failed",
+			body:          `{"id":"evt-4","event":"refund.declined","payment_id":"payment-123","refund_id":"refund-1","reason":"closed card"}`,
+			expectedTopic: domain.TopicGatewayRefundFailed,
+			assertEvent: func(t *testing.T, msg []byte) {
+				var ev domain.RefundCommandEvent
+				require.NoError(t, json.Unmarshal(msg, &ev))
+				assert.Equal(t, "refund-1", ev.RefundID)
+				assert.Equal(t, "closed card", ev.Reason)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			pub := new(MockPublisher)
+			pub.On("Publish", mock.Anything, tt.expectedTopic, mock.Anything).Return(nil).Once()
+			uc := newCallbackUseCase(newCallbackDB(t), pub, now)
+
+			timestamp := strconv.FormatInt(now.Unix(), 10)
+			err := uc.Execute(context.Background(), SimulatedProviderName, timestamp, SignCallback(callbackSecret, timestamp, []byte(tt.body)), []byte(tt.body))
+
+			require.NoError(t, err)
+			pub.AssertExpectations(t)
+			tt.assertEvent(t, pub.Calls[0].Arguments.Get(2).([]byte))
+		})
+	}
+}
+
+func TestHandleCallbackUseCase_Rejects(t *testing.T) {
+	now := time.Now()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	stale := strconv.FormatInt(now.Add(-CallbackTolerance-time.Second).Unix(), 10)
+	body := []byte(`{"id":"evt-1","event":"payment.authorized","payment_id":"payment-123"}`)
+
+	tests := []struct {
+		name          string
+		provider      string
+		timestamp     string
+		signature     string
+		body          []byte
+		expectedError error
+	}{
+		{
+			name:          "unknown provider",
+			provider:      "acme",
+			timestamp:     timestamp,
+			signature:     SignCallback(callbackSecret, timestamp, body),
+			body:          body,
+			expectedError: domain.ErrUnknownGatewayProvider,
+		},
+		{
+			name:          "signed with another secret",
+			provider:      SimulatedProviderName,
+			timestamp:     timestamp,
+			signature:     SignCallback([]byte("other"), timestamp, body),
+			body:          body,
+			expectedError: domain.ErrInvalidCallbackSignature,
+		},
+		{
+			name:          "body changed after signing",
+			provider:      SimulatedProviderName,
+			timestamp:     timestamp,
+			signature:     SignCallback(callbackSecret, timestamp, body),
+			body:          []byte(`{"id":"evt-1","event":"payment.declined","payment_id":"payment-123"}`),
+			expectedError: domain.ErrInvalidCallbackSignature,
+		},
+		{
+			name:          "missing signature",
+			provider:      SimulatedProviderName,
+			timestamp:     timestamp,
+			body:          body,
+			expectedError: domain.ErrInvalidCallbackSignature,
+		},
+		{
+			name:          "timestamp outside the tolerance",
+			provider:      SimulatedProviderName,
+			timestamp:     stale,
+			signature:     SignCallback(callbackSecret, stale, body),
+			body:          body,
+			expectedError: domain.ErrInvalidCallbackSignature,
+		},
+		{
+			name:          "unknown event",
+			provider:      SimulatedProviderName,
+			timestamp:     timestamp,
+			signature:     SignCallback(callbackSecret, timestamp, []byte(`{"id":"evt-1","event":"payment.disputed","payment_id":"payment-123"}`)),
+			body:          []byte(`{"id":"evt-1","event":"payment.disputed","payment_id":"payment-123"}`),
+			expectedError: domain.ErrInvalidCallback,
+		},
+		{
+			name:          "unknown payment",
+			provider:      SimulatedProviderName,
+			timestamp:     timestamp,
+			signature:     SignCallback(callbackSecret, timestamp, []byte(`{"id":"evt-1","event":"payment.authorized","payment_id":"payment-456"}`)),
+			body:          []byte(`{"id":"evt-1","event":"payment.authorized","payment_id":"payment-456"}`),
+			expectedError: domain.ErrInvalidCallback,
+		},
+		{
+			name:          "amount of another payment",
+			provider:      SimulatedProviderName,
+			timestamp:     timestamp,
+			signature:     SignCallback(callbackSecret, timestamp, []byte(`{"id":"evt-1","event":"payment.authorized","payment_id":"payment-123","amount":{"amount":"1000.00","currency":"USD"}}`)),
+			body:          []byte(`{"id":"evt-1","event":"payment.authorized","payment_id":"payment-123","amount":{"amount":"1000.00","currency":"USD"}}`),
+			expectedError: domain.ErrInvalidCallback,
+		},
+		{
+			name:          "refund of another payment",
+			provider:      SimulatedProviderName,
+			timestamp:     timestamp,
+			signature:     SignCallback(callbackSecret, timestamp, []byte(`{"id":"evt-1","event":"refund.succeeded","payment_id":"payment-456","refund_id":"refund-1"}`)),
+			body:          []byte(`{"id":"evt-1","event":"refund.succeeded","payment_id":"payment-456","refund_id":"refund-1"}`),
+			expectedError: domain.ErrInvalidCallback,
+		},
+		{
+			name:          "capture refund of another amount",
+			provider:      SimulatedProviderName,
+			timestamp:     timestamp,
+			signature:     SignCallback(callbackSecret, timestamp, []byte(`{"id":"evt-1","event":"refund.succeeded","payment_id":"payment-123","refund_id":"authorization.payment-123","amount":{"amount":"4.00","currency":"USD"}}`)),
+			body:          []byte(`{"id":"evt-1","event":"refund.succeeded","payment_id":"payment-123","refund_id":"authorization.payment-123","amount":{"amount":"4.00","currency":"USD"}}`),
+			expectedError: domain.ErrInvalidCallback,
+		},
+		{
+			name:          "refund without refund id",
+			provider:      SimulatedProviderName,
+			timestamp:     timestamp,
+			signature:     SignCallback(callbackSecret, timestamp, []byte(`{"id":"evt-1","event":"refund.succeeded","payment_id":"payment-123"}`)),
+			body:          []byte(`{"id":"evt-1","event":"refund.succeeded","payment_id":"payment-123"}`),
+			expectedError: domain.ErrInvalidCallback,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub := new(MockPublisher)
+			uc := newCallbackUseCase(newCallbackDB(t), pub, now)
+
+			err := uc.Execute(context.Background(), tt.provider, tt.timestamp, tt.signature, tt.body)
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			pub.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandleCallbackUseCase_ReplayIsAcknowledgedOnce(t *testing.T) {
+	now := time.Now()
+	pub := new(MockPublisher)
+	pub.On("Publish", mock.Anything, domain.TopicGatewayAuthorized, mock.Anything).Return(nil).Once()
+	uc := newCallbackUseCase(newCallbackDB(t), pub, now)
+
+	body := []byte(`{"id":"evt-1","event":"payment.authorized","payment_id":"payment-123"}`)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := SignCallback(callbackSecret, timestamp, body)
+
+	require.NoError(t, uc.Execute(context.Background(), SimulatedProviderName, timestamp, signature, body))
+	require.NoError(t, uc.Execute(context.Background(), SimulatedProviderName, timestamp, signature, body))
+
+	pub.AssertNumberOfCalls(t, "Publish", 1)
+}
+
+func TestHandleCallbackUseCase_ReplayToAnotherInstanceIsAcknowledged(t *testing.T) {
+	now := time.Now()
+	db := newCallbackDB(t)
+	pub := new(MockPublisher)
+	pub.On("Publish", mock.Anything, domain.TopicGatewayAuthorized, mock.Anything).Return(nil).Once()
+
+	body := []byte(`{"id":"evt-1","event":"payment.authorized","payment_id":"payment-123"}`)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := SignCallback(callbackSecret, timestamp, body)
+
+	require.NoError(t, newCallbackUseCase(db, pub, now).Execute(context.Background(), SimulatedProviderName, timestamp, signature, body))
+	require.NoError(t, newCallbackUseCase(db, pub, now).Execute(context.Background(), SimulatedProviderName, timestamp, signature, body))
+
+	pub.AssertNumberOfCalls(t, "Publish", 1)
+}
+
+func TestHandleCallbackUseCase_FailedPublishIsRetried(t *testing.T) {
+	now := time.Now()
+	pub := new(MockPublisher)
+	pub.On("Publish", mock.Anything, domain.TopicGatewayAuthorized, mock.Anything).Return(errors.New("bus down")).Once()
+	pub.On("Publish", mock.Anything, domain.TopicGatewayAuthorized, mock.Anything).Return(nil).Once()
+	uc := newCallbackUseCase(newCallbackDB(t), pub, now)
+
+	body := []byte(`{"id":"evt-1","event":"payment.authorized","payment_id":"payment-123"}`)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := SignCallback(callbackSecret, timestamp, body)
+
+	assert.Error(t, uc.Execute(context.Background(), SimulatedProviderName, timestamp, signature, body))
+	assert.NoError(t, uc.Execute(context.Background(), SimulatedProviderName, timestamp, signature, body))
+
+	pub.AssertExpectations(t)
+}
@@ -0,0 +1,98 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+type providerRegistry struct {
+	defaultMethod string
+	providers     map[string]domain.PaymentProvider
+}
+
+// NewProviderRegistry selects the provider of each payment method.
+// Commands sent before they carried the method use defaultMethod.
+func NewProviderRegistry(
+	defaultMethod string,
+	providers map[string]domain.PaymentProvider,
+) *providerRegistry {
+	return &providerRegistry{
+		defaultMethod,
+		providers,
+	}
+}
+
+func (r *providerRegistry) Provider(method string) (domain.PaymentProvider, error) {
+	if method == "" {
+		method = r.defaultMethod
+	}
+	provider, ok := r.providers[method]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", domain.ErrUnsupportedPaymentMethod, method)
+	}
+	return provider, nil
+}
+
+// simulatedProvidersFile is the format of a simulated providers file, keyed by
+// payment method:
+//
+//	{
+//	  "default_method": "card",
+//	  "providers": {
+//	    "card": {
+//	      "latency": "200ms", "error_rate": 0.1, "seed": 42,
+//	      "declines": [{"bin": "400002", "reason": "card declined"}]
+//	    }
+//	  }
+//	}
+type simulatedProvidersFile struct {
+	DefaultMethod string `json:"default_method"`
+	Providers     map[string]struct {
+		Latency   string  `json:"latency"`
+		ErrorRate float64 `json:"error_rate"`
+		Seed      uint64  `json:"seed"`
+		Declines  []struct {
+			MinAmount *domain.Money `json:"min_amount"`
+			Token     string        `json:"token"`
+			BIN       string        `json:"bin"`
+			Reason    string        `json:"reason"`
+		} `json:"declines"`
+	} `json:"providers"`
+}
+
+// LoadSimulatedProviders builds a registry with a simulated provider for
+// every payment method listed in the file at path.
+func LoadSimulatedProviders(path string) (*providerRegistry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read payment providers: %w", err)
+	}
+
+	var file simulatedProvidersFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("decode payment providers %s: %w", path, err)
+	}
+
+	providers := map[string]domain.PaymentProvider{}
+	for method, p := range file.Providers {
+		config := SimulatedProviderConfig{ErrorRate: p.ErrorRate, Seed: p.Seed}
+		if p.Latency != "" {
+			if config.Latency, err = time.ParseDuration(p.Latency); err != nil {
+				return nil, fmt.Errorf("payment providers %s: latency of %s: %w", path, method, err)
+			}
+		}
+		for _, d := range p.Declines {
+			config.Declines = append(config.Declines, DeclineRule{MinAmount: d.MinAmount, Token: d.Token, BIN: d.BIN, Reason: d.Reason})
+		}
+		providers[method] = NewSimulatedProvider(SimulatedProviderName+"."+method, config)
+	}
+
+	if _, ok := providers[file.DefaultMethod]; !ok {
+		return nil, fmt.Errorf("payment providers %s: %w: default %q", path, domain.ErrUnsupportedPaymentMethod, file.DefaultMethod)
+	}
+	return NewProviderRegistry(file.DefaultMethod, providers), nil
+}
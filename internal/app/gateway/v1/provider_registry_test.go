@@ -0,0 +1,49 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderRegistry_Provider(t *testing.T) {
+	card := NewSimulatedProvider("simulated.card", SimulatedProviderConfig{})
+	registry := NewProviderRegistry("card", map[string]domain.PaymentProvider{"card": card})
+
+	provider, err := registry.Provider("card")
+	require.NoError(t, err)
+	assert.Same(t, card, provider)
+
+	provider, err = registry.Provider("")
+	require.NoError(t, err)
+	assert.Same(t, card, provider, "commands without a method use the default")
+
+	_, err = registry.Provider("crypto")
+	assert.ErrorIs(t, err, domain.ErrUnsupportedPaymentMethod)
+}
+
+func TestLoadSimulatedProviders(t *testing.T) {
+	registry, err := LoadSimulatedProviders("testdata/providers.json")
+	require.NoError(t, err)
+
+	card, err := registry.Provider("card")
+	require.NoError(t, err)
+	assert.ErrorIs(t, card.Authorize(context.Background(), "payment-123", domain.NewMoney(10_00, "USD"), "4000020000000000"), domain.ErrGatewayDeclined)
+	assert.ErrorIs(t, card.Authorize(context.Background(), "payment-123", domain.NewMoney(150_00, "USD"), "4242424242424242"), domain.ErrGatewayDeclined)
+	assert.NoError(t, card.Authorize(context.Background(), "payment-123", domain.NewMoney(10_00, "USD"), "4242424242424242"))
+
+	balance, err := registry.Provider("balance")
+	require.NoError(t, err)
+	assert.NoError(t, balance.Authorize(context.Background(), "payment-123", domain.NewMoney(150_00, "USD"), "4000020000000000"))
+}
+
+func TestLoadSimulatedProviders_Errors(t *testing.T) {
+	_, err := LoadSimulatedProviders("testdata/unknown_default.json")
+	assert.ErrorIs(t, err, domain.ErrUnsupportedPaymentMethod)
+
+	_, err = LoadSimulatedProviders("testdata/missing.json")
+	assert.Error(t, err)
+}
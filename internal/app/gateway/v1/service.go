@@ -0,0 +1,142 @@
+package v1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+// SimulatedProviderConfig tunes a simulated provider. Its answers only depend
+// on the config and the operation, payment and attempt of each call, so runs
+// with the same config behave the same whatever order the calls arrive in.
+type SimulatedProviderConfig struct {
+	// Latency is how long every call takes.
+	Latency time.Duration
+	// ErrorRate is the share of calls failing with ErrGatewayUnavailable.
+	// Whether a call fails is drawn from a hash of Seed, the operation, the
+	// payment or refund it is for and how many times it failed before, so
+	// its retries are drawn apart.
+	ErrorRate float64
+	Seed      uint64
+	Declines  []DeclineRule
+}
+
+// DeclineRule declines the authorizations matching every field it sets.
+type DeclineRule struct {
+	// MinAmount matches amounts of its currency from it up.
+	MinAmount *domain.Money
+	Token     string
+	// BIN matches card tokens starting with the bank identification number.
+	BIN    string
+	Reason string
+}
+
+func (r DeclineRule) matches(amount domain.Money, token string) bool {
+	if r.MinAmount != nil {
+		if below, err := amount.LessThan(*r.MinAmount); err != nil || below {
+			return false
+		}
+	}
+	if r.Token != "" && r.Token != token {
+		return false
+	}
+	if r.BIN != "" && !strings.HasPrefix(token, r.BIN) {
+		return false
+	}
+	return true
+}
+
+// simulatedProvider stands in for an external payment provider.
+type simulatedProvider struct {
+	name   string
+	config SimulatedProviderConfig
+
+	// failures counts the failed calls of every operation and id until one
+	// succeeds.
+	mu       sync.Mutex
+	failures map[string]uint64
+}
+
+func NewSimulatedProvider(
+	name string,
+	config SimulatedProviderConfig,
+) *simulatedProvider {
+	return &simulatedProvider{
+		name:     name,
+		config:   config,
+		failures: map[string]uint64{},
+	}
+}
+
+func (p *simulatedProvider) Authorize(ctx context.Context, paymentId string, amount domain.Money, token string) error {
+	if err := p.call(ctx, "authorize", paymentId); err != nil {
+		return err
+	}
+	for _, rule := range p.config.Declines {
+		if rule.matches(amount, token) {
+			return fmt.Errorf("%w: %s", domain.ErrGatewayDeclined, rule.Reason)
+		}
+	}
+	log.Printf("[Gateway] Payment %s of %s authorized by %s", paymentId, amount, p.name)
+	return nil
+}
+
+func (p *simulatedProvider) Capture(ctx context.Context, paymentId string, amount domain.Money) error {
+	if err := p.call(ctx, "capture", paymentId); err != nil {
+		return err
+	}
+	log.Printf("[Gateway] Payment %s of %s captured by %s", paymentId, amount, p.name)
+	return nil
+}
+
+func (p *simulatedProvider) Void(ctx context.Context, paymentId string) error {
+	if err := p.call(ctx, "void", paymentId); err != nil {
+		return err
+	}
+	log.Printf("[Gateway] Authorization of payment %s voided by %s", paymentId, p.name)
+	return nil
+}
+
+func (p *simulatedProvider) Refund(ctx context.Context, refundId, paymentId string, amount domain.Money) error {
+	if err := p.call(ctx, "refund", refundId); err != nil {
+		return err
+	}
+	log.Printf("[Gateway] Refund %s of %s for payment %s accepted by %s", refundId, amount, paymentId, p.name)
+	return nil
+}
+
+// call waits for the latency of the provider and fails operation on id at
+// the configured error rate.
+func (p *simulatedProvider) call(ctx context.Context, operation, id string) error {
+	select {
+	case <-time.After(p.config.Latency):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	key := operation + "|" + id
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.draw(key, p.failures[key]) < p.config.ErrorRate {
+		p.failures[key]++
+		return fmt.Errorf("%w: %s", domain.ErrGatewayUnavailable, p.name)
+	}
+	delete(p.failures, key)
+	return nil
+}
+
+// draw maps the seed, the key of a call and its attempt to a number in [0, 1).
+func (p *simulatedProvider) draw(key string, attempt uint64) float64 {
+	h := sha256.New()
+	binary.Write(h, binary.BigEndian, p.config.Seed)
+	h.Write([]byte(key))
+	binary.Write(h, binary.BigEndian, attempt)
+	return float64(binary.BigEndian.Uint64(h.Sum(nil))>>11) / (1 << 53)
+}
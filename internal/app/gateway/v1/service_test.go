@@ -0,0 +1,121 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulatedProvider(t *testing.T) {
+	provider := NewSimulatedProvider("simulated.card", SimulatedProviderConfig{Latency: time.Millisecond})
+	amount := domain.NewMoney(10_00, "USD")
+
+	assert.NoError(t, provider.Authorize(context.Background(), "payment-123", amount, "token-789"))
+	assert.NoError(t, provider.Capture(context.Background(), "payment-123", amount))
+	assert.NoError(t, provider.Void(context.Background(), "payment-123"))
+	assert.NoError(t, provider.Refund(context.Background(), "refund-1", "payment-123", amount))
+}
+
+func TestSimulatedProvider_Declines(t *testing.T) {
+	limit := domain.NewMoney(100_00, "USD")
+	provider := NewSimulatedProvider("simulated.card", SimulatedProviderConfig{Declines: []DeclineRule{
+		{BIN: "400002", Reason: "card declined"},
+		{Token: "tok_insufficient_funds", Reason: "insufficient funds"},
+		{MinAmount: &limit, Reason: "over the card limit"},
+	}})
+
+	tests := []struct {
+		name           string
+		amount         domain.Money
+		token          string
+		expectedReason string
+	}{
+		{name: "authorized", amount: domain.NewMoney(10_00, "USD"), token: "4242424242424242"},
+		{name: "card BIN", amount: domain.NewMoney(10_00, "USD"), token: "4000020000000000", expectedReason: "card declined"},
+		{name: "token", amount: domain.NewMoney(10_00, "USD"), token: "tok_insufficient_funds", expectedReason: "insufficient funds"},
+		{name: "amount at the limit", amount: domain.NewMoney(100_00, "USD"), token: "4242424242424242", expectedReason: "over the card limit"},
+		{name: "amount in another currency", amount: domain.NewMoney(100_00, "EUR"), token: "4242424242424242"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := provider.Authorize(context.Background(), "payment-123", tt.amount, tt.token)
+
+			if tt.expectedReason == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, domain.ErrGatewayDeclined)
+			assert.ErrorContains(t, err, tt.expectedReason)
+		})
+	}
+}
+
+func TestSimulatedProvider_ErrorRateIsDeterministic(t *testing.T) {
+	failures := func(provider *simulatedProvider, ids []int) []bool {
+		failed := make([]bool, len(ids))
+		for _, i := range ids {
+			err := provider.Capture(context.Background(), fmt.Sprintf("payment-%d", i), domain.NewMoney(10_00, "USD"))
+			if err != nil {
+				assert.ErrorIs(t, err, domain.ErrGatewayUnavailable)
+			}
+			failed[i] = err != nil
+		}
+		return failed
+	}
+	config := SimulatedProviderConfig{ErrorRate: 0.5, Seed: 42}
+	ids, reversed := make([]int, 20), make([]int, 20)
+	for i := range ids {
+		ids[i], reversed[len(ids)-1-i] = i, i
+	}
+
+	// The outcome of a payment does not depend on the calls before it.
+	first := failures(NewSimulatedProvider("simulated.card", config), ids)
+	assert.Equal(t, first, failures(NewSimulatedProvider("simulated.card", config), reversed))
+	assert.Contains(t, first, true)
+	assert.Contains(t, first, false)
+
+	// Other operations on the same payments are drawn apart.
+	provider := NewSimulatedProvider("simulated.card", config)
+	var differs bool
+	for i := range ids {
+		voidFailed := provider.Void(context.Background(), fmt.Sprintf("payment-%d", i)) != nil
+		differs = differs || voidFailed != first[i]
+	}
+	assert.True(t, differs)
+}
+
+func TestSimulatedProvider_RetriesOfAFailedCallAreDrawnAgain(t *testing.T) {
+	provider := NewSimulatedProvider("simulated.card", SimulatedProviderConfig{ErrorRate: 0.5, Seed: 42})
+	capture := func(paymentId string) error {
+		return provider.Capture(context.Background(), paymentId, domain.NewMoney(10_00, "USD"))
+	}
+
+	var failing string
+	for i := 0; failing == ""; i++ {
+		if id := fmt.Sprintf("payment-%d", i); capture(id) != nil {
+			failing = id
+		}
+	}
+
+	var retried bool
+	for range 50 {
+		if capture(failing) == nil {
+			retried = true
+			break
+		}
+	}
+	assert.True(t, retried, "retries of %s never succeeded", failing)
+}
+
+func TestSimulatedProvider_StopsWhenContextIsDone(t *testing.T) {
+	provider := NewSimulatedProvider("simulated.card", SimulatedProviderConfig{Latency: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.ErrorIs(t, provider.Refund(ctx, "refund-1", "payment-123", domain.NewMoney(10_00, "USD")), context.Canceled)
+}
@@ -0,0 +1,72 @@
+package v1
+
+import (
+	"strings"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// sagaDurationBuckets go from the fastest payments, a few gateway round trips,
+// to the sagas stuck until a step timed out.
+var sagaDurationBuckets = []float64{0.25, 0.5, 1, 2, 5, 10, 30, 60, 120}
+
+// metricsService aggregates the metric events of the services into
+// Prometheus counters and histograms.
+type metricsService struct {
+	events       *prometheus.CounterVec
+	failures     *prometheus.CounterVec
+	stepTimeouts *prometheus.CounterVec
+	sagaDuration *prometheus.HistogramVec
+}
+
+func NewMetricsService(
+	registerer prometheus.Registerer,
+) *metricsService {
+	s := &metricsService{
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "golearn_metric_events_total",
+			Help: "Metric events received, by metric.",
+		}, []string{"metric"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "golearn_failures_total",
+			Help: "Failures reported by the services, by metric and reason.",
+		}, []string{"metric", domain.MetricLabelReason}),
+		stepTimeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "golearn_saga_step_timeouts_total",
+			Help: "Saga steps not answered before their deadline.",
+		}, []string{domain.MetricLabelSaga, domain.MetricLabelStep}),
+		sagaDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "golearn_saga_duration_seconds",
+			Help:    "Time from the start of a saga to its end, by outcome.",
+			Buckets: sagaDurationBuckets,
+		}, []string{domain.MetricLabelSaga, domain.MetricLabelOutcome}),
+	}
+	registerer.MustRegister(s.events, s.failures, s.stepTimeouts, s.sagaDuration)
+	return s
+}
+
+// Record adds a metric event to the aggregates its labels belong to: every
+// event is counted, the ones with a reason are failures and the ones with an
+// outcome end a saga that lasted Value seconds.
+func (s *metricsService) Record(m domain.MetricEventPayload) {
+	s.events.WithLabelValues(m.Metric).Inc()
+
+	if reason := m.Labels[domain.MetricLabelReason]; reason != "" {
+		s.failures.WithLabelValues(m.Metric, reasonLabel(reason)).Inc()
+	}
+	if m.Metric == domain.MetricSagaStepTimeout {
+		s.stepTimeouts.WithLabelValues(m.Labels[domain.MetricLabelSaga], m.Labels[domain.MetricLabelStep]).Inc()
+	}
+	if outcome := m.Labels[domain.MetricLabelOutcome]; outcome != "" {
+		s.sagaDuration.WithLabelValues(m.Labels[domain.MetricLabelSaga], outcome).Observe(m.Value)
+	}
+}
+
+// reasonLabel keeps the error of a reason without the details wrapped after
+// it, e.g. the amounts of a currency mismatch, so every payment does not open
+// a new series.
+func reasonLabel(reason string) string {
+	reason, _, _ = strings.Cut(reason, ":")
+	return reason
+}
@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsService_Record(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	s := NewMetricsService(registry)
+
+	s.Record(domain.MetricEventPayload{
+		Metric: domain.MetricPaymentSuccess,
+		Labels: map[string]string{domain.MetricLabelSaga: "payment", domain.MetricLabelOutcome: "completed"},
+		Value:  0.8,
+	})
+	s.Record(domain.MetricEventPayload{
+		Metric: domain.MetricPaymentFailure,
+		Labels: map[string]string{
+			domain.MetricLabelSaga:    "payment",
+			domain.MetricLabelOutcome: "failed",
+			domain.MetricLabelReason:  "currency mismatch: EUR and USD",
+		},
+		Value: 3,
+	})
+	s.Record(domain.MetricEventPayload{
+		Metric: domain.MetricWalletHoldFundsFailure,
+		Labels: map[string]string{domain.MetricLabelReason: "insufficient funds"},
+	})
+	s.Record(domain.MetricEventPayload{
+		Metric: domain.MetricSagaStepTimeout,
+		Labels: map[string]string{domain.MetricLabelSaga: "payment", domain.MetricLabelStep: "AUTHORIZING"},
+	})
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(s.events.WithLabelValues(domain.MetricPaymentSuccess)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(s.failures.WithLabelValues(domain.MetricPaymentFailure, "currency mismatch")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(s.failures.WithLabelValues(domain.MetricWalletHoldFundsFailure, "insufficient funds")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(s.stepTimeouts.WithLabelValues("payment", "AUTHORIZING")))
+
+	err := testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP golearn_saga_duration_seconds Time from the start of a saga to its end, by outcome.
+# TYPE golearn_saga_duration_seconds histogram
+golearn_saga_duration_seconds_bucket{outcome="completed",saga="payment",le="0.25"} 0
+golearn_saga_duration_seconds_bucket{outcome="completed",saga="payment",le="0.5"} 0
+golearn_saga_duration_seconds_bucket{outcome="completed",saga="payment",le="1"} 1
+golearn_saga_duration_seconds_bucket{outcome="completed",saga="payment",le="2"} 1
+golearn_saga_duration_seconds_bucket{outcome="completed",saga="payment",le="5"} 1
+golearn_saga_duration_seconds_bucket{outcome="completed",saga="payment",le="10"} 1
+golearn_saga_duration_seconds_bucket{outcome="completed",saga="payment",le="30"} 1
+golearn_saga_duration_seconds_bucket{outcome="completed",saga="payment",le="60"} 1
+golearn_saga_duration_seconds_bucket{outcome="completed",saga="payment",le="120"} 1
+golearn_saga_duration_seconds_bucket{outcome="completed",saga="payment",le="+Inf"} 1
+golearn_saga_duration_seconds_sum{outcome="completed",saga="payment"} 0.8
+golearn_saga_duration_seconds_count{outcome="completed",saga="payment"} 1
+golearn_saga_duration_seconds_bucket{outcome="failed",saga="payment",le="0.25"} 0
+golearn_saga_duration_seconds_bucket{outcome="failed",saga="payment",le="0.5"} 0
+golearn_saga_duration_seconds_bucket{outcome="failed",saga="payment",le="1"} 0
+golearn_saga_duration_seconds_bucket{outcome="failed",saga="payment",le="2"} 0
+golearn_saga_duration_seconds_bucket{outcome="failed",saga="payment",le="5"} 1
+golearn_saga_duration_seconds_bucket{outcome="failed",saga="payment",le="10"} 1
+golearn_saga_duration_seconds_bucket{outcome="failed",saga="payment",le="30"} 1
+golearn_saga_duration_seconds_bucket{outcome="failed",saga="payment",le="60"} 1
+golearn_saga_duration_seconds_bucket{outcome="failed",saga="payment",le="120"} 1
+golearn_saga_duration_seconds_bucket{outcome="failed",saga="payment",le="+Inf"} 1
+golearn_saga_duration_seconds_sum{outcome="failed",saga="payment"} 3
+golearn_saga_duration_seconds_count{outcome="failed",saga="payment"} 1
+`), "golearn_saga_duration_seconds")
+	require.NoError(t, err)
+}
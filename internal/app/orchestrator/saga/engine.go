@@ -0,0 +1,483 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+var (
+	// ErrNotFound is wrapped by Store.Load when there is no saga with the id.
+	ErrNotFound = errors.New("saga not found")
+	// ErrInvalidEvent is returned for messages the saga cannot read, which a
+	// redelivery will not fix.
+	ErrInvalidEvent = errors.New("invalid saga event")
+)
+
+// State is the data a saga keeps between steps. The engine only looks at its
+// id and progress.
+type State interface {
+	SagaID() string
+	Progress() *domain.SagaProgress
+}
+
+// Store persists the saga states of a definition.
+type Store[S State] interface {
+	Load(id string) (S, error)
+	Save(state S) error
+	// Expired returns the sagas whose step deadline is before now.
+	Expired(now time.Time) ([]S, error)
+}
+
+// Command sends the request of a step. The answer comes back as an event.
+type Command[S State] func(ctx context.Context, state S) error
+
+// Event is what the engine reads from every message of a saga.
+type Event struct {
+	// ID identifies the saga instance.
+	ID string
+	// Reason explains a failure event.
+	Reason string
+	// Step is the step a timeout event refers to.
+	Step domain.SagaStep
+}
+
+// Step is a request to a service and the events it answers with.
+type Step[S State] struct {
+	Name    domain.SagaStep
+	Action  Command[S]
+	Success string
+	// Failure is empty when the step cannot fail.
+	Failure string
+	// Compensation undoes the step once it succeeded. Steps without one cannot
+	// be undone, so Definition.Cancelable must reject cancellations once they
+	// started.
+	Compensation *Compensation[S]
+	// Timeout is how long the step waits for its answer, zero to wait forever.
+	// The outcome of a step that timed out is unknown, so its own compensation
+	// runs too and has to be harmless when the step never happened.
+	Timeout time.Duration
+}
+
+type Compensation[S State] struct {
+	Name   domain.SagaStep
+	Action Command[S]
+	// Done is the event confirming the compensation. When empty, the engine
+	// moves on to the previous step as soon as the command was sent.
+	Done string
+	// Failure is the event telling the compensation could not be done, which
+	// ends the saga at Definition.Unresolved. Empty if it cannot fail.
+	Failure string
+}
+
+// End is a terminal step and the command it sends, e.g. to notify the user.
+type End[S State] struct {
+	Step   domain.SagaStep
+	Action Command[S]
+}
+
+// Definition declares a saga: the event starting it, its steps in order, and
+// how it ends. A failed step compensates the steps before it, last first.
+type Definition[S State] struct {
+	Name  string
+	Start string
+	// New builds the state of a saga from its start message.
+	New    func(msg []byte) (S, error)
+	Decode func(msg []byte) (Event, error)
+	// Cancel is the event asking to abort the saga, empty if it cannot be canceled.
+	Cancel string
+	// Cancelable tells whether the saga can still be canceled at the step it
+	// is at, and why not otherwise. Whoever accepts cancellations asks the
+	// same question, so both agree.
+	Cancelable func(state S) error
+	// CancelRejected runs when a cancellation arrives once the saga can no
+	// longer be canceled, as whoever asked for it was told it was accepted.
+	CancelRejected Command[S]
+	// TimedOut is the event the sweeper publishes for steps past their
+	// deadline, empty if no step times out.
+	TimedOut  string
+	Steps     []Step[S]
+	Completed End[S]
+	Failed    End[S]
+	Canceled  End[S]
+	// Unresolved ends a saga whose compensation failed, leaving it to someone
+	// to settle what the steps before it did.
+	Unresolved End[S]
+}
+
+// Engine runs the sagas of a definition. Every event is routed by the step the
+// saga is at, and the step is saved before its command is sent, so answers
+// never find the saga behind.
+type Engine[S State] struct {
+	def   Definition[S]
+	store Store[S]
+}
+
+func New[S State](def Definition[S], store Store[S]) *Engine[S] {
+	return &Engine[S]{
+		def:   def,
+		store: store,
+	}
+}
+
+func (e *Engine[S]) Name() string {
+	return e.def.Name
+}
+
+// Topics are the events the saga listens to.
+func (e *Engine[S]) Topics() []string {
+	var topics []string
+	seen := map[string]bool{"": true}
+	add := func(topic string) {
+		if !seen[topic] {
+			seen[topic] = true
+			topics = append(topics, topic)
+		}
+	}
+
+	add(e.def.Start)
+	add(e.def.Cancel)
+	add(e.def.TimedOut)
+	for _, step := range e.def.Steps {
+		add(step.Success)
+		add(step.Failure)
+		if c := step.Compensation; c != nil {
+			add(c.Done)
+			add(c.Failure)
+		}
+	}
+	return topics
+}
+
+// Handle moves the saga the message on topic belongs to. Returning an error
+// hands the message back for redelivery, which resends the commands of the
+// step the saga was moved to.
+func (e *Engine[S]) Handle(ctx context.Context, topic string, msg []byte) error {
+	decode := e.def.Decode
+	if topic == e.def.TimedOut {
+		decode = decodeTimeout
+	}
+	event, err := decode(msg)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidEvent, topic, err)
+	}
+
+	if topic == e.def.Start {
+		return e.start(ctx, event.ID, msg)
+	}
+
+	state, err := e.store.Load(event.ID)
+	if err != nil {
+		log.Printf("ERROR: [%s saga] Failed to load state of %s: %v", e.def.Name, event.ID, err)
+		return err
+	}
+	p := state.Progress()
+	log.Printf("[%s saga] Handling %s for %s at step %s.", e.def.Name, topic, event.ID, p.Step)
+
+	if topic == e.def.Cancel {
+		return e.cancel(ctx, state, event.ID)
+	}
+	if p.Ended {
+		log.Printf("[%s saga] %s already ended at %s, ignoring %s.", e.def.Name, event.ID, p.Step, topic)
+		return nil
+	}
+	if topic == p.LastEvent {
+		return e.resume(ctx, state)
+	}
+	if _, ended := e.end(p.Step); ended {
+		log.Printf("[%s saga] %s is ending at %s, ignoring %s.", e.def.Name, event.ID, p.Step, topic)
+		return nil
+	}
+	if p.Compensating {
+		return e.compensated(ctx, state, topic, event)
+	}
+
+	i := e.step(p.Step)
+	if i < 0 {
+		log.Printf("WARN: [%s saga] %s is at unknown step %s, ignoring %s.", e.def.Name, event.ID, p.Step, topic)
+		return nil
+	}
+	switch topic {
+	case e.def.Steps[i].Success:
+		if p.CancelRequested {
+			return e.compensate(ctx, state, topic, i)
+		}
+		return e.run(ctx, state, topic, i+1)
+	case e.def.Steps[i].Failure:
+		p.Reason = event.Reason
+		return e.compensate(ctx, state, topic, i-1)
+	case e.def.TimedOut:
+		if event.Step != p.Step || !p.Expired(time.Now()) {
+			break
+		}
+		log.Printf("WARN: [%s saga] %s timed out at step %s.", e.def.Name, event.ID, p.Step)
+		p.Reason = event.Reason
+		return e.compensate(ctx, state, topic, i)
+	}
+
+	// An answer to a step the saga already left, e.g. a duplicate.
+	log.Printf("[%s saga] Ignoring %s for %s at step %s.", e.def.Name, topic, event.ID, p.Step)
+	return nil
+}
+
+func (e *Engine[S]) start(ctx context.Context, id string, msg []byte) error {
+	state, err := e.store.Load(id)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		state, err = e.def.New(msg)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidEvent, e.def.Start, err)
+		}
+		log.Printf("[%s saga] Starting %s.", e.def.Name, id)
+		return e.run(ctx, state, e.def.Start, 0)
+	case err != nil:
+		log.Printf("ERROR: [%s saga] Failed to load state of %s: %v", e.def.Name, id, err)
+		return err
+	case state.Progress().LastEvent == e.def.Start:
+		return e.resume(ctx, state)
+	}
+
+	log.Printf("[%s saga] %s already started, ignoring duplicated %s.", e.def.Name, id, e.def.Start)
+	return nil
+}
+
+// run moves the saga to step i and sends its command, or completes the saga
+// after its last step.
+func (e *Engine[S]) run(ctx context.Context, state S, topic string, i int) error {
+	if i == len(e.def.Steps) {
+		return e.finish(ctx, state, topic, e.def.Completed)
+	}
+
+	step := e.def.Steps[i]
+	if err := e.save(state, topic, step.Name); err != nil {
+		return err
+	}
+	return step.Action(ctx, state)
+}
+
+// compensate undoes the steps from i down to the first one. It stops at the
+// first compensation that has to be confirmed and goes on once it is.
+func (e *Engine[S]) compensate(ctx context.Context, state S, topic string, i int) error {
+	p := state.Progress()
+	p.Compensating = true
+
+	for ; i >= 0; i-- {
+		c := e.def.Steps[i].Compensation
+		if c == nil {
+			continue
+		}
+		if err := e.save(state, topic, c.Name); err != nil {
+			return err
+		}
+		if err := c.Action(ctx, state); err != nil {
+			return err
+		}
+		if c.Done != "" {
+			return nil
+		}
+	}
+
+	if p.CancelRequested {
+		return e.finish(ctx, state, topic, e.def.Canceled)
+	}
+	return e.finish(ctx, state, topic, e.def.Failed)
+}
+
+// compensated goes on compensating once the compensation in flight is
+// confirmed, and gives up once it failed.
+func (e *Engine[S]) compensated(ctx context.Context, state S, topic string, event Event) error {
+	p := state.Progress()
+	i, c := e.compensation(p.Step)
+	switch {
+	case c == nil:
+	case c.Done == topic:
+		return e.compensate(ctx, state, topic, i-1)
+	case c.Failure == topic:
+		log.Printf("ERROR: [%s saga] %s could not be compensated at step %s: %s", e.def.Name, event.ID, p.Step, event.Reason)
+		p.Reason = event.Reason
+		return e.finish(ctx, state, topic, e.def.Unresolved)
+	}
+
+	log.Printf("[%s saga] Ignoring %s for %s while compensating at step %s.", e.def.Name, topic, event.ID, p.Step)
+	return nil
+}
+
+// cancel records the cancellation, as the step in flight cannot be called
+// back. The saga compensates once that step answers. Sagas that completed or
+// Definition.Cancelable rejects reject it; those already compensating or over
+// otherwise end without its help.
+func (e *Engine[S]) cancel(ctx context.Context, state S, id string) error {
+	p := state.Progress()
+	if p.Compensating || p.CancelRequested {
+		return nil
+	}
+	if end, ended := e.end(p.Step); ended && end.Step != e.def.Completed.Step {
+		log.Printf("[%s saga] %s already ended at %s, ignoring cancellation.", e.def.Name, id, p.Step)
+		return nil
+	}
+	if e.step(p.Step) < 0 {
+		log.Printf("WARN: [%s saga] Rejecting cancellation of %s at step %s.", e.def.Name, id, p.Step)
+		return act(ctx, state, e.def.CancelRejected)
+	}
+	if err := e.def.Cancelable(state); err != nil {
+		log.Printf("WARN: [%s saga] Rejecting cancellation of %s at step %s: %v", e.def.Name, id, p.Step, err)
+		return act(ctx, state, e.def.CancelRejected)
+	}
+
+	p.CancelRequested = true
+	p.SetStep(p.Step)
+	if err := e.store.Save(state); err != nil {
+		log.Printf("ERROR: [%s saga] Failed to save state of %s: %v", e.def.Name, id, err)
+		return err
+	}
+	return nil
+}
+
+func (e *Engine[S]) finish(ctx context.Context, state S, topic string, end End[S]) error {
+	if err := e.save(state, topic, end.Step); err != nil {
+		return err
+	}
+	log.Printf("[%s saga] %s ended at %s.", e.def.Name, state.SagaID(), end.Step)
+	return e.act(ctx, state, end)
+}
+
+// act runs the action of end and records that it ran. When it fails, the
+// saga stays at end.Step and the redelivery of the event runs it again.
+func (e *Engine[S]) act(ctx context.Context, state S, end End[S]) error {
+	if err := act(ctx, state, end.Action); err != nil {
+		return err
+	}
+
+	state.Progress().Ended = true
+	if err := e.store.Save(state); err != nil {
+		log.Printf("ERROR: [%s saga] Failed to save end of %s: %v", e.def.Name, state.SagaID(), err)
+		return err
+	}
+	return nil
+}
+
+// resume sends again the commands of the step the saga is at, after the
+// event that moved it there was redelivered.
+func (e *Engine[S]) resume(ctx context.Context, state S) error {
+	p := state.Progress()
+
+	if end, ended := e.end(p.Step); ended {
+		return e.act(ctx, state, end)
+	}
+	if p.Compensating {
+		i, c := e.compensation(p.Step)
+		if c == nil {
+			return nil
+		}
+		if err := c.Action(ctx, state); err != nil {
+			return err
+		}
+		if c.Done != "" {
+			return nil
+		}
+		return e.compensate(ctx, state, p.LastEvent, i-1)
+	}
+	if i := e.step(p.Step); i >= 0 {
+		return e.def.Steps[i].Action(ctx, state)
+	}
+	return nil
+}
+
+// Expired lists the sagas whose step was not answered before its deadline.
+// The sweeper publishes their timeout event.
+func (e *Engine[S]) Expired(now time.Time) ([]Timeout, error) {
+	if e.def.TimedOut == "" {
+		return nil, nil
+	}
+	states, err := e.store.Expired(now)
+	if err != nil {
+		return nil, err
+	}
+
+	timeouts := make([]Timeout, 0, len(states))
+	for _, state := range states {
+		p := state.Progress()
+		timeouts = append(timeouts, Timeout{
+			Topic:    e.def.TimedOut,
+			Saga:     e.def.Name,
+			SagaID:   state.SagaID(),
+			Step:     p.Step,
+			Deadline: *p.Deadline,
+		})
+	}
+	return timeouts, nil
+}
+
+// save moves the saga to step, starting the deadline of the step if it has
+// a timeout.
+func (e *Engine[S]) save(state S, topic string, step domain.SagaStep) error {
+	p := state.Progress()
+	p.LastEvent = topic
+	p.SetStep(step)
+	p.Deadline = nil
+	if i := e.step(step); i >= 0 && e.def.Steps[i].Timeout > 0 {
+		deadline := p.UpdatedAt.Add(e.def.Steps[i].Timeout)
+		p.Deadline = &deadline
+	}
+	if err := e.store.Save(state); err != nil {
+		log.Printf("ERROR: [%s saga] Failed to save state at step %s: %v", e.def.Name, step, err)
+		return err
+	}
+	return nil
+}
+
+func (e *Engine[S]) step(name domain.SagaStep) int {
+	for i, step := range e.def.Steps {
+		if step.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// compensation finds the compensation called name and the step it undoes.
+func (e *Engine[S]) compensation(name domain.SagaStep) (int, *Compensation[S]) {
+	for i, step := range e.def.Steps {
+		if step.Compensation != nil && step.Compensation.Name == name {
+			return i, step.Compensation
+		}
+	}
+	return -1, nil
+}
+
+func (e *Engine[S]) end(step domain.SagaStep) (End[S], bool) {
+	for _, end := range []End[S]{e.def.Completed, e.def.Failed, e.def.Canceled, e.def.Unresolved} {
+		if end.Step != "" && end.Step == step {
+			return end, true
+		}
+	}
+	return End[S]{}, false
+}
+
+// decodeTimeout reads the timeout events published by the sweeper.
+func decodeTimeout(msg []byte) (Event, error) {
+	var event domain.SagaTimedOutEvent
+	if err := json.Unmarshal(msg, &event); err != nil {
+		return Event{}, err
+	}
+	if event.SagaID == "" {
+		return Event{}, errors.New("missing saga_id")
+	}
+	return Event{
+		ID:     event.SagaID,
+		Step:   event.Step,
+		Reason: fmt.Sprintf("step %s timed out", event.Step),
+	}, nil
+}
+
+func act[S State](ctx context.Context, state S, action Command[S]) error {
+	if action == nil {
+		return nil
+	}
+	return action(ctx, state)
+}
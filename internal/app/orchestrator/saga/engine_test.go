@@ -0,0 +1,389 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testState struct {
+	ID string
+	domain.SagaProgress
+}
+
+func (s *testState) SagaID() string {
+	return s.ID
+}
+
+type testStore map[string]testState
+
+func (s testStore) Load(id string) (*testState, error) {
+	state, ok := s[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return &state, nil
+}
+
+func (s testStore) Save(state *testState) error {
+	s[state.ID] = *state
+	return nil
+}
+
+func (s testStore) Expired(now time.Time) ([]*testState, error) {
+	var expired []*testState
+	for _, state := range s {
+		if state.Expired(now) {
+			expired = append(expired, &state)
+		}
+	}
+	return expired, nil
+}
+
+// testSaga has a step confirming its compensation, one that does not wait for
+// it and times out, and one that cannot be undone. Every command it sends is recorded in sent.
+type testSaga struct {
+	*Engine[*testState]
+	store testStore
+	sent  []string
+	// fail makes the next command with that name fail.
+	fail map[string]bool
+}
+
+func newTestSaga() *testSaga {
+	ts := &testSaga{store: testStore{}, fail: map[string]bool{}}
+	send := func(name string) Command[*testState] {
+		return func(ctx context.Context, s *testState) error {
+			if ts.fail[name] {
+				delete(ts.fail, name)
+				return errors.New(name + " failed")
+			}
+			ts.sent = append(ts.sent, name)
+			return nil
+		}
+	}
+
+	ts.Engine = New(Definition[*testState]{
+		Name:  "test",
+		Start: "started",
+		New: func(msg []byte) (*testState, error) {
+			event, err := decode(msg)
+			return &testState{ID: event.ID}, err
+		},
+		Decode: decode,
+		Cancel: "cancel",
+		// C cannot be undone.
+		Cancelable: func(s *testState) error {
+			if s.Step == "C" {
+				return errors.New("c started")
+			}
+			return nil
+		},
+		CancelRejected: send("cancel rejected"),
+		TimedOut:       "timed_out",
+		Steps: []Step[*testState]{
+			{
+				Name: "A", Action: send("a"), Success: "a.ok", Failure: "a.failed",
+				Compensation: &Compensation[*testState]{Name: "UNDOING_A", Action: send("undo a"), Done: "a.undone", Failure: "a.undo_failed"},
+			},
+			{
+				Name: "B", Action: send("b"), Success: "b.ok", Failure: "b.failed",
+				Compensation: &Compensation[*testState]{Name: "UNDOING_B", Action: send("undo b")},
+				Timeout:      time.Minute,
+			},
+			{
+				Name: "C", Action: send("c"), Success: "c.ok", Failure: "c.failed",
+			},
+		},
+		Completed:  End[*testState]{Step: "COMPLETED", Action: send("completed")},
+		Failed:     End[*testState]{Step: "FAILED", Action: send("failed")},
+		Canceled:   End[*testState]{Step: "CANCELED", Action: send("canceled")},
+		Unresolved: End[*testState]{Step: "REQUIRES_REVIEW", Action: send("review")},
+	}, ts.store)
+	return ts
+}
+
+func decode(msg []byte) (Event, error) {
+	var event Event
+	err := json.Unmarshal(msg, &event)
+	return event, err
+}
+
+func (ts *testSaga) handle(t *testing.T, topics ...string) {
+	t.Helper()
+	for _, topic := range topics {
+		require.NoError(t, ts.Handle(context.Background(), topic, []byte(`{"ID":"saga-1","Reason":"`+topic+`"}`)), topic)
+	}
+}
+
+func (ts *testSaga) state(t *testing.T) testState {
+	t.Helper()
+	state, ok := ts.store["saga-1"]
+	require.True(t, ok)
+	return state
+}
+
+func TestEngine_Topics(t *testing.T) {
+	ts := newTestSaga()
+
+	assert.Equal(t, []string{"started", "cancel", "timed_out", "a.ok", "a.failed", "a.undone", "a.undo_failed", "b.ok", "b.failed", "c.ok", "c.failed"}, ts.Topics())
+}
+
+func TestEngine_RunsStepsInOrder(t *testing.T) {
+	ts := newTestSaga()
+
+	ts.handle(t, "started", "a.ok", "b.ok", "c.ok")
+
+	assert.Equal(t, []string{"a", "b", "c", "completed"}, ts.sent)
+	assert.Equal(t, domain.SagaStep("COMPLETED"), ts.state(t).Step)
+}
+
+func TestEngine_Compensation(t *testing.T) {
+	tests := []struct {
+		name         string
+		events       []string
+		expectedSent []string
+		expectedStep domain.SagaStep
+	}{
+		{
+			name:         "first step fails, nothing to undo",
+			events:       []string{"started", "a.failed"},
+			expectedSent: []string{"a", "failed"},
+			expectedStep: "FAILED",
+		},
+		{
+			name:         "waits for the confirmed compensation",
+			events:       []string{"started", "a.ok", "b.failed"},
+			expectedSent: []string{"a", "b", "undo a"},
+			expectedStep: "UNDOING_A",
+		},
+		{
+			name:         "undoes the steps last first",
+			events:       []string{"started", "a.ok", "b.ok", "c.failed", "a.undone"},
+			expectedSent: []string{"a", "b", "c", "undo b", "undo a", "failed"},
+			expectedStep: "FAILED",
+		},
+		{
+			name:         "a failed compensation leaves the saga for review",
+			events:       []string{"started", "a.ok", "b.failed", "a.undo_failed", "a.undone"},
+			expectedSent: []string{"a", "b", "undo a", "review"},
+			expectedStep: "REQUIRES_REVIEW",
+		},
+		{
+			name:         "cancel uses the compensations of the steps",
+			events:       []string{"started", "a.ok", "cancel", "b.ok", "a.undone"},
+			expectedSent: []string{"a", "b", "undo b", "undo a", "canceled"},
+			expectedStep: "CANCELED",
+		},
+		{
+			name:         "ignores answers of steps it left",
+			events:       []string{"started", "a.ok", "b.failed", "b.ok", "a.undone", "c.ok"},
+			expectedSent: []string{"a", "b", "undo a", "failed"},
+			expectedStep: "FAILED",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestSaga()
+
+			ts.handle(t, tt.events...)
+
+			assert.Equal(t, tt.expectedSent, ts.sent)
+			assert.Equal(t, tt.expectedStep, ts.state(t).Step)
+		})
+	}
+}
+
+func TestEngine_FailureKeepsReason(t *testing.T) {
+	ts := newTestSaga()
+
+	ts.handle(t, "started", "a.failed")
+
+	assert.Equal(t, "a.failed", ts.state(t).Reason)
+}
+
+func TestEngine_Cancel(t *testing.T) {
+	tests := []struct {
+		name         string
+		events       []string
+		expectedSent []string
+		expectedStep domain.SagaStep
+	}{
+		{
+			name:         "compensates once the step in flight answers",
+			events:       []string{"started", "a.ok", "cancel", "b.ok", "a.undone"},
+			expectedSent: []string{"a", "b", "undo b", "undo a", "canceled"},
+			expectedStep: "CANCELED",
+		},
+		{
+			name:         "failed step in flight has nothing to undo",
+			events:       []string{"started", "cancel", "a.failed"},
+			expectedSent: []string{"a", "canceled"},
+			expectedStep: "CANCELED",
+		},
+		{
+			name:         "rejected once a step that cannot be undone started",
+			events:       []string{"started", "a.ok", "b.ok", "cancel", "c.ok"},
+			expectedSent: []string{"a", "b", "c", "cancel rejected", "completed"},
+			expectedStep: "COMPLETED",
+		},
+		{
+			name:         "rejected once completed",
+			events:       []string{"started", "a.ok", "b.ok", "c.ok", "cancel"},
+			expectedSent: []string{"a", "b", "c", "completed", "cancel rejected"},
+			expectedStep: "COMPLETED",
+		},
+		{
+			name:         "ignored once failed",
+			events:       []string{"started", "a.failed", "cancel"},
+			expectedSent: []string{"a", "failed"},
+			expectedStep: "FAILED",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestSaga()
+
+			ts.handle(t, tt.events...)
+
+			assert.Equal(t, tt.expectedSent, ts.sent)
+			assert.Equal(t, tt.expectedStep, ts.state(t).Step)
+		})
+	}
+}
+
+func TestEngine_RedeliveryResendsCommands(t *testing.T) {
+	ts := newTestSaga()
+	ts.handle(t, "started")
+
+	ts.fail["b"] = true
+	err := ts.Handle(context.Background(), "a.ok", []byte(`{"ID":"saga-1"}`))
+	require.Error(t, err)
+	assert.Equal(t, domain.SagaStep("B"), ts.state(t).Step)
+
+	// The bus hands a.ok back, and b is sent although the saga is already at B.
+	ts.handle(t, "a.ok", "b.ok")
+
+	assert.Equal(t, []string{"a", "b", "c"}, ts.sent)
+}
+
+func TestEngine_RedeliveredEndDoesNotActAgain(t *testing.T) {
+	ts := newTestSaga()
+	ts.handle(t, "started", "a.ok", "b.ok")
+
+	// The end action failed, so the redelivered c.ok runs it again.
+	ts.fail["completed"] = true
+	require.Error(t, ts.Handle(context.Background(), "c.ok", []byte(`{"ID":"saga-1"}`)))
+	assert.False(t, ts.state(t).Ended)
+
+	ts.handle(t, "c.ok", "c.ok")
+
+	assert.Equal(t, []string{"a", "b", "c", "completed"}, ts.sent)
+	assert.Equal(t, domain.SagaStep("COMPLETED"), ts.state(t).Step)
+	assert.True(t, ts.state(t).Ended)
+}
+
+func TestEngine_RedeliveryResendsCompensation(t *testing.T) {
+	ts := newTestSaga()
+	ts.handle(t, "started", "a.ok", "b.ok")
+
+	ts.fail["undo a"] = true
+	err := ts.Handle(context.Background(), "c.failed", []byte(`{"ID":"saga-1"}`))
+	require.Error(t, err)
+	assert.Equal(t, domain.SagaStep("UNDOING_A"), ts.state(t).Step)
+
+	ts.handle(t, "c.failed", "a.undone")
+
+	assert.Equal(t, []string{"a", "b", "c", "undo b", "undo a", "failed"}, ts.sent)
+}
+
+func TestEngine_StepDeadline(t *testing.T) {
+	ts := newTestSaga()
+
+	ts.handle(t, "started")
+	assert.Nil(t, ts.state(t).Deadline)
+
+	ts.handle(t, "a.ok")
+	state := ts.state(t)
+	require.NotNil(t, state.Deadline)
+	assert.Equal(t, state.UpdatedAt.Add(time.Minute), *state.Deadline)
+
+	ts.handle(t, "b.ok")
+	assert.Nil(t, ts.state(t).Deadline)
+}
+
+func TestEngine_Timeout(t *testing.T) {
+	ts := newTestSaga()
+	ts.handle(t, "started", "a.ok")
+
+	timeouts, err := ts.Expired(time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, timeouts)
+
+	later := time.Now().Add(2 * time.Minute)
+	timeouts, err = ts.Expired(later)
+	require.NoError(t, err)
+	require.Len(t, timeouts, 1)
+	assert.Equal(t, Timeout{Topic: "timed_out", Saga: "test", SagaID: "saga-1", Step: "B", Deadline: *ts.state(t).Deadline}, timeouts[0])
+
+	// Past the deadline, the step itself is undone too, as it may have happened.
+	state := ts.store["saga-1"]
+	past := time.Now().Add(-time.Second)
+	state.Deadline = &past
+	ts.store["saga-1"] = state
+	require.NoError(t, ts.Handle(context.Background(), "timed_out", []byte(`{"payload":{"saga_id":"saga-1","step":"B"}}`)))
+
+	assert.Equal(t, []string{"a", "b", "undo b", "undo a"}, ts.sent)
+	assert.Equal(t, domain.SagaStep("UNDOING_A"), ts.state(t).Step)
+	assert.Equal(t, "step B timed out", ts.state(t).Reason)
+}
+
+func TestEngine_StaleTimeoutIsIgnored(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []string
+	}{
+		{name: "step answered meanwhile", events: []string{"started", "a.ok", "b.ok"}},
+		{name: "deadline not reached", events: []string{"started", "a.ok"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestSaga()
+			ts.handle(t, tt.events...)
+			sent := len(ts.sent)
+
+			require.NoError(t, ts.Handle(context.Background(), "timed_out", []byte(`{"payload":{"saga_id":"saga-1","step":"B"}}`)))
+
+			assert.Len(t, ts.sent, sent)
+			assert.False(t, ts.state(t).Compensating)
+		})
+	}
+}
+
+func TestEngine_DuplicatedStartIsIgnored(t *testing.T) {
+	ts := newTestSaga()
+
+	ts.handle(t, "started", "a.ok", "started")
+
+	assert.Equal(t, []string{"a", "b"}, ts.sent)
+}
+
+func TestEngine_Errors(t *testing.T) {
+	ts := newTestSaga()
+
+	err := ts.Handle(context.Background(), "a.ok", []byte(`{"ID":"missing"}`))
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	err = ts.Handle(context.Background(), "a.ok", []byte(`not json`))
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+}
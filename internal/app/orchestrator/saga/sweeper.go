@@ -0,0 +1,144 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/publisher"
+)
+
+// Timeout is a saga step that was not answered before its deadline.
+type Timeout struct {
+	// Topic is the timeout event of the saga.
+	Topic    string
+	Saga     string
+	SagaID   string
+	Step     domain.SagaStep
+	Deadline time.Time
+}
+
+// Sweepable is a saga whose steps can time out.
+type Sweepable interface {
+	Expired(now time.Time) ([]Timeout, error)
+}
+
+// Sweeper looks for sagas stuck past the deadline of their step and publishes
+// their timeout event, so the saga compensates instead of waiting forever. Every
+// timeout is also published as a metric.
+type Sweeper struct {
+	sagas     []Sweepable
+	publisher publisher.Client
+	interval  time.Duration
+	// published are the timeouts already published that the sagas did not
+	// handle yet, so they are not published on every pass.
+	published map[string]bool
+}
+
+func NewSweeper(publisher publisher.Client, interval time.Duration, sagas ...Sweepable) *Sweeper {
+	return &Sweeper{
+		sagas:     sagas,
+		publisher: publisher,
+		interval:  interval,
+		published: map[string]bool{},
+	}
+}
+
+// Run sweeps every interval until ctx is done.
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Sweep(ctx, time.Now().UTC()); err != nil {
+			log.Printf("ERROR: [SagaSweeper] %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep publishes the timeout event of every saga past its deadline at now.
+// A timeout that could not be published is retried on the next pass.
+func (s *Sweeper) Sweep(ctx context.Context, now time.Time) error {
+	var timeouts []Timeout
+	for _, saga := range s.sagas {
+		expired, err := saga.Expired(now)
+		if err != nil {
+			return err
+		}
+		timeouts = append(timeouts, expired...)
+	}
+
+	published := make(map[string]bool, len(timeouts))
+	for _, t := range timeouts {
+		dedupId := domain.BuildDeduplicationId(domain.SagaTimedOutEventType, t.Saga, t.SagaID, string(t.Step))
+		if s.published[dedupId] {
+			published[dedupId] = true
+			continue
+		}
+
+		log.Printf("WARN: [SagaSweeper] %s saga %s timed out at step %s (deadline %s).", t.Saga, t.SagaID, t.Step, t.Deadline.Format(time.RFC3339))
+		if err := s.publisher.Publish(ctx, t.Topic, buildTimedOutEvent(t, dedupId)); err != nil {
+			log.Printf("ERROR: [SagaSweeper] Failed to publish timeout of %s: %v", t.SagaID, err)
+			continue
+		}
+		published[dedupId] = true
+
+		if err := s.publisher.Publish(ctx, domain.TopicMetrics, buildTimeoutMetricEvent(t)); err != nil {
+			log.Printf("WARN: [SagaSweeper] Failed to publish timeout metric of %s: %v", t.SagaID, err)
+		}
+	}
+	s.published = published
+	return nil
+}
+
+func buildTimedOutEvent(t Timeout, dedupId string) []byte {
+	event := domain.SagaTimedOutEvent{
+		CommandEvent: domain.CommandEvent{
+			EventType:    domain.SagaTimedOutEventType,
+			EventVersion: "1",
+			Timestamp:    time.Now().UTC().Format(time.RFC3339),
+			CommandEventMetadata: domain.CommandEventMetadata{
+				TraceID:                uuid.NewString(),
+				MessageGroupID:         t.SagaID,
+				MessageDeduplicationId: dedupId,
+			},
+		},
+		SagaTimedOutEventPayload: domain.SagaTimedOutEventPayload{
+			Saga:     t.Saga,
+			SagaID:   t.SagaID,
+			Step:     t.Step,
+			Deadline: t.Deadline,
+		},
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("ERROR: Failed to marshal saga timed out event: %v", err)
+	}
+	return b
+}
+
+func buildTimeoutMetricEvent(t Timeout) []byte {
+	// Keyed by step as well: a saga can time out at more than one.
+	event := domain.NewMetricEvent(
+		domain.MetricSagaStepTimeout,
+		domain.BuildDeduplicationId(t.Saga, t.SagaID, string(t.Step)),
+		map[string]string{domain.MetricLabelSaga: t.Saga, domain.MetricLabelStep: string(t.Step)},
+		0,
+	)
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("ERROR: Failed to marshal saga timeout metric event: %v", err)
+	}
+	return b
+}
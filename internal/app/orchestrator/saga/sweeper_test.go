@@ -0,0 +1,71 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingPublisher struct {
+	topics []string
+	msgs   [][]byte
+	err    error
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, topic string, msg []byte) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.topics = append(p.topics, topic)
+	p.msgs = append(p.msgs, msg)
+	return nil
+}
+
+func TestSweeper_Sweep(t *testing.T) {
+	ts := newTestSaga()
+	ts.handle(t, "started", "a.ok")
+	pub := &recordingPublisher{}
+	sweeper := NewSweeper(pub, time.Second, ts)
+	later := time.Now().Add(2 * time.Minute)
+
+	require.NoError(t, sweeper.Sweep(context.Background(), time.Now()))
+	assert.Empty(t, pub.topics)
+
+	require.NoError(t, sweeper.Sweep(context.Background(), later))
+	assert.Equal(t, []string{"timed_out", domain.TopicMetrics}, pub.topics)
+
+	var event domain.SagaTimedOutEvent
+	require.NoError(t, json.Unmarshal(pub.msgs[0], &event))
+	assert.Equal(t, "saga-1", event.SagaID)
+	assert.Equal(t, domain.SagaStep("B"), event.Step)
+	assert.Equal(t, "saga_timed_out.test.saga-1.B", event.MessageDeduplicationId)
+
+	var metric domain.MetricEvent
+	require.NoError(t, json.Unmarshal(pub.msgs[1], &metric))
+	assert.Equal(t, domain.MetricSagaStepTimeout, metric.Metric)
+	assert.Equal(t, map[string]string{"saga": "test", "step": "B"}, metric.Labels)
+
+	// The saga has not handled it yet, it is not published again.
+	require.NoError(t, sweeper.Sweep(context.Background(), later))
+	assert.Len(t, pub.topics, 2)
+}
+
+func TestSweeper_RetriesFailedPublish(t *testing.T) {
+	ts := newTestSaga()
+	ts.handle(t, "started", "a.ok")
+	pub := &recordingPublisher{err: errors.New("bus down")}
+	sweeper := NewSweeper(pub, time.Second, ts)
+	later := time.Now().Add(2 * time.Minute)
+
+	require.NoError(t, sweeper.Sweep(context.Background(), later))
+
+	pub.err = nil
+	require.NoError(t, sweeper.Sweep(context.Background(), later))
+	assert.Equal(t, []string{"timed_out", domain.TopicMetrics}, pub.topics)
+}
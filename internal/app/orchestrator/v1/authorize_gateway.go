@@ -13,7 +13,7 @@ import (
 )
 
 type AuthorizeGatewayCommand interface {
-	Authorize(ctx context.Context, paymentId, walletId string, amount float64, currency, token string) error
+	Authorize(ctx context.Context, paymentId, walletId string, amount domain.Money, method, token string) error
 }
 
 type authorizeGatewayCommand struct {
@@ -28,10 +28,10 @@ func NewAuthorizeGatewayCommand(
 	}
 }
 
-func (c *authorizeGatewayCommand) Authorize(ctx context.Context, paymentId, walletId string, amount float64, currency, token string) error {
+func (c *authorizeGatewayCommand) Authorize(ctx context.Context, paymentId, walletId string, amount domain.Money, method, token string) error {
 	traceID := uuid.NewString()
 
-	b := c.buildEventV1(traceID, paymentId, walletId, amount, currency, token)
+	b := c.buildEventV2(traceID, paymentId, walletId, amount, method, token)
 
 	return retry.Do(
 		func() error {
@@ -43,11 +43,11 @@ func (c *authorizeGatewayCommand) Authorize(ctx context.Context, paymentId, wall
 	)
 }
 
-func (c *authorizeGatewayCommand) buildEventV1(traceID, paymentId, walletId string, amount float64, currency, token string) []byte {
+func (c *authorizeGatewayCommand) buildEventV2(traceID, paymentId, walletId string, amount domain.Money, method, token string) []byte {
 	event := domain.WalletCommandEvent{
 		CommandEvent: domain.CommandEvent{
 			EventType:    domain.AuthorizeGatewayEventType,
-			EventVersion: "1",
+			EventVersion: domain.WalletCommandEventVersion,
 			Timestamp:    time.Now().UTC().Format(time.RFC3339),
 			CommandEventMetadata: domain.CommandEventMetadata{
 				TraceID:        traceID,
@@ -62,8 +62,8 @@ func (c *authorizeGatewayCommand) buildEventV1(traceID, paymentId, walletId stri
 			WalletID:  walletId,
 			PaymentID: paymentId,
 			Amount:    amount,
-			Currency:  currency,
 			Token:     token,
+			Method:    method,
 		},
 	}
 
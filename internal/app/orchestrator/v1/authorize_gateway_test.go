@@ -38,7 +38,7 @@ func TestAuthorizeGatewayCommand_Authorize(t *testing.T) {
 			tt.setupMocks(publisherMock)
 
 			cmd := NewAuthorizeGatewayCommand(publisherMock)
-			err := cmd.Authorize(context.Background(), "payment-123", "wallet-456", 100.0, "USD", "token-789")
+			err := cmd.Authorize(context.Background(), "payment-123", "wallet-456", domain.NewMoney(100_00, "USD"), "card", "token-789")
 
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -0,0 +1,77 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/google/uuid"
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/publisher"
+)
+
+// CaptureAuthorizationCommand collects the gateway authorization of a payment
+// whose funds were debited.
+type CaptureAuthorizationCommand interface {
+	Capture(ctx context.Context, paymentId, walletId, method string, amount domain.Money) error
+}
+
+type captureAuthorizationCommand struct {
+	publisher publisher.Client
+}
+
+func NewCaptureAuthorizationCommand(
+	publisher publisher.Client,
+) *captureAuthorizationCommand {
+	return &captureAuthorizationCommand{
+		publisher,
+	}
+}
+
+func (c *captureAuthorizationCommand) Capture(ctx context.Context, paymentId, walletId, method string, amount domain.Money) error {
+	traceID := uuid.NewString()
+
+	b := c.buildEventV2(traceID, paymentId, walletId, method, amount)
+
+	return retry.Do(
+		func() error {
+			return c.publisher.Publish(ctx, domain.TopicOrchestratorGateway, b)
+		},
+		retry.Attempts(3),
+		retry.DelayType(retry.BackOffDelay),
+		retry.Delay(100*time.Millisecond),
+	)
+}
+
+func (c *captureAuthorizationCommand) buildEventV2(traceID, paymentId, walletId, method string, amount domain.Money) []byte {
+	event := domain.WalletCommandEvent{
+		CommandEvent: domain.CommandEvent{
+			EventType:    domain.CaptureAuthorizationEventType,
+			EventVersion: domain.WalletCommandEventVersion,
+			Timestamp:    time.Now().UTC().Format(time.RFC3339),
+			CommandEventMetadata: domain.CommandEventMetadata{
+				TraceID:        traceID,
+				MessageGroupID: paymentId,
+				MessageDeduplicationId: domain.BuildDeduplicationId(
+					domain.CaptureAuthorizationEventType,
+					paymentId,
+				),
+			},
+		},
+		WalletCommandEventPayload: domain.WalletCommandEventPayload{
+			WalletID:  walletId,
+			PaymentID: paymentId,
+			Amount:    amount,
+			Method:    method,
+		},
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("ERROR: Failed to marshal captureAuthorizationCommand event: %v", err)
+	}
+
+	return b
+}
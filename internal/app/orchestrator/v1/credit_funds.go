@@ -0,0 +1,75 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/google/uuid"
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/publisher"
+)
+
+type CreditFundsCommand interface {
+	Credit(ctx context.Context, refundId, paymentId, walletId string, amount domain.Money) error
+}
+
+type creditFundsCommand struct {
+	publisher publisher.Client
+}
+
+func NewCreditFundsCommand(
+	publisher publisher.Client,
+) *creditFundsCommand {
+	return &creditFundsCommand{
+		publisher,
+	}
+}
+
+func (c *creditFundsCommand) Credit(ctx context.Context, refundId, paymentId, walletId string, amount domain.Money) error {
+	traceID := uuid.NewString()
+
+	b := c.buildEventV1(traceID, refundId, paymentId, walletId, amount)
+
+	return retry.Do(
+		func() error {
+			return c.publisher.Publish(ctx, domain.TopicOrchestratorWallet, b)
+		},
+		retry.Attempts(3),
+		retry.DelayType(retry.BackOffDelay),
+		retry.Delay(100*time.Millisecond),
+	)
+}
+
+func (c *creditFundsCommand) buildEventV1(traceID, refundId, paymentId, walletId string, amount domain.Money) []byte {
+	event := domain.RefundCommandEvent{
+		CommandEvent: domain.CommandEvent{
+			EventType:    domain.CreditFundsEventType,
+			EventVersion: "1",
+			Timestamp:    time.Now().UTC().Format(time.RFC3339),
+			CommandEventMetadata: domain.CommandEventMetadata{
+				TraceID:        traceID,
+				MessageGroupID: paymentId,
+				MessageDeduplicationId: domain.BuildDeduplicationId(
+					domain.CreditFundsEventType,
+					refundId,
+				),
+			},
+		},
+		RefundCommandEventPayload: domain.RefundCommandEventPayload{
+			RefundID:     refundId,
+			PaymentID:    paymentId,
+			WalletID:     walletId,
+			WalletAmount: amount,
+		},
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("ERROR: Failed to marshal creditFundsCommand event: %v", err)
+	}
+
+	return b
+}
@@ -13,7 +13,7 @@ import (
 )
 
 type DebitFundsCommand interface {
-	Debit(ctx context.Context, paymentId, walletId string, amount float64, currency string) error
+	Debit(ctx context.Context, paymentId, walletId string, amount domain.Money) error
 }
 
 type debitFundsCommand struct {
@@ -28,10 +28,10 @@ func NewDebitFundsCommand(
 	}
 }
 
-func (c *debitFundsCommand) Debit(ctx context.Context, paymentId, walletId string, amount float64, currency string) error {
+func (c *debitFundsCommand) Debit(ctx context.Context, paymentId, walletId string, amount domain.Money) error {
 	traceID := uuid.NewString()
 
-	b := c.buildDebitEventV1(traceID, paymentId, walletId, amount, currency)
+	b := c.buildDebitEventV2(traceID, paymentId, walletId, amount)
 
 	return retry.Do(
 		func() error {
@@ -43,11 +43,11 @@ func (c *debitFundsCommand) Debit(ctx context.Context, paymentId, walletId strin
 	)
 }
 
-func (c *debitFundsCommand) buildDebitEventV1(traceID, paymentId, walletId string, amount float64, currency string) []byte {
+func (c *debitFundsCommand) buildDebitEventV2(traceID, paymentId, walletId string, amount domain.Money) []byte {
 	event := domain.WalletCommandEvent{
 		CommandEvent: domain.CommandEvent{
 			EventType:    domain.DebitFundsEventType,
-			EventVersion: "1",
+			EventVersion: domain.WalletCommandEventVersion,
 			Timestamp:    time.Now().UTC().Format(time.RFC3339),
 			CommandEventMetadata: domain.CommandEventMetadata{
 				TraceID:        traceID,
@@ -62,7 +62,6 @@ func (c *debitFundsCommand) buildDebitEventV1(traceID, paymentId, walletId strin
 			WalletID:  walletId,
 			PaymentID: paymentId,
 			Amount:    amount,
-			Currency:  currency,
 		},
 	}
 
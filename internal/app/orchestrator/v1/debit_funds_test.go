@@ -38,7 +38,7 @@ func TestDebitFundsCommand_Debit(t *testing.T) {
 			tt.setupMocks(publisherMock)
 
 			cmd := NewDebitFundsCommand(publisherMock)
-			err := cmd.Debit(context.Background(), "payment-123", "wallet-456", 100.0, "USD")
+			err := cmd.Debit(context.Background(), "payment-123", "wallet-456", domain.NewMoney(100_00, "USD"))
 
 			if tt.expectedError {
 				assert.Error(t, err)
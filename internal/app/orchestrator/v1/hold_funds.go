@@ -14,7 +14,7 @@ import (
 )
 
 type HoldFundsCommand interface {
-	Hold(ctx context.Context, paymentId, walletId string, amount float64, currency string) error
+	Hold(ctx context.Context, paymentId, walletId string, amount domain.Money) error
 }
 
 type holdFundsCommand struct {
@@ -29,11 +29,11 @@ func NewHoldFundsCommand(
 	}
 }
 
-func (c *holdFundsCommand) Hold(ctx context.Context, paymentId, walletId string, amount float64, currency string) error {
+func (c *holdFundsCommand) Hold(ctx context.Context, paymentId, walletId string, amount domain.Money) error {
 	// extracted from context implementation of otel for example
 	traceID := uuid.NewString()
 
-	b := c.buildHoldEventV1(traceID, paymentId, walletId, amount, currency)
+	b := c.buildHoldEventV2(traceID, paymentId, walletId, amount)
 
 	return retry.Do(
 		func() error {
@@ -45,11 +45,11 @@ func (c *holdFundsCommand) Hold(ctx context.Context, paymentId, walletId string,
 	)
 }
 
-func (c *holdFundsCommand) buildHoldEventV1(traceID, paymentId, walletId string, amount float64, currency string) []byte {
+func (c *holdFundsCommand) buildHoldEventV2(traceID, paymentId, walletId string, amount domain.Money) []byte {
 	event := domain.WalletCommandEvent{
 		CommandEvent: domain.CommandEvent{
 			EventType:    domain.HoldFundsEventType,
-			EventVersion: "1",
+			EventVersion: domain.WalletCommandEventVersion,
 			Timestamp:    time.Now().UTC().Format(time.RFC3339),
 			CommandEventMetadata: domain.CommandEventMetadata{
 				TraceID:        traceID,
@@ -64,7 +64,6 @@ func (c *holdFundsCommand) buildHoldEventV1(traceID, paymentId, walletId string,
 			WalletID:  walletId,
 			PaymentID: paymentId,
 			Amount:    amount,
-			Currency:  currency,
 		},
 	}
 
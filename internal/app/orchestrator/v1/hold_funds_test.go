@@ -2,6 +2,7 @@ package orchestrator
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"testing"
 
@@ -48,7 +49,7 @@ func TestHoldFundsCommand_Hold(t *testing.T) {
 			tt.setupMocks(publisherMock)
 
 			cmd := NewHoldFundsCommand(publisherMock)
-			err := cmd.Hold(context.Background(), "payment-123", "wallet-456", 100.0, "USD")
+			err := cmd.Hold(context.Background(), "payment-123", "wallet-456", domain.NewMoney(100_00, "USD"))
 
 			if tt.expectedError {
 				assert.Error(t, err)
@@ -60,3 +61,24 @@ func TestHoldFundsCommand_Hold(t *testing.T) {
 		})
 	}
 }
+
+func TestHoldFundsCommand_PublishesAmountInMinorUnits(t *testing.T) {
+	publisherMock := new(MockPublisher)
+	publisherMock.On("Publish", mock.Anything, domain.TopicOrchestratorWallet, mock.MatchedBy(func(body []byte) bool {
+		var raw struct {
+			EventVersion string `json:"event_version"`
+			Payload      struct {
+				Amount json.RawMessage `json:"amount"`
+			} `json:"payload"`
+		}
+		return json.Unmarshal(body, &raw) == nil &&
+			raw.EventVersion == domain.WalletCommandEventVersion &&
+			string(raw.Payload.Amount) == `{"amount":"12.30","currency":"USD"}`
+	})).Return(nil).Once()
+
+	cmd := NewHoldFundsCommand(publisherMock)
+	err := cmd.Hold(context.Background(), "payment-123", "wallet-456", domain.NewMoney(1230, "USD"))
+
+	assert.NoError(t, err)
+	publisherMock.AssertExpectations(t)
+}
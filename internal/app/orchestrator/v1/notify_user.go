@@ -16,6 +16,11 @@ type NotifyUserCommand interface {
 	Notify(ctx context.Context, paymentId string, notificationType domain.Notification) error
 }
 
+// NotifyRefundCommand notifies the user about a refund of a payment.
+type NotifyRefundCommand interface {
+	NotifyRefund(ctx context.Context, refundId, paymentId string, notificationType domain.Notification) error
+}
+
 type notifyUserCommand struct {
 	publisher publisher.Client
 }
@@ -29,9 +34,17 @@ func NewNotifyUserCommand(
 }
 
 func (c *notifyUserCommand) Notify(ctx context.Context, paymentId string, notificationType domain.Notification) error {
+	return c.publish(ctx, "", paymentId, notificationType)
+}
+
+func (c *notifyUserCommand) NotifyRefund(ctx context.Context, refundId, paymentId string, notificationType domain.Notification) error {
+	return c.publish(ctx, refundId, paymentId, notificationType)
+}
+
+func (c *notifyUserCommand) publish(ctx context.Context, refundId, paymentId string, notificationType domain.Notification) error {
 	traceID := uuid.NewString()
 
-	b := c.buildEventV1(traceID, paymentId, notificationType)
+	b := c.buildEventV1(traceID, refundId, paymentId, notificationType)
 
 	return retry.Do(
 		func() error {
@@ -43,7 +56,13 @@ func (c *notifyUserCommand) Notify(ctx context.Context, paymentId string, notifi
 	)
 }
 
-func (c *notifyUserCommand) buildEventV1(traceID, paymentId string, notificationType domain.Notification) []byte {
+func (c *notifyUserCommand) buildEventV1(traceID, refundId, paymentId string, notificationType domain.Notification) []byte {
+	// A payment gets one notification of each type, but may be refunded many times.
+	ids := []string{paymentId, string(notificationType)}
+	if refundId != "" {
+		ids = append(ids, refundId)
+	}
+
 	event := domain.NotifyUserEvent{
 		CommandEvent: domain.CommandEvent{
 			EventType:    domain.NotifyUserEventType,
@@ -54,12 +73,13 @@ func (c *notifyUserCommand) buildEventV1(traceID, paymentId string, notification
 				MessageGroupID: paymentId,
 				MessageDeduplicationId: domain.BuildDeduplicationId(
 					domain.NotifyUserEventType,
-					paymentId,
+					ids...,
 				),
 			},
 		},
 		NotifyUserEventPayload: domain.NotifyUserEventPayload{
 			PaymentID:    paymentId,
+			RefundID:     refundId,
 			Notification: notificationType,
 		},
 	}
@@ -2,6 +2,7 @@ package orchestrator
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"testing"
 
@@ -50,3 +51,19 @@ func TestNotifyUserCommand_Notify(t *testing.T) {
 		})
 	}
 }
+
+func TestNotifyUserCommand_NotifyRefundIsDeduplicatedPerRefund(t *testing.T) {
+	publisherMock := new(MockPublisher)
+	publisherMock.On("Publish", mock.Anything, domain.TopicOrchestratorNotification, mock.MatchedBy(func(body []byte) bool {
+		var ev domain.NotifyUserEvent
+		return json.Unmarshal(body, &ev) == nil &&
+			ev.RefundID == "refund-1" &&
+			ev.MessageDeduplicationId == "notify_user.payment-123.refund_success.refund-1"
+	})).Return(nil).Once()
+
+	cmd := NewNotifyUserCommand(publisherMock)
+	err := cmd.NotifyRefund(context.Background(), "refund-1", "payment-123", domain.RefundSuccess)
+
+	assert.NoError(t, err)
+	publisherMock.AssertExpectations(t)
+}
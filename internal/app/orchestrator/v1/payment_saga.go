@@ -0,0 +1,251 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mmarias/golearn/internal/app/orchestrator/saga"
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+type PaymentSaga = saga.Engine[*domain.SagaState]
+
+const paymentSagaName = "payment"
+
+// AuthorizationTimeout is how long the payment saga waits for the gateway.
+// After it, the authorization is voided and the held funds are released.
+const AuthorizationTimeout = 30 * time.Second
+
+// NewPaymentSaga runs the payment saga: funds are held in the wallet, the
+// gateway authorizes and captures the payment, the held funds are debited and
+// the payment service records the payment as completed. Every step first
+// moves the payment to the status the previous one reached, in the order the
+// payment state machine allows; the payment service applies them in the order
+// they are sent. A payment can be canceled until its capture is requested
+// (see SagaState.Cancelable), and fails if the gateway does not authorize it
+// within AuthorizationTimeout, declines its capture or the wallet cannot debit
+// the held funds.
+func NewPaymentSaga(
+	sagaStates domain.SagaStateRepository,
+	holdFundsCmd HoldFundsCommand,
+	releaseFundsCmd ReleaseFundsCommand,
+	debitFundsCmd DebitFundsCommand,
+	authorizeCmd AuthorizeGatewayCommand,
+	captureCmd CaptureAuthorizationCommand,
+	voidCmd VoidAuthorizationCommand,
+	refundAuthCmd RefundAuthorizationCommand,
+	updateStatusCmd UpdatePaymentStatusCommand,
+	notifyUserCmd NotifyUserCommand,
+	recordMetricCmd RecordMetricCommand,
+) *PaymentSaga {
+	// end records the final status of the payment and its metric. The
+	// notification consumer notifies the user once the payment service
+	// applied the status.
+	end := func(status domain.PaymentStatus, metric, outcome string) saga.Command[*domain.SagaState] {
+		return func(ctx context.Context, s *domain.SagaState) error {
+			if err := updateStatusCmd.UpdateStatus(ctx, s.PaymentID, status); err != nil {
+				log.Printf("CRITICAL: Failed to update payment status to %s for PaymentID %s: %v", status, s.PaymentID, err)
+				return err
+			}
+			recordSagaEnd(ctx, recordMetricCmd, paymentSagaName, s.PaymentID, metric, outcome, s.Progress())
+			return nil
+		}
+	}
+
+	return saga.New(saga.Definition[*domain.SagaState]{
+		Name:       paymentSagaName,
+		Start:      domain.TopicPaymentCreated,
+		New:        newPaymentSagaState,
+		Decode:     decodePaymentEvent,
+		Cancel:     domain.TopicPaymentCancelRequested,
+		Cancelable: (*domain.SagaState).Cancelable,
+		// The saga may reach the capture between the API accepting a
+		// cancellation and the orchestrator reading it.
+		CancelRejected: func(ctx context.Context, s *domain.SagaState) error {
+			warnNotify(notifyUserCmd.Notify(ctx, s.PaymentID, domain.PaymentCancelRejected), domain.PaymentCancelRejected, s.PaymentID)
+			if err := recordMetricCmd.Record(ctx, s.PaymentID, domain.MetricPaymentCancelRejected, map[string]string{domain.MetricLabelStep: string(s.Step)}, 0); err != nil {
+				log.Printf("WARN: Failed to record %s for %s: %v", domain.MetricPaymentCancelRejected, s.PaymentID, err)
+			}
+			return nil
+		},
+		TimedOut: domain.TopicPaymentSagaTimedOut,
+		Steps: []saga.Step[*domain.SagaState]{
+			{
+				Name: domain.SagaStepHoldingFunds,
+				Action: func(ctx context.Context, s *domain.SagaState) error {
+					return holdFundsCmd.Hold(ctx, s.PaymentID, s.WalletID, s.WalletAmount)
+				},
+				Success: domain.TopicWalletFunds,
+				Failure: domain.TopicWalletHoldFundsFailed,
+				Compensation: &saga.Compensation[*domain.SagaState]{
+					Name: domain.SagaStepReleasingFunds,
+					Action: func(ctx context.Context, s *domain.SagaState) error {
+						return releaseFundsCmd.Release(ctx, s.PaymentID, s.WalletID, s.WalletAmount)
+					},
+					Done: domain.TopicWalletFundsReleased,
+				},
+			},
+			{
+				Name: domain.SagaStepAuthorizing,
+				Action: func(ctx context.Context, s *domain.SagaState) error {
+					if err := updateStatusCmd.UpdateStatus(ctx, s.PaymentID, domain.PaymentStatusFundsHeld); err != nil {
+						return err
+					}
+					return authorizeCmd.Authorize(ctx, s.PaymentID, s.WalletID, s.Amount, s.Method, s.Token)
+				},
+				Success: domain.TopicGatewayAuthorized,
+				Failure: domain.TopicGatewayAuthorizationFailed,
+				Timeout: AuthorizationTimeout,
+				// The gateway does not confirm voids, nothing waits on them.
+				// Voiding a payment it never authorized, or whose capture was
+				// refunded, does nothing.
+				Compensation: &saga.Compensation[*domain.SagaState]{
+					Name: domain.SagaStepVoidingAuth,
+					Action: func(ctx context.Context, s *domain.SagaState) error {
+						return voidCmd.Void(ctx, s.PaymentID, s.WalletID, s.Method)
+					},
+				},
+			},
+			{
+				Name: domain.SagaStepCapturing,
+				Action: func(ctx context.Context, s *domain.SagaState) error {
+					if err := updateStatusCmd.UpdateStatus(ctx, s.PaymentID, domain.PaymentStatusAuthorized); err != nil {
+						return err
+					}
+					return captureCmd.Capture(ctx, s.PaymentID, s.WalletID, s.Method, s.Amount)
+				},
+				Success: domain.TopicGatewayCaptured,
+				Failure: domain.TopicGatewayCaptureFailed,
+				// A captured payment whose debit fails is refunded. A declined
+				// refund leaves the payment for review.
+				Compensation: &saga.Compensation[*domain.SagaState]{
+					Name: domain.SagaStepRefundingAuth,
+					Action: func(ctx context.Context, s *domain.SagaState) error {
+						return refundAuthCmd.Refund(ctx, s.PaymentID, s.WalletID, s.Method, s.Amount)
+					},
+					Done:    domain.TopicGatewayAuthorizationRefunded,
+					Failure: domain.TopicGatewayAuthorizationRefundFailed,
+				},
+			},
+			{
+				Name: domain.SagaStepDebiting,
+				Action: func(ctx context.Context, s *domain.SagaState) error {
+					return debitFundsCmd.Debit(ctx, s.PaymentID, s.WalletID, s.WalletAmount)
+				},
+				Success: domain.TopicWalletDebitFunds,
+				Failure: domain.TopicWalletDebitFailed,
+			},
+			{
+				Name: domain.SagaStepCompleting,
+				Action: func(ctx context.Context, s *domain.SagaState) error {
+					if err := updateStatusCmd.UpdateStatus(ctx, s.PaymentID, domain.PaymentStatusDebited); err != nil {
+						return err
+					}
+					return updateStatusCmd.UpdateStatus(ctx, s.PaymentID, domain.PaymentStatusCompleted)
+				},
+				Success: domain.TopicPaymentCompleted,
+			},
+		},
+		Completed: saga.End[*domain.SagaState]{
+			Step: domain.SagaStepCompleted,
+			Action: func(ctx context.Context, s *domain.SagaState) error {
+				warnNotify(notifyUserCmd.Notify(ctx, s.PaymentID, domain.PaymentSuccess), domain.PaymentSuccess, s.PaymentID)
+				recordSagaEnd(ctx, recordMetricCmd, paymentSagaName, s.PaymentID, domain.MetricPaymentSuccess, outcomeCompleted, s.Progress())
+				return nil
+			},
+		},
+		Failed: saga.End[*domain.SagaState]{
+			Step:   domain.SagaStepFailed,
+			Action: end(domain.PaymentStatusFailed, domain.MetricPaymentFailure, outcomeFailed),
+		},
+		Canceled: saga.End[*domain.SagaState]{
+			Step:   domain.SagaStepCanceled,
+			Action: end(domain.PaymentStatusCanceled, domain.MetricPaymentCanceled, outcomeCanceled),
+		},
+		// The held funds stay in the wallet until someone settles the
+		// payment with the gateway.
+		Unresolved: saga.End[*domain.SagaState]{
+			Step: domain.SagaStepRequiresReview,
+			Action: func(ctx context.Context, s *domain.SagaState) error {
+				log.Printf("CRITICAL: Payment %s requires review: %s", s.PaymentID, s.Reason)
+				return end(domain.PaymentStatusRequiresReview, domain.MetricPaymentFailure, outcomeRequiresReview)(ctx, s)
+			},
+		},
+	}, paymentSagaStore{sagaStates})
+}
+
+// warnNotify logs a notification that could not be sent. The saga is over
+// either way, so it does not fail the step.
+func warnNotify(err error, notification domain.Notification, id string) {
+	if err != nil {
+		log.Printf("WARN: Failed to send %s notification for %s: %v", notification, id, err)
+	}
+}
+
+func newPaymentSagaState(msg []byte) (*domain.SagaState, error) {
+	var event domain.PaymentCreatedEvent
+	if err := json.Unmarshal(msg, &event); err != nil {
+		return nil, err
+	}
+	state := domain.NewSagaState(event)
+	return &state, nil
+}
+
+// decodePaymentEvent reads the payment an event refers to. Gateway events
+// carry it at the top level, the other services in the payload.
+func decodePaymentEvent(msg []byte) (saga.Event, error) {
+	var event struct {
+		PaymentID string `json:"payment_id"`
+		Reason    string `json:"reason"`
+		Payload   struct {
+			PaymentID string `json:"payment_id"`
+			Reason    string `json:"reason"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(msg, &event); err != nil {
+		return saga.Event{}, err
+	}
+
+	if event.PaymentID == "" {
+		event.PaymentID, event.Reason = event.Payload.PaymentID, event.Payload.Reason
+	}
+	if event.PaymentID == "" {
+		return saga.Event{}, errors.New("missing payment_id")
+	}
+	return saga.Event{ID: event.PaymentID, Reason: event.Reason}, nil
+}
+
+type paymentSagaStore struct {
+	sagaStates domain.SagaStateRepository
+}
+
+func (s paymentSagaStore) Load(paymentId string) (*domain.SagaState, error) {
+	state, err := s.sagaStates.GetByPaymentID(paymentId)
+	if errors.Is(err, domain.ErrSagaStateNotFound) {
+		return nil, fmt.Errorf("%w: %w", saga.ErrNotFound, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (s paymentSagaStore) Save(state *domain.SagaState) error {
+	return s.sagaStates.Save(*state)
+}
+
+func (s paymentSagaStore) Expired(now time.Time) ([]*domain.SagaState, error) {
+	states, err := s.sagaStates.ListExpired(now)
+	if err != nil {
+		return nil, err
+	}
+	expired := make([]*domain.SagaState, len(states))
+	for i := range states {
+		expired[i] = &states[i]
+	}
+	return expired, nil
+}
@@ -0,0 +1,525 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockHoldFunds struct{ mock.Mock }
+
+func (m *mockHoldFunds) Hold(ctx context.Context, paymentId, walletId string, amount domain.Money) error {
+	return m.Called(ctx, paymentId, walletId, amount).Error(0)
+}
+
+type mockReleaseFunds struct{ mock.Mock }
+
+func (m *mockReleaseFunds) Release(ctx context.Context, paymentId, walletId string, amount domain.Money) error {
+	return m.Called(ctx, paymentId, walletId, amount).Error(0)
+}
+
+type mockDebitFunds struct{ mock.Mock }
+
+func (m *mockDebitFunds) Debit(ctx context.Context, paymentId, walletId string, amount domain.Money) error {
+	return m.Called(ctx, paymentId, walletId, amount).Error(0)
+}
+
+type mockAuthorize struct{ mock.Mock }
+
+func (m *mockAuthorize) Authorize(ctx context.Context, paymentId, walletId string, amount domain.Money, method, token string) error {
+	return m.Called(ctx, paymentId, walletId, amount, method, token).Error(0)
+}
+
+type mockCapture struct{ mock.Mock }
+
+func (m *mockCapture) Capture(ctx context.Context, paymentId, walletId, method string, amount domain.Money) error {
+	return m.Called(ctx, paymentId, walletId, method, amount).Error(0)
+}
+
+type mockVoid struct{ mock.Mock }
+
+func (m *mockVoid) Void(ctx context.Context, paymentId, walletId, method string) error {
+	return m.Called(ctx, paymentId, walletId, method).Error(0)
+}
+
+type mockRefundAuthorization struct{ mock.Mock }
+
+func (m *mockRefundAuthorization) Refund(ctx context.Context, paymentId, walletId, method string, amount domain.Money) error {
+	return m.Called(ctx, paymentId, walletId, method, amount).Error(0)
+}
+
+type mockUpdateStatus struct{ mock.Mock }
+
+func (m *mockUpdateStatus) UpdateStatus(ctx context.Context, paymentId string, status domain.PaymentStatus) error {
+	return m.Called(ctx, paymentId, status).Error(0)
+}
+
+type mockNotifyUser struct{ mock.Mock }
+
+func (m *mockNotifyUser) Notify(ctx context.Context, paymentId string, notificationType domain.Notification) error {
+	return m.Called(ctx, paymentId, notificationType).Error(0)
+}
+
+type mockRecordMetric struct{ mock.Mock }
+
+func (m *mockRecordMetric) Record(ctx context.Context, id, metric string, labels map[string]string, value float64) error {
+	return m.Called(ctx, id, metric, labels, value).Error(0)
+}
+
+// sagaEndLabels matches the labels of a saga end metric.
+func sagaEndLabels(saga, outcome, reason string) any {
+	return mock.MatchedBy(func(labels map[string]string) bool {
+		return labels[domain.MetricLabelSaga] == saga &&
+			labels[domain.MetricLabelOutcome] == outcome &&
+			labels[domain.MetricLabelReason] == reason
+	})
+}
+
+type sagaMocks struct {
+	hold      *mockHoldFunds
+	release   *mockReleaseFunds
+	debit     *mockDebitFunds
+	authorize *mockAuthorize
+	capture   *mockCapture
+	void      *mockVoid
+	refund    *mockRefundAuthorization
+	update    *mockUpdateStatus
+	notify    *mockNotifyUser
+	metric    *mockRecordMetric
+}
+
+func newTestPaymentSaga() (*PaymentSaga, domain.SagaStateRepository, sagaMocks) {
+	m := sagaMocks{
+		hold:      new(mockHoldFunds),
+		release:   new(mockReleaseFunds),
+		debit:     new(mockDebitFunds),
+		authorize: new(mockAuthorize),
+		capture:   new(mockCapture),
+		void:      new(mockVoid),
+		refund:    new(mockRefundAuthorization),
+		update:    new(mockUpdateStatus),
+		notify:    new(mockNotifyUser),
+		metric:    new(mockRecordMetric),
+	}
+	repo := database.NewInMemorySagaStateRepository()
+
+	m.update.On("UpdateStatus", mock.Anything, "payment-123", mock.Anything).Return(nil)
+	m.capture.On("Capture", mock.Anything, "payment-123", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	return NewPaymentSaga(repo, m.hold, m.release, m.debit, m.authorize, m.capture, m.void, m.refund, m.update, m.notify, m.metric), repo, m
+}
+
+// assertStatuses checks the statuses the saga moved the payment to, which
+// the payment state machine must allow in that order.
+func assertStatuses(t *testing.T, m *mockUpdateStatus, expected ...domain.PaymentStatus) {
+	t.Helper()
+	var statuses []domain.PaymentStatus
+	for _, call := range m.Calls {
+		statuses = append(statuses, call.Arguments.Get(2).(domain.PaymentStatus))
+	}
+	assert.Equal(t, expected, statuses)
+
+	payment := domain.Payment{Status: domain.PaymentStatusPending}
+	for _, status := range statuses {
+		assert.NoError(t, payment.Transition(status))
+	}
+}
+
+func paymentCreated(t *testing.T) []byte {
+	return marshal(t, domain.PaymentCreatedEvent{
+		PaymentCreatedEventPayload: domain.PaymentCreatedEventPayload{
+			PaymentID: "payment-123",
+			WalletID:  "wallet-456",
+			Amount:    domain.NewMoney(250_00, "EUR"),
+			Token:     "token-789",
+			Method:    "card",
+			Status:    domain.PaymentStatusPending,
+		},
+	})
+}
+
+// Events coming back from the services only identify the payment.
+func walletEvent(t *testing.T) []byte {
+	return marshal(t, domain.WalletCommandEvent{WalletCommandEventPayload: domain.WalletCommandEventPayload{PaymentID: "payment-123"}})
+}
+
+func gatewayEvent(t *testing.T) []byte {
+	return marshal(t, domain.GatewayAuthorizedEvent{PaymentID: "payment-123"})
+}
+
+func paymentEvent(t *testing.T, status domain.PaymentStatus) []byte {
+	return marshal(t, domain.PaymentUpdateStatusEvent{
+		PaymentUpdateStatusEventPayload: domain.PaymentUpdateStatusEventPayload{PaymentID: "payment-123", Status: status},
+	})
+}
+
+func marshal(t *testing.T, event any) []byte {
+	t.Helper()
+	b, err := json.Marshal(event)
+	require.NoError(t, err)
+	return b
+}
+
+func TestPaymentSaga_PaymentDataFlowsThroughSaga(t *testing.T) {
+	ctx := context.Background()
+	s, repo, m := newTestPaymentSaga()
+
+	m.hold.On("Hold", ctx, "payment-123", "wallet-456", domain.NewMoney(250_00, "EUR")).Return(nil).Once()
+	m.authorize.On("Authorize", ctx, "payment-123", "wallet-456", domain.NewMoney(250_00, "EUR"), "card", "token-789").Return(nil).Once()
+	m.debit.On("Debit", ctx, "payment-123", "wallet-456", domain.NewMoney(250_00, "EUR")).Return(nil).Once()
+	m.notify.On("Notify", ctx, "payment-123", domain.PaymentSuccess).Return(nil).Once()
+	m.metric.On("Record", ctx, "payment-123", domain.MetricPaymentSuccess, sagaEndLabels("payment", "completed", ""), mock.Anything).Return(nil).Once()
+
+	require.NoError(t, s.Handle(ctx, domain.TopicPaymentCreated, paymentCreated(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletFunds, walletEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicGatewayAuthorized, gatewayEvent(t)))
+	// The funds are only debited once the capture is confirmed.
+	m.debit.AssertNotCalled(t, "Debit", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	require.NoError(t, s.Handle(ctx, domain.TopicGatewayCaptured, gatewayEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletDebitFunds, walletEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicPaymentCompleted, paymentEvent(t, domain.PaymentStatusCompleted)))
+	// A redelivered payment.completed neither notifies nor counts the payment twice.
+	require.NoError(t, s.Handle(ctx, domain.TopicPaymentCompleted, paymentEvent(t, domain.PaymentStatusCompleted)))
+
+	state, err := repo.GetByPaymentID("payment-123")
+	require.NoError(t, err)
+	assert.Equal(t, domain.SagaStepCompleted, state.Step)
+	assert.True(t, state.Ended)
+
+	m.hold.AssertExpectations(t)
+	m.authorize.AssertExpectations(t)
+	m.debit.AssertExpectations(t)
+	m.capture.AssertCalled(t, "Capture", ctx, "payment-123", "wallet-456", "card", domain.NewMoney(250_00, "EUR"))
+	assertStatuses(t, m.update, domain.PaymentStatusFundsHeld, domain.PaymentStatusAuthorized, domain.PaymentStatusDebited, domain.PaymentStatusCompleted)
+	m.notify.AssertExpectations(t)
+	m.metric.AssertExpectations(t)
+}
+
+func TestPaymentSaga_AuthorizationFailedReleasesStoredAmount(t *testing.T) {
+	ctx := context.Background()
+	s, repo, m := newTestPaymentSaga()
+
+	m.hold.On("Hold", ctx, "payment-123", "wallet-456", domain.NewMoney(250_00, "EUR")).Return(nil).Once()
+	m.authorize.On("Authorize", ctx, "payment-123", "wallet-456", mock.Anything, "card", "token-789").Return(nil).Once()
+	m.release.On("Release", ctx, "payment-123", "wallet-456", domain.NewMoney(250_00, "EUR")).Return(nil).Once()
+	m.metric.On("Record", ctx, "payment-123", domain.MetricPaymentFailure, sagaEndLabels("payment", "failed", "declined"), mock.Anything).Return(nil).Once()
+
+	require.NoError(t, s.Handle(ctx, domain.TopicPaymentCreated, paymentCreated(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletFunds, walletEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicGatewayAuthorizationFailed, marshal(t, domain.GatewayAuthorizationFailedEvent{
+		PaymentID: "payment-123",
+		Reason:    "declined",
+	})))
+
+	state, err := repo.GetByPaymentID("payment-123")
+	require.NoError(t, err)
+	assert.Equal(t, domain.SagaStepReleasingFunds, state.Step)
+	assert.Equal(t, "declined", state.Reason)
+
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletFundsReleased, walletEvent(t)))
+
+	state, err = repo.GetByPaymentID("payment-123")
+	require.NoError(t, err)
+	assert.Equal(t, domain.SagaStepFailed, state.Step)
+
+	m.void.AssertNotCalled(t, "Void", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	m.release.AssertExpectations(t)
+	assertStatuses(t, m.update, domain.PaymentStatusFundsHeld, domain.PaymentStatusFailed)
+	m.notify.AssertExpectations(t)
+	m.metric.AssertExpectations(t)
+}
+
+func TestPaymentSaga_AuthorizationTimeoutVoidsAndReleases(t *testing.T) {
+	ctx := context.Background()
+	s, repo, m := newTestPaymentSaga()
+
+	m.hold.On("Hold", ctx, "payment-123", "wallet-456", mock.Anything).Return(nil).Once()
+	m.authorize.On("Authorize", ctx, "payment-123", "wallet-456", mock.Anything, "card", "token-789").Return(nil).Once()
+	m.void.On("Void", ctx, "payment-123", "wallet-456", "card").Return(nil).Once()
+	m.release.On("Release", ctx, "payment-123", "wallet-456", domain.NewMoney(250_00, "EUR")).Return(nil).Once()
+	m.metric.On("Record", ctx, "payment-123", domain.MetricPaymentFailure, sagaEndLabels("payment", "failed", "step AUTHORIZING timed out"), mock.Anything).Return(nil).Once()
+
+	require.NoError(t, s.Handle(ctx, domain.TopicPaymentCreated, paymentCreated(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletFunds, walletEvent(t)))
+
+	timeouts, err := s.Expired(time.Now().Add(AuthorizationTimeout + time.Second))
+	require.NoError(t, err)
+	require.Len(t, timeouts, 1)
+	assert.Equal(t, domain.TopicPaymentSagaTimedOut, timeouts[0].Topic)
+	assert.Equal(t, domain.SagaStepAuthorizing, timeouts[0].Step)
+
+	// The gateway never answers.
+	state, err := repo.GetByPaymentID("payment-123")
+	require.NoError(t, err)
+	past := time.Now().Add(-time.Second)
+	state.Deadline = &past
+	require.NoError(t, repo.Save(state))
+
+	require.NoError(t, s.Handle(ctx, domain.TopicPaymentSagaTimedOut, marshal(t, domain.SagaTimedOutEvent{
+		SagaTimedOutEventPayload: domain.SagaTimedOutEventPayload{Saga: "payment", SagaID: "payment-123", Step: domain.SagaStepAuthorizing},
+	})))
+	// A late authorization does not resume the saga.
+	require.NoError(t, s.Handle(ctx, domain.TopicGatewayAuthorized, gatewayEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletFundsReleased, walletEvent(t)))
+
+	state, err = repo.GetByPaymentID("payment-123")
+	require.NoError(t, err)
+	assert.Equal(t, domain.SagaStepFailed, state.Step)
+	assert.Equal(t, "step AUTHORIZING timed out", state.Reason)
+
+	m.void.AssertExpectations(t)
+	m.release.AssertExpectations(t)
+	m.debit.AssertNotCalled(t, "Debit", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assertStatuses(t, m.update, domain.PaymentStatusFundsHeld, domain.PaymentStatusFailed)
+	m.notify.AssertExpectations(t)
+}
+
+func TestPaymentSaga_DebitFailedRefundsCaptureAndReleases(t *testing.T) {
+	ctx := context.Background()
+	s, repo, m := newTestPaymentSaga()
+
+	m.hold.On("Hold", ctx, "payment-123", "wallet-456", mock.Anything).Return(nil).Once()
+	m.authorize.On("Authorize", ctx, "payment-123", "wallet-456", mock.Anything, "card", "token-789").Return(nil).Once()
+	m.debit.On("Debit", ctx, "payment-123", "wallet-456", mock.Anything).Return(nil).Once()
+	m.refund.On("Refund", ctx, "payment-123", "wallet-456", "card", domain.NewMoney(250_00, "EUR")).Return(nil).Once()
+	m.void.On("Void", ctx, "payment-123", "wallet-456", "card").Return(nil).Once()
+	m.release.On("Release", ctx, "payment-123", "wallet-456", domain.NewMoney(250_00, "EUR")).Return(nil).Once()
+	m.metric.On("Record", ctx, "payment-123", domain.MetricPaymentFailure, sagaEndLabels("payment", "failed", "hold is not active"), mock.Anything).Return(nil).Once()
+
+	require.NoError(t, s.Handle(ctx, domain.TopicPaymentCreated, paymentCreated(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletFunds, walletEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicGatewayAuthorized, gatewayEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicGatewayCaptured, gatewayEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletDebitFailed, marshal(t, domain.WalletCommandEvent{
+		WalletCommandEventPayload: domain.WalletCommandEventPayload{PaymentID: "payment-123", Reason: "hold is not active"},
+	})))
+
+	state, err := repo.GetByPaymentID("payment-123")
+	require.NoError(t, err)
+	assert.Equal(t, domain.SagaStepRefundingAuth, state.Step)
+	assert.Equal(t, "hold is not active", state.Reason)
+	assert.ErrorIs(t, state.Cancelable(), domain.ErrPaymentNotCancelable)
+
+	require.NoError(t, s.Handle(ctx, domain.TopicGatewayAuthorizationRefunded, gatewayEvent(t)))
+
+	state, err = repo.GetByPaymentID("payment-123")
+	require.NoError(t, err)
+	assert.Equal(t, domain.SagaStepReleasingFunds, state.Step)
+
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletFundsReleased, walletEvent(t)))
+
+	state, err = repo.GetByPaymentID("payment-123")
+	require.NoError(t, err)
+	assert.Equal(t, domain.SagaStepFailed, state.Step)
+
+	m.refund.AssertExpectations(t)
+	m.void.AssertExpectations(t)
+	m.release.AssertExpectations(t)
+	assertStatuses(t, m.update, domain.PaymentStatusFundsHeld, domain.PaymentStatusAuthorized, domain.PaymentStatusFailed)
+	m.notify.AssertExpectations(t)
+}
+
+func TestPaymentSaga_DeclinedAuthorizationRefundRequiresReview(t *testing.T) {
+	ctx := context.Background()
+	s, repo, m := newTestPaymentSaga()
+
+	m.hold.On("Hold", ctx, "payment-123", "wallet-456", mock.Anything).Return(nil).Once()
+	m.authorize.On("Authorize", ctx, "payment-123", "wallet-456", mock.Anything, "card", "token-789").Return(nil).Once()
+	m.debit.On("Debit", ctx, "payment-123", "wallet-456", mock.Anything).Return(nil).Once()
+	m.refund.On("Refund", ctx, "payment-123", "wallet-456", "card", domain.NewMoney(250_00, "EUR")).Return(nil).Once()
+	m.metric.On("Record", ctx, "payment-123", domain.MetricPaymentFailure, sagaEndLabels("payment", "requires_review", "gateway: declined"), mock.Anything).Return(nil).Once()
+
+	require.NoError(t, s.Handle(ctx, domain.TopicPaymentCreated, paymentCreated(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletFunds, walletEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicGatewayAuthorized, gatewayEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicGatewayCaptured, gatewayEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletDebitFailed, marshal(t, domain.WalletCommandEvent{
+		WalletCommandEventPayload: domain.WalletCommandEventPayload{PaymentID: "payment-123", Reason: "hold is not active"},
+	})))
+	require.NoError(t, s.Handle(ctx, domain.TopicGatewayAuthorizationRefundFailed, marshal(t, domain.GatewayAuthorizationFailedEvent{
+		PaymentID: "payment-123",
+		Reason:    "gateway: declined",
+	})))
+	// The funds are not released once the saga gave up.
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletFundsReleased, walletEvent(t)))
+
+	state, err := repo.GetByPaymentID("payment-123")
+	require.NoError(t, err)
+	assert.Equal(t, domain.SagaStepRequiresReview, state.Step)
+	assert.Equal(t, "gateway: declined", state.Reason)
+	assert.True(t, state.Ended)
+
+	m.release.AssertNotCalled(t, "Release", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	m.metric.AssertExpectations(t)
+	assertStatuses(t, m.update, domain.PaymentStatusFundsHeld, domain.PaymentStatusAuthorized, domain.PaymentStatusRequiresReview)
+}
+
+func TestPaymentSaga_CaptureFailedVoidsAndReleases(t *testing.T) {
+	ctx := context.Background()
+	s, repo, m := newTestPaymentSaga()
+
+	m.hold.On("Hold", ctx, "payment-123", "wallet-456", mock.Anything).Return(nil).Once()
+	m.authorize.On("Authorize", ctx, "payment-123", "wallet-456", mock.Anything, "card", "token-789").Return(nil).Once()
+	m.void.On("Void", ctx, "payment-123", "wallet-456", "card").Return(nil).Once()
+	m.release.On("Release", ctx, "payment-123", "wallet-456", domain.NewMoney(250_00, "EUR")).Return(nil).Once()
+	m.metric.On("Record", ctx, "payment-123", domain.MetricPaymentFailure, sagaEndLabels("payment", "failed", "gateway: declined"), mock.Anything).Return(nil).Once()
+
+	require.NoError(t, s.Handle(ctx, domain.TopicPaymentCreated, paymentCreated(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletFunds, walletEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicGatewayAuthorized, gatewayEvent(t)))
+
+	state, err := repo.GetByPaymentID("payment-123")
+	require.NoError(t, err)
+	assert.Equal(t, domain.SagaStepCapturing, state.Step)
+	assert.ErrorIs(t, state.Cancelable(), domain.ErrPaymentNotCancelable)
+
+	require.NoError(t, s.Handle(ctx, domain.TopicGatewayCaptureFailed, marshal(t, domain.GatewayAuthorizationFailedEvent{
+		PaymentID: "payment-123",
+		Reason:    "gateway: declined",
+	})))
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletFundsReleased, walletEvent(t)))
+
+	state, err = repo.GetByPaymentID("payment-123")
+	require.NoError(t, err)
+	assert.Equal(t, domain.SagaStepFailed, state.Step)
+	assert.Equal(t, "gateway: declined", state.Reason)
+
+	m.debit.AssertNotCalled(t, "Debit", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	m.refund.AssertNotCalled(t, "Refund", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	m.void.AssertExpectations(t)
+	m.release.AssertExpectations(t)
+	assertStatuses(t, m.update, domain.PaymentStatusFundsHeld, domain.PaymentStatusAuthorized, domain.PaymentStatusFailed)
+	m.metric.AssertExpectations(t)
+}
+
+func TestPaymentSaga_HoldFailedFailsPayment(t *testing.T) {
+	ctx := context.Background()
+	s, repo, m := newTestPaymentSaga()
+
+	m.hold.On("Hold", ctx, "payment-123", "wallet-456", mock.Anything).Return(nil).Once()
+	m.metric.On("Record", ctx, "payment-123", domain.MetricPaymentFailure, sagaEndLabels("payment", "failed", ""), mock.Anything).Return(nil).Once()
+
+	require.NoError(t, s.Handle(ctx, domain.TopicPaymentCreated, paymentCreated(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletHoldFundsFailed, walletEvent(t)))
+
+	state, err := repo.GetByPaymentID("payment-123")
+	require.NoError(t, err)
+	assert.Equal(t, domain.SagaStepFailed, state.Step)
+
+	m.release.AssertNotCalled(t, "Release", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assertStatuses(t, m.update, domain.PaymentStatusFailed)
+	m.notify.AssertExpectations(t)
+}
+
+func TestPaymentSaga_UnknownPayment(t *testing.T) {
+	s, _, _ := newTestPaymentSaga()
+
+	err := s.Handle(context.Background(), domain.TopicWalletFunds, marshal(t, domain.WalletCommandEvent{
+		WalletCommandEventPayload: domain.WalletCommandEventPayload{PaymentID: "missing"},
+	}))
+
+	assert.ErrorIs(t, err, domain.ErrSagaStateNotFound)
+}
+
+func TestPaymentSaga_CancelWhileHoldingReleasesFunds(t *testing.T) {
+	ctx := context.Background()
+	s, repo, m := newTestPaymentSaga()
+
+	m.hold.On("Hold", ctx, "payment-123", "wallet-456", domain.NewMoney(250_00, "EUR")).Return(nil).Once()
+	m.release.On("Release", ctx, "payment-123", "wallet-456", domain.NewMoney(250_00, "EUR")).Return(nil).Once()
+	m.metric.On("Record", ctx, "payment-123", domain.MetricPaymentCanceled, sagaEndLabels("payment", "canceled", ""), mock.Anything).Return(nil).Once()
+
+	require.NoError(t, s.Handle(ctx, domain.TopicPaymentCreated, paymentCreated(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicPaymentCancelRequested, paymentEvent(t, domain.PaymentStatusCanceled)))
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletFunds, walletEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletFundsReleased, walletEvent(t)))
+
+	state, err := repo.GetByPaymentID("payment-123")
+	require.NoError(t, err)
+	assert.Equal(t, domain.SagaStepCanceled, state.Step)
+
+	m.authorize.AssertNotCalled(t, "Authorize", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	m.release.AssertExpectations(t)
+	assertStatuses(t, m.update, domain.PaymentStatusCanceled)
+	m.notify.AssertExpectations(t)
+	m.metric.AssertExpectations(t)
+}
+
+func TestPaymentSaga_CancelWhileAuthorizingVoidsAuthorization(t *testing.T) {
+	ctx := context.Background()
+	s, repo, m := newTestPaymentSaga()
+
+	m.hold.On("Hold", ctx, "payment-123", "wallet-456", mock.Anything).Return(nil).Once()
+	m.authorize.On("Authorize", ctx, "payment-123", "wallet-456", mock.Anything, "card", "token-789").Return(nil).Once()
+	m.void.On("Void", ctx, "payment-123", "wallet-456", "card").Return(nil).Once()
+	m.release.On("Release", ctx, "payment-123", "wallet-456", domain.NewMoney(250_00, "EUR")).Return(nil).Once()
+
+	require.NoError(t, s.Handle(ctx, domain.TopicPaymentCreated, paymentCreated(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletFunds, walletEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicPaymentCancelRequested, paymentEvent(t, domain.PaymentStatusCanceled)))
+	require.NoError(t, s.Handle(ctx, domain.TopicGatewayAuthorized, gatewayEvent(t)))
+
+	state, err := repo.GetByPaymentID("payment-123")
+	require.NoError(t, err)
+	assert.Equal(t, domain.SagaStepReleasingFunds, state.Step)
+
+	m.debit.AssertNotCalled(t, "Debit", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	m.void.AssertExpectations(t)
+	m.release.AssertExpectations(t)
+	assertStatuses(t, m.update, domain.PaymentStatusFundsHeld)
+}
+
+func TestPaymentSaga_CancelWhileCapturingIsRejected(t *testing.T) {
+	ctx := context.Background()
+	s, repo, m := newTestPaymentSaga()
+
+	m.hold.On("Hold", ctx, "payment-123", "wallet-456", mock.Anything).Return(nil).Once()
+	m.authorize.On("Authorize", ctx, "payment-123", "wallet-456", mock.Anything, "card", "token-789").Return(nil).Once()
+	m.notify.On("Notify", ctx, "payment-123", domain.PaymentCancelRejected).Return(nil).Once()
+	m.metric.On("Record", ctx, "payment-123", domain.MetricPaymentCancelRejected, map[string]string{domain.MetricLabelStep: string(domain.SagaStepCapturing)}, mock.Anything).Return(nil).Once()
+
+	require.NoError(t, s.Handle(ctx, domain.TopicPaymentCreated, paymentCreated(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletFunds, walletEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicGatewayAuthorized, gatewayEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicPaymentCancelRequested, paymentEvent(t, domain.PaymentStatusCanceled)))
+
+	state, err := repo.GetByPaymentID("payment-123")
+	require.NoError(t, err)
+	assert.Equal(t, domain.SagaStepCapturing, state.Step)
+	assert.False(t, state.CancelRequested)
+	assert.ErrorIs(t, state.Cancelable(), domain.ErrPaymentNotCancelable)
+	m.notify.AssertExpectations(t)
+	m.metric.AssertExpectations(t)
+}
+
+func TestPaymentSaga_CancelAfterDebitIsRejected(t *testing.T) {
+	ctx := context.Background()
+	s, repo, m := newTestPaymentSaga()
+
+	m.hold.On("Hold", ctx, "payment-123", "wallet-456", mock.Anything).Return(nil).Once()
+	m.authorize.On("Authorize", ctx, "payment-123", "wallet-456", mock.Anything, "card", "token-789").Return(nil).Once()
+	m.debit.On("Debit", ctx, "payment-123", "wallet-456", mock.Anything).Return(nil).Once()
+	m.notify.On("Notify", ctx, "payment-123", domain.PaymentCancelRejected).Return(nil).Once()
+	m.metric.On("Record", ctx, "payment-123", domain.MetricPaymentCancelRejected, map[string]string{domain.MetricLabelStep: string(domain.SagaStepDebiting)}, mock.Anything).Return(nil).Once()
+
+	require.NoError(t, s.Handle(ctx, domain.TopicPaymentCreated, paymentCreated(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletFunds, walletEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicGatewayAuthorized, gatewayEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicGatewayCaptured, gatewayEvent(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicPaymentCancelRequested, paymentEvent(t, domain.PaymentStatusCanceled)))
+
+	state, err := repo.GetByPaymentID("payment-123")
+	require.NoError(t, err)
+	assert.Equal(t, domain.SagaStepDebiting, state.Step)
+	assert.False(t, state.CancelRequested)
+	assertStatuses(t, m.update, domain.PaymentStatusFundsHeld, domain.PaymentStatusAuthorized)
+	m.notify.AssertExpectations(t)
+	m.metric.AssertExpectations(t)
+}
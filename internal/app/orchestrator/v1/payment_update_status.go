@@ -56,6 +56,7 @@ func (c *updatePaymentStatusCommand) buildEventV1(traceID, paymentId string, sta
 				MessageDeduplicationId: domain.BuildDeduplicationId(
 					domain.PaymentUpdateStatusEventType,
 					paymentId,
+					string(status),
 				),
 			},
 		},
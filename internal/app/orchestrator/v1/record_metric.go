@@ -0,0 +1,73 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/publisher"
+)
+
+// Outcomes of a saga, as reported to the metrics consumer.
+const (
+	outcomeCompleted = "completed"
+	outcomeFailed    = "failed"
+	outcomeCanceled  = "canceled"
+	// outcomeCreditFailed is a refund given back by the gateway but never
+	// credited to the wallet, which needs manual review.
+	outcomeCreditFailed = "credit_failed"
+	// outcomeRequiresReview is a payment the saga could not compensate.
+	outcomeRequiresReview = "requires_review"
+)
+
+type RecordMetricCommand interface {
+	Record(ctx context.Context, id, metric string, labels map[string]string, value float64) error
+}
+
+type recordMetricCommand struct {
+	publisher publisher.Client
+}
+
+func NewRecordMetricCommand(
+	publisher publisher.Client,
+) *recordMetricCommand {
+	return &recordMetricCommand{
+		publisher,
+	}
+}
+
+func (c *recordMetricCommand) Record(ctx context.Context, id, metric string, labels map[string]string, value float64) error {
+	b, err := json.Marshal(domain.NewMetricEvent(metric, id, labels, value))
+	if err != nil {
+		log.Printf("ERROR: Failed to marshal recordMetricCommand event: %v", err)
+	}
+
+	return retry.Do(
+		func() error {
+			return c.publisher.Publish(ctx, domain.TopicMetrics, b)
+		},
+		retry.Attempts(3),
+		retry.DelayType(retry.BackOffDelay),
+		retry.Delay(100*time.Millisecond),
+	)
+}
+
+// recordSagaEnd reports how a saga ended and how long it took, with the reason
+// of a failure. Like notifications, a metric that could not be sent does not
+// fail the end of the saga.
+func recordSagaEnd(ctx context.Context, cmd RecordMetricCommand, saga, id, metric, outcome string, p *domain.SagaProgress) {
+	labels := map[string]string{
+		domain.MetricLabelSaga:    saga,
+		domain.MetricLabelOutcome: outcome,
+	}
+	if p.Reason != "" {
+		labels[domain.MetricLabelReason] = p.Reason
+	}
+
+	if err := cmd.Record(ctx, id, metric, labels, time.Since(p.CreatedAt).Seconds()); err != nil {
+		log.Printf("WARN: Failed to record %s for %s: %v", metric, id, err)
+	}
+}
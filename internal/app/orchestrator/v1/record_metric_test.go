@@ -0,0 +1,61 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordMetricCommand_Record(t *testing.T) {
+	tests := []struct {
+		name          string
+		setupMocks    func(publisher *MockPublisher)
+		expectedError bool
+	}{
+		{
+			name: "publisher fails after retries",
+			setupMocks: func(publisher *MockPublisher) {
+				publisher.On("Publish", mock.Anything, domain.TopicMetrics, mock.Anything).Return(errors.New("publisher error")).Times(3)
+			},
+			expectedError: true,
+		},
+		{
+			name: "publisher succeeds",
+			setupMocks: func(publisher *MockPublisher) {
+				publisher.On("Publish", mock.Anything, domain.TopicMetrics, mock.MatchedBy(func(b []byte) bool {
+					var event domain.MetricEvent
+					require.NoError(t, json.Unmarshal(b, &event))
+					return event.Metric == domain.MetricPaymentSuccess &&
+						event.Value == 1.5 &&
+						event.Labels[domain.MetricLabelSaga] == "payment" &&
+						event.MessageDeduplicationId == "metric.payment_success.payment-123"
+				})).Return(nil).Once()
+			},
+			expectedError: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisherMock := new(MockPublisher)
+			tt.setupMocks(publisherMock)
+
+			cmd := NewRecordMetricCommand(publisherMock)
+			err := cmd.Record(context.Background(), "payment-123", domain.MetricPaymentSuccess, map[string]string{domain.MetricLabelSaga: "payment"}, 1.5)
+
+			if tt.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			publisherMock.AssertExpectations(t)
+		})
+	}
+}
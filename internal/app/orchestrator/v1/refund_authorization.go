@@ -0,0 +1,77 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/google/uuid"
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/publisher"
+)
+
+// RefundAuthorizationCommand gives back a whole gateway authorization that can
+// no longer be voided, e.g. because the debit of its payment failed.
+type RefundAuthorizationCommand interface {
+	Refund(ctx context.Context, paymentId, walletId, method string, amount domain.Money) error
+}
+
+type refundAuthorizationCommand struct {
+	publisher publisher.Client
+}
+
+func NewRefundAuthorizationCommand(
+	publisher publisher.Client,
+) *refundAuthorizationCommand {
+	return &refundAuthorizationCommand{
+		publisher,
+	}
+}
+
+func (c *refundAuthorizationCommand) Refund(ctx context.Context, paymentId, walletId, method string, amount domain.Money) error {
+	traceID := uuid.NewString()
+
+	b := c.buildEventV2(traceID, paymentId, walletId, method, amount)
+
+	return retry.Do(
+		func() error {
+			return c.publisher.Publish(ctx, domain.TopicOrchestratorGateway, b)
+		},
+		retry.Attempts(3),
+		retry.DelayType(retry.BackOffDelay),
+		retry.Delay(100*time.Millisecond),
+	)
+}
+
+func (c *refundAuthorizationCommand) buildEventV2(traceID, paymentId, walletId, method string, amount domain.Money) []byte {
+	event := domain.WalletCommandEvent{
+		CommandEvent: domain.CommandEvent{
+			EventType:    domain.RefundAuthorizationEventType,
+			EventVersion: domain.WalletCommandEventVersion,
+			Timestamp:    time.Now().UTC().Format(time.RFC3339),
+			CommandEventMetadata: domain.CommandEventMetadata{
+				TraceID:        traceID,
+				MessageGroupID: paymentId,
+				MessageDeduplicationId: domain.BuildDeduplicationId(
+					domain.RefundAuthorizationEventType,
+					paymentId,
+				),
+			},
+		},
+		WalletCommandEventPayload: domain.WalletCommandEventPayload{
+			WalletID:  walletId,
+			PaymentID: paymentId,
+			Amount:    amount,
+			Method:    method,
+		},
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("ERROR: Failed to marshal refundAuthorizationCommand event: %v", err)
+	}
+
+	return b
+}
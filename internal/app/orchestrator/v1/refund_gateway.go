@@ -0,0 +1,75 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/google/uuid"
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/publisher"
+)
+
+type RefundGatewayCommand interface {
+	Refund(ctx context.Context, refundId, paymentId, method string, amount domain.Money) error
+}
+
+type refundGatewayCommand struct {
+	publisher publisher.Client
+}
+
+func NewRefundGatewayCommand(
+	publisher publisher.Client,
+) *refundGatewayCommand {
+	return &refundGatewayCommand{
+		publisher,
+	}
+}
+
+func (c *refundGatewayCommand) Refund(ctx context.Context, refundId, paymentId, method string, amount domain.Money) error {
+	traceID := uuid.NewString()
+
+	b := c.buildEventV1(traceID, refundId, paymentId, method, amount)
+
+	return retry.Do(
+		func() error {
+			return c.publisher.Publish(ctx, domain.TopicOrchestratorGateway, b)
+		},
+		retry.Attempts(3),
+		retry.DelayType(retry.BackOffDelay),
+		retry.Delay(100*time.Millisecond),
+	)
+}
+
+func (c *refundGatewayCommand) buildEventV1(traceID, refundId, paymentId, method string, amount domain.Money) []byte {
+	event := domain.RefundCommandEvent{
+		CommandEvent: domain.CommandEvent{
+			EventType:    domain.RefundGatewayEventType,
+			EventVersion: "1",
+			Timestamp:    time.Now().UTC().Format(time.RFC3339),
+			CommandEventMetadata: domain.CommandEventMetadata{
+				TraceID:        traceID,
+				MessageGroupID: paymentId,
+				MessageDeduplicationId: domain.BuildDeduplicationId(
+					domain.RefundGatewayEventType,
+					refundId,
+				),
+			},
+		},
+		RefundCommandEventPayload: domain.RefundCommandEventPayload{
+			RefundID:  refundId,
+			PaymentID: paymentId,
+			Amount:    amount,
+			Method:    method,
+		},
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("ERROR: Failed to marshal refundGatewayCommand event: %v", err)
+	}
+
+	return b
+}
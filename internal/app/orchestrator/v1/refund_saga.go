@@ -0,0 +1,147 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/mmarias/golearn/internal/app/orchestrator/saga"
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+type RefundSaga = saga.Engine[*domain.RefundSagaState]
+
+const refundSagaName = "refund"
+
+// NewRefundSaga runs the refund saga: the gateway gives the money back, the
+// wallet is credited and the refunds service records the refund. A failed
+// credit cannot be compensated, as the gateway already gave the money back:
+// the refund is kept as CREDIT_FAILED, still counting against the payment, and
+// reported as critical for manual review.
+func NewRefundSaga(
+	refundSagas domain.RefundSagaStateRepository,
+	refundGatewayCmd RefundGatewayCommand,
+	creditFundsCmd CreditFundsCommand,
+	updateRefundStatusCmd UpdateRefundStatusCommand,
+	notifyRefundCmd NotifyRefundCommand,
+	recordMetricCmd RecordMetricCommand,
+) *RefundSaga {
+	return saga.New(saga.Definition[*domain.RefundSagaState]{
+		Name:   refundSagaName,
+		Start:  domain.TopicRefundCreated,
+		New:    newRefundSagaState,
+		Decode: decodeRefundEvent,
+		Steps: []saga.Step[*domain.RefundSagaState]{
+			{
+				Name: domain.RefundSagaStepRefundingGateway,
+				Action: func(ctx context.Context, s *domain.RefundSagaState) error {
+					return refundGatewayCmd.Refund(ctx, s.RefundID, s.PaymentID, s.Method, s.Amount)
+				},
+				Success: domain.TopicGatewayRefunded,
+				Failure: domain.TopicGatewayRefundFailed,
+			},
+			{
+				// Credited in the wallet currency.
+				Name: domain.RefundSagaStepCreditingWallet,
+				Action: func(ctx context.Context, s *domain.RefundSagaState) error {
+					return creditFundsCmd.Credit(ctx, s.RefundID, s.PaymentID, s.WalletID, s.WalletAmount)
+				},
+				Success: domain.TopicWalletFundsCredited,
+				Failure: domain.TopicWalletCreditFailed,
+			},
+			{
+				Name: domain.RefundSagaStepCompleting,
+				Action: func(ctx context.Context, s *domain.RefundSagaState) error {
+					return updateRefundStatusCmd.UpdateRefundStatus(ctx, s.RefundID, s.PaymentID, domain.RefundStatusCompleted, "")
+				},
+				Success: domain.TopicRefundCompleted,
+			},
+		},
+		Completed: saga.End[*domain.RefundSagaState]{
+			Step: domain.RefundSagaStepCompleted,
+			Action: func(ctx context.Context, s *domain.RefundSagaState) error {
+				err := notifyRefundCmd.NotifyRefund(ctx, s.RefundID, s.PaymentID, domain.RefundSuccess)
+				warnNotify(err, domain.RefundSuccess, s.RefundID)
+				recordSagaEnd(ctx, recordMetricCmd, refundSagaName, s.RefundID, domain.MetricRefundSuccess, outcomeCompleted, s.Progress())
+				return nil
+			},
+		},
+		Failed: saga.End[*domain.RefundSagaState]{
+			Step: domain.RefundSagaStepFailed,
+			Action: func(ctx context.Context, s *domain.RefundSagaState) error {
+				status, outcome := domain.RefundStatusFailed, outcomeFailed
+				if s.LastEvent == domain.TopicWalletCreditFailed {
+					status, outcome = domain.RefundStatusCreditFailed, outcomeCreditFailed
+					log.Printf("CRITICAL: Refund %s was given back by the gateway but wallet %s was not credited with %s: %s", s.RefundID, s.WalletID, s.WalletAmount, s.Reason)
+				}
+
+				err := updateRefundStatusCmd.UpdateRefundStatus(ctx, s.RefundID, s.PaymentID, status, s.Reason)
+				if err != nil {
+					log.Printf("CRITICAL: Failed to update refund status for failed RefundID %s: %v", s.RefundID, err)
+					return err
+				}
+				err = notifyRefundCmd.NotifyRefund(ctx, s.RefundID, s.PaymentID, domain.RefundFailure)
+				warnNotify(err, domain.RefundFailure, s.RefundID)
+				recordSagaEnd(ctx, recordMetricCmd, refundSagaName, s.RefundID, domain.MetricRefundFailure, outcome, s.Progress())
+				return nil
+			},
+		},
+	}, refundSagaStore{refundSagas})
+}
+
+func newRefundSagaState(msg []byte) (*domain.RefundSagaState, error) {
+	var event domain.RefundCommandEvent
+	if err := json.Unmarshal(msg, &event); err != nil {
+		return nil, err
+	}
+	state := domain.NewRefundSagaState(event)
+	return &state, nil
+}
+
+func decodeRefundEvent(msg []byte) (saga.Event, error) {
+	var event domain.RefundCommandEvent
+	if err := json.Unmarshal(msg, &event); err != nil {
+		return saga.Event{}, err
+	}
+	if event.RefundID == "" {
+		return saga.Event{}, errors.New("missing refund_id")
+	}
+	return saga.Event{ID: event.RefundID, Reason: event.Reason}, nil
+}
+
+type refundSagaStore struct {
+	refundSagas domain.RefundSagaStateRepository
+}
+
+func (s refundSagaStore) Load(refundId string) (*domain.RefundSagaState, error) {
+	state, err := s.refundSagas.GetByRefundID(refundId)
+	if errors.Is(err, domain.ErrRefundSagaStateNotFound) {
+		return nil, fmt.Errorf("%w: %w", saga.ErrNotFound, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Sagas moved before refund.created and refund.completed were renamed
+	// still resume on the redelivery of the event.
+	state.LastEvent = domain.CurrentTopic(state.LastEvent)
+	return &state, nil
+}
+
+func (s refundSagaStore) Save(state *domain.RefundSagaState) error {
+	return s.refundSagas.Save(*state)
+}
+
+func (s refundSagaStore) Expired(now time.Time) ([]*domain.RefundSagaState, error) {
+	states, err := s.refundSagas.ListExpired(now)
+	if err != nil {
+		return nil, err
+	}
+	expired := make([]*domain.RefundSagaState, len(states))
+	for i := range states {
+		expired[i] = &states[i]
+	}
+	return expired, nil
+}
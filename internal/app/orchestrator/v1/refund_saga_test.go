@@ -0,0 +1,186 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRefundGateway struct{ mock.Mock }
+
+func (m *mockRefundGateway) Refund(ctx context.Context, refundId, paymentId, method string, amount domain.Money) error {
+	return m.Called(ctx, refundId, paymentId, method, amount).Error(0)
+}
+
+type mockCreditFunds struct{ mock.Mock }
+
+func (m *mockCreditFunds) Credit(ctx context.Context, refundId, paymentId, walletId string, amount domain.Money) error {
+	return m.Called(ctx, refundId, paymentId, walletId, amount).Error(0)
+}
+
+type mockUpdateRefundStatus struct{ mock.Mock }
+
+func (m *mockUpdateRefundStatus) UpdateRefundStatus(ctx context.Context, refundId, paymentId string, status domain.RefundStatus, reason string) error {
+	return m.Called(ctx, refundId, paymentId, status, reason).Error(0)
+}
+
+type mockNotifyRefund struct{ mock.Mock }
+
+func (m *mockNotifyRefund) NotifyRefund(ctx context.Context, refundId, paymentId string, notificationType domain.Notification) error {
+	return m.Called(ctx, refundId, paymentId, notificationType).Error(0)
+}
+
+type refundMocks struct {
+	gateway *mockRefundGateway
+	credit  *mockCreditFunds
+	update  *mockUpdateRefundStatus
+	notify  *mockNotifyRefund
+	metric  *mockRecordMetric
+}
+
+func newTestRefundSaga() (*RefundSaga, domain.RefundSagaStateRepository, refundMocks) {
+	m := refundMocks{
+		gateway: new(mockRefundGateway),
+		credit:  new(mockCreditFunds),
+		update:  new(mockUpdateRefundStatus),
+		notify:  new(mockNotifyRefund),
+		metric:  new(mockRecordMetric),
+	}
+	repo := database.NewInMemoryRefundSagaStateRepository()
+
+	return NewRefundSaga(repo, m.gateway, m.credit, m.update, m.notify, m.metric), repo, m
+}
+
+func refundCreated(t *testing.T) []byte {
+	return marshal(t, domain.RefundCommandEvent{
+		RefundCommandEventPayload: domain.RefundCommandEventPayload{
+			RefundID:     "refund-1",
+			PaymentID:    "payment-123",
+			WalletID:     "wallet-456",
+			Amount:       domain.NewMoney(50_00, "EUR"),
+			WalletAmount: domain.NewMoney(54_00, "USD"),
+			Method:       "card",
+			Status:       domain.RefundStatusPending,
+		},
+	})
+}
+
+// Events coming back from the services only identify the refund.
+func refundEvent(t *testing.T, reason string) []byte {
+	return marshal(t, domain.RefundCommandEvent{RefundCommandEventPayload: domain.RefundCommandEventPayload{RefundID: "refund-1", Reason: reason}})
+}
+
+func TestRefundSaga_RefundFlowsThroughSaga(t *testing.T) {
+	ctx := context.Background()
+	s, repo, m := newTestRefundSaga()
+
+	m.gateway.On("Refund", ctx, "refund-1", "payment-123", "card", domain.NewMoney(50_00, "EUR")).Return(nil).Once()
+	m.credit.On("Credit", ctx, "refund-1", "payment-123", "wallet-456", domain.NewMoney(54_00, "USD")).Return(nil).Once()
+	m.update.On("UpdateRefundStatus", ctx, "refund-1", "payment-123", domain.RefundStatusCompleted, "").Return(nil).Once()
+	m.notify.On("NotifyRefund", ctx, "refund-1", "payment-123", domain.RefundSuccess).Return(nil).Once()
+	m.metric.On("Record", ctx, "refund-1", domain.MetricRefundSuccess, sagaEndLabels("refund", "completed", ""), mock.Anything).Return(nil).Once()
+
+	require.NoError(t, s.Handle(ctx, domain.TopicRefundCreated, refundCreated(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicGatewayRefunded, refundEvent(t, "")))
+	// A redelivered refund.created does not refund twice.
+	require.NoError(t, s.Handle(ctx, domain.TopicRefundCreated, refundCreated(t)))
+	require.NoError(t, s.Handle(ctx, domain.TopicWalletFundsCredited, refundEvent(t, "")))
+	require.NoError(t, s.Handle(ctx, domain.TopicRefundCompleted, refundEvent(t, "")))
+
+	state, err := repo.GetByRefundID("refund-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.RefundSagaStepCompleted, state.Step)
+
+	m.gateway.AssertExpectations(t)
+	m.credit.AssertExpectations(t)
+	m.update.AssertExpectations(t)
+	m.notify.AssertExpectations(t)
+	m.metric.AssertExpectations(t)
+}
+
+func TestRefundSaga_ResumesSagaMovedByRenamedTopic(t *testing.T) {
+	ctx := context.Background()
+	s, repo, m := newTestRefundSaga()
+
+	// Started from refund.requested, the name of refund.created before the
+	// rename, which the bus redelivers under its current name.
+	state := domain.NewRefundSagaState(domain.RefundCommandEvent{
+		RefundCommandEventPayload: domain.RefundCommandEventPayload{
+			RefundID:  "refund-1",
+			PaymentID: "payment-123",
+			Amount:    domain.NewMoney(50_00, "EUR"),
+			Method:    "card",
+		},
+	})
+	state.LastEvent = domain.TopicRefundRequestedV1
+	require.NoError(t, repo.Save(state))
+	m.gateway.On("Refund", ctx, "refund-1", "payment-123", "card", domain.NewMoney(50_00, "EUR")).Return(nil).Once()
+
+	require.NoError(t, s.Handle(ctx, domain.TopicRefundCreated, refundCreated(t)))
+
+	m.gateway.AssertExpectations(t)
+}
+
+func TestRefundSaga_Failures(t *testing.T) {
+	tests := []struct {
+		name            string
+		events          []string
+		expectedStatus  domain.RefundStatus
+		expectedOutcome string
+	}{
+		{
+			name:            "gateway refund failed",
+			events:          []string{domain.TopicGatewayRefundFailed},
+			expectedStatus:  domain.RefundStatusFailed,
+			expectedOutcome: "failed",
+		},
+		{
+			// The gateway already gave the money back.
+			name:            "wallet credit failed",
+			events:          []string{domain.TopicGatewayRefunded, domain.TopicWalletCreditFailed},
+			expectedStatus:  domain.RefundStatusCreditFailed,
+			expectedOutcome: "credit_failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, repo, m := newTestRefundSaga()
+
+			m.gateway.On("Refund", ctx, "refund-1", "payment-123", "card", mock.Anything).Return(nil).Once()
+			m.credit.On("Credit", ctx, "refund-1", "payment-123", "wallet-456", mock.Anything).Return(nil).Maybe()
+			m.update.On("UpdateRefundStatus", ctx, "refund-1", "payment-123", tt.expectedStatus, "declined").Return(nil).Once()
+			m.notify.On("NotifyRefund", ctx, "refund-1", "payment-123", domain.RefundFailure).Return(nil).Once()
+			m.metric.On("Record", ctx, "refund-1", domain.MetricRefundFailure, sagaEndLabels("refund", tt.expectedOutcome, "declined"), mock.Anything).Return(nil).Once()
+
+			require.NoError(t, s.Handle(ctx, domain.TopicRefundCreated, refundCreated(t)))
+			for _, topic := range tt.events {
+				require.NoError(t, s.Handle(ctx, topic, refundEvent(t, "declined")))
+			}
+
+			state, err := repo.GetByRefundID("refund-1")
+			require.NoError(t, err)
+			assert.Equal(t, domain.RefundSagaStepFailed, state.Step)
+
+			m.update.AssertExpectations(t)
+			m.notify.AssertExpectations(t)
+			m.metric.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRefundSaga_UnknownRefund(t *testing.T) {
+	s, _, _ := newTestRefundSaga()
+
+	err := s.Handle(context.Background(), domain.TopicGatewayRefunded, marshal(t, domain.RefundCommandEvent{
+		RefundCommandEventPayload: domain.RefundCommandEventPayload{RefundID: "missing"},
+	}))
+
+	assert.ErrorIs(t, err, domain.ErrRefundSagaStateNotFound)
+}
@@ -0,0 +1,76 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/google/uuid"
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/publisher"
+)
+
+type UpdateRefundStatusCommand interface {
+	UpdateRefundStatus(ctx context.Context, refundId, paymentId string, status domain.RefundStatus, reason string) error
+}
+
+type updateRefundStatusCommand struct {
+	publisher publisher.Client
+}
+
+func NewUpdateRefundStatusCommand(
+	publisher publisher.Client,
+) *updateRefundStatusCommand {
+	return &updateRefundStatusCommand{
+		publisher,
+	}
+}
+
+func (c *updateRefundStatusCommand) UpdateRefundStatus(ctx context.Context, refundId, paymentId string, status domain.RefundStatus, reason string) error {
+	traceID := uuid.NewString()
+
+	b := c.buildEventV1(traceID, refundId, paymentId, status, reason)
+
+	return retry.Do(
+		func() error {
+			return c.publisher.Publish(ctx, domain.TopicOrchestratorRefund, b)
+		},
+		retry.Attempts(3),
+		retry.DelayType(retry.BackOffDelay),
+		retry.Delay(100*time.Millisecond),
+	)
+}
+
+func (c *updateRefundStatusCommand) buildEventV1(traceID, refundId, paymentId string, status domain.RefundStatus, reason string) []byte {
+	event := domain.RefundCommandEvent{
+		CommandEvent: domain.CommandEvent{
+			EventType:    domain.RefundUpdateStatusEventType,
+			EventVersion: "1",
+			Timestamp:    time.Now().UTC().Format(time.RFC3339),
+			CommandEventMetadata: domain.CommandEventMetadata{
+				TraceID:        traceID,
+				MessageGroupID: paymentId,
+				MessageDeduplicationId: domain.BuildDeduplicationId(
+					domain.RefundUpdateStatusEventType,
+					refundId,
+					string(status),
+				),
+			},
+		},
+		RefundCommandEventPayload: domain.RefundCommandEventPayload{
+			RefundID:  refundId,
+			PaymentID: paymentId,
+			Status:    status,
+			Reason:    reason,
+		},
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("ERROR: Failed to marshal updateRefundStatusCommand event: %v", err)
+	}
+
+	return b
+}
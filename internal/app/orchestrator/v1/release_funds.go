@@ -13,7 +13,7 @@ import (
 )
 
 type ReleaseFundsCommand interface {
-	Release(ctx context.Context, paymentId, walletId string, amount float64, currency string) error
+	Release(ctx context.Context, paymentId, walletId string, amount domain.Money) error
 }
 
 type releaseFundsCommand struct {
@@ -28,10 +28,10 @@ func NewReleaseFundsCommand(
 	}
 }
 
-func (c *releaseFundsCommand) Release(ctx context.Context, paymentId, walletId string, amount float64, currency string) error {
+func (c *releaseFundsCommand) Release(ctx context.Context, paymentId, walletId string, amount domain.Money) error {
 	traceID := uuid.NewString()
 
-	b := c.buildReleaseEventV1(traceID, paymentId, walletId, amount, currency)
+	b := c.buildReleaseEventV2(traceID, paymentId, walletId, amount)
 
 	return retry.Do(
 		func() error {
@@ -43,11 +43,11 @@ func (c *releaseFundsCommand) Release(ctx context.Context, paymentId, walletId s
 	)
 }
 
-func (c *releaseFundsCommand) buildReleaseEventV1(traceID, paymentId, walletId string, amount float64, currency string) []byte {
+func (c *releaseFundsCommand) buildReleaseEventV2(traceID, paymentId, walletId string, amount domain.Money) []byte {
 	event := domain.WalletCommandEvent{
 		CommandEvent: domain.CommandEvent{
 			EventType:    domain.ReleaseFundsEventType,
-			EventVersion: "1",
+			EventVersion: domain.WalletCommandEventVersion,
 			Timestamp:    time.Now().UTC().Format(time.RFC3339),
 			CommandEventMetadata: domain.CommandEventMetadata{
 				TraceID:        traceID,
@@ -62,7 +62,6 @@ func (c *releaseFundsCommand) buildReleaseEventV1(traceID, paymentId, walletId s
 			WalletID:  walletId,
 			PaymentID: paymentId,
 			Amount:    amount,
-			Currency:  currency,
 		},
 	}
 
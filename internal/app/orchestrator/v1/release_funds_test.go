@@ -38,7 +38,7 @@ func TestReleaseFundsCommand_Release(t *testing.T) {
 			tt.setupMocks(publisherMock)
 
 			cmd := NewReleaseFundsCommand(publisherMock)
-			err := cmd.Release(context.Background(), "payment-123", "wallet-456", 100.0, "USD")
+			err := cmd.Release(context.Background(), "payment-123", "wallet-456", domain.NewMoney(100_00, "USD"))
 
 			if tt.expectedError {
 				assert.Error(t, err)
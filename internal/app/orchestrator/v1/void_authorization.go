@@ -0,0 +1,74 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/google/uuid"
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/publisher"
+)
+
+type VoidAuthorizationCommand interface {
+	Void(ctx context.Context, paymentId, walletId, method string) error
+}
+
+type voidAuthorizationCommand struct {
+	publisher publisher.Client
+}
+
+func NewVoidAuthorizationCommand(
+	publisher publisher.Client,
+) *voidAuthorizationCommand {
+	return &voidAuthorizationCommand{
+		publisher,
+	}
+}
+
+func (c *voidAuthorizationCommand) Void(ctx context.Context, paymentId, walletId, method string) error {
+	traceID := uuid.NewString()
+
+	b := c.buildEventV2(traceID, paymentId, walletId, method)
+
+	return retry.Do(
+		func() error {
+			return c.publisher.Publish(ctx, domain.TopicOrchestratorGateway, b)
+		},
+		retry.Attempts(3),
+		retry.DelayType(retry.BackOffDelay),
+		retry.Delay(100*time.Millisecond),
+	)
+}
+
+func (c *voidAuthorizationCommand) buildEventV2(traceID, paymentId, walletId, method string) []byte {
+	event := domain.WalletCommandEvent{
+		CommandEvent: domain.CommandEvent{
+			EventType:    domain.VoidAuthorizationEventType,
+			EventVersion: domain.WalletCommandEventVersion,
+			Timestamp:    time.Now().UTC().Format(time.RFC3339),
+			CommandEventMetadata: domain.CommandEventMetadata{
+				TraceID:        traceID,
+				MessageGroupID: paymentId,
+				MessageDeduplicationId: domain.BuildDeduplicationId(
+					domain.VoidAuthorizationEventType,
+					paymentId,
+				),
+			},
+		},
+		WalletCommandEventPayload: domain.WalletCommandEventPayload{
+			WalletID:  walletId,
+			PaymentID: paymentId,
+			Method:    method,
+		},
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("ERROR: Failed to marshal voidAuthorizationCommand event: %v", err)
+	}
+
+	return b
+}
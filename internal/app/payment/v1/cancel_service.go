@@ -0,0 +1,101 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+type cancelPaymentUseCase struct {
+	repository domain.PaymentRepository
+	sagaStates domain.SagaStateRepository
+	outbox     domain.OutboxRepository
+}
+
+func NewCancelPaymentUseCase(
+	repository domain.PaymentRepository,
+	sagaStates domain.SagaStateRepository,
+	outbox domain.OutboxRepository,
+) *cancelPaymentUseCase {
+	return &cancelPaymentUseCase{
+		repository,
+		sagaStates,
+		outbox,
+	}
+}
+
+// Execute asks the orchestrator to cancel a pending payment through the
+// outbox, which keeps the request after payment.created. The payment is
+// rejected as SagaState.Cancelable tells once its saga asked for the capture,
+// and the orchestrator marks it CANCELED after compensating the steps it ran.
+// A saga reaching the capture before the orchestrator reads the request
+// rejects it with the same check and notifies the user instead.
+func (uc *cancelPaymentUseCase) Execute(ctx context.Context, paymentId string) (domain.Payment, domain.SagaStep, error) {
+	traceID := uuid.NewString()
+
+	pay, err := uc.repository.GetByID(paymentId)
+	if err != nil {
+		return domain.Payment{}, "", err
+	}
+
+	switch {
+	case pay.Status == domain.PaymentStatusCanceled:
+		return pay, domain.SagaStepCanceled, nil
+	case pay.Status.CanTransitionTo(domain.PaymentStatusCanceled):
+	case pay.Status.Debited():
+		return domain.Payment{}, "", domain.ErrPaymentAlreadyDebited
+	default:
+		return domain.Payment{}, "", domain.ErrPaymentNotCancelable
+	}
+
+	state, err := uc.sagaStates.GetByPaymentID(paymentId)
+	switch {
+	case errors.Is(err, domain.ErrSagaStateNotFound):
+		// The orchestrator did not pick the payment up yet.
+	case err != nil:
+		return domain.Payment{}, "", err
+	case state.CancelRequested:
+		return pay, state.Step, nil
+	default:
+		if err := state.Cancelable(); err != nil {
+			return domain.Payment{}, "", err
+		}
+	}
+
+	b, err := json.Marshal(uc.buildEventV1(traceID, pay.ID))
+	if err != nil {
+		return domain.Payment{}, "", err
+	}
+
+	if err := uc.outbox.Add(domain.NewOutboxMessage(domain.TopicPaymentCancelRequested, b)); err != nil {
+		return domain.Payment{}, "", err
+	}
+
+	return pay, state.Step, nil
+}
+
+func (uc *cancelPaymentUseCase) buildEventV1(traceID, paymentId string) domain.PaymentUpdateStatusEvent {
+	return domain.PaymentUpdateStatusEvent{
+		CommandEvent: domain.CommandEvent{
+			EventType:    domain.TopicPaymentCancelRequested,
+			EventVersion: "1",
+			Timestamp:    time.Now().UTC().Format(time.RFC3339),
+			CommandEventMetadata: domain.CommandEventMetadata{
+				TraceID:        traceID,
+				MessageGroupID: paymentId,
+				MessageDeduplicationId: domain.BuildDeduplicationId(
+					domain.TopicPaymentCancelRequested,
+					paymentId,
+				),
+			},
+		},
+		PaymentUpdateStatusEventPayload: domain.PaymentUpdateStatusEventPayload{
+			PaymentID: paymentId,
+			Status:    domain.PaymentStatusCanceled,
+		},
+	}
+}
@@ -0,0 +1,119 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCancelPaymentUseCase_Execute(t *testing.T) {
+	pending := domain.Payment{ID: "payment-123", Status: domain.PaymentStatusPending}
+
+	tests := []struct {
+		name          string
+		setupMocks    func(repo *mockPaymentRepository, sagaStates *mockSagaStateRepository, outbox *mockOutboxRepository)
+		expectedError error
+	}{
+		{
+			name: "payment not found",
+			setupMocks: func(repo *mockPaymentRepository, sagaStates *mockSagaStateRepository, outbox *mockOutboxRepository) {
+				repo.On("GetByID", "payment-123").Return(domain.Payment{}, domain.ErrPaymentNotFound)
+			},
+			expectedError: domain.ErrPaymentNotFound,
+		},
+		{
+			name: "saga not started yet",
+			setupMocks: func(repo *mockPaymentRepository, sagaStates *mockSagaStateRepository, outbox *mockOutboxRepository) {
+				repo.On("GetByID", "payment-123").Return(pending, nil)
+				sagaStates.On("GetByPaymentID", "payment-123").Return(domain.SagaState{}, domain.ErrSagaStateNotFound)
+				outbox.On("Add", mock.MatchedBy(func(msgs []domain.OutboxMessage) bool {
+					return len(msgs) == 1 && msgs[0].Topic == domain.TopicPaymentCancelRequested
+				})).Return(nil).Once()
+			},
+		},
+		{
+			name: "funds held",
+			setupMocks: func(repo *mockPaymentRepository, sagaStates *mockSagaStateRepository, outbox *mockOutboxRepository) {
+				repo.On("GetByID", "payment-123").Return(pending, nil)
+				sagaStates.On("GetByPaymentID", "payment-123").Return(domain.SagaState{SagaProgress: domain.SagaProgress{Step: domain.SagaStepAuthorizing}}, nil)
+				outbox.On("Add", mock.Anything).Return(nil).Once()
+			},
+		},
+		{
+			name: "already requested",
+			setupMocks: func(repo *mockPaymentRepository, sagaStates *mockSagaStateRepository, outbox *mockOutboxRepository) {
+				repo.On("GetByID", "payment-123").Return(pending, nil)
+				sagaStates.On("GetByPaymentID", "payment-123").Return(domain.SagaState{SagaProgress: domain.SagaProgress{Step: domain.SagaStepAuthorizing, CancelRequested: true}}, nil)
+			},
+		},
+		{
+			name: "already canceled",
+			setupMocks: func(repo *mockPaymentRepository, sagaStates *mockSagaStateRepository, outbox *mockOutboxRepository) {
+				repo.On("GetByID", "payment-123").Return(domain.Payment{ID: "payment-123", Status: domain.PaymentStatusCanceled}, nil)
+			},
+		},
+		{
+			name: "debiting",
+			setupMocks: func(repo *mockPaymentRepository, sagaStates *mockSagaStateRepository, outbox *mockOutboxRepository) {
+				repo.On("GetByID", "payment-123").Return(pending, nil)
+				sagaStates.On("GetByPaymentID", "payment-123").Return(domain.SagaState{SagaProgress: domain.SagaProgress{Step: domain.SagaStepDebiting}}, nil)
+			},
+			expectedError: domain.ErrPaymentAlreadyDebited,
+		},
+		{
+			name: "capturing",
+			setupMocks: func(repo *mockPaymentRepository, sagaStates *mockSagaStateRepository, outbox *mockOutboxRepository) {
+				repo.On("GetByID", "payment-123").Return(pending, nil)
+				sagaStates.On("GetByPaymentID", "payment-123").Return(domain.SagaState{SagaProgress: domain.SagaProgress{Step: domain.SagaStepCapturing}}, nil)
+			},
+			expectedError: domain.ErrPaymentNotCancelable,
+		},
+		{
+			name: "debited",
+			setupMocks: func(repo *mockPaymentRepository, sagaStates *mockSagaStateRepository, outbox *mockOutboxRepository) {
+				repo.On("GetByID", "payment-123").Return(domain.Payment{ID: "payment-123", Status: domain.PaymentStatusDebited}, nil)
+			},
+			expectedError: domain.ErrPaymentAlreadyDebited,
+		},
+		{
+			name: "completed",
+			setupMocks: func(repo *mockPaymentRepository, sagaStates *mockSagaStateRepository, outbox *mockOutboxRepository) {
+				repo.On("GetByID", "payment-123").Return(domain.Payment{ID: "payment-123", Status: domain.PaymentStatusCompleted}, nil)
+			},
+			expectedError: domain.ErrPaymentAlreadyDebited,
+		},
+		{
+			name: "releasing funds after a failure",
+			setupMocks: func(repo *mockPaymentRepository, sagaStates *mockSagaStateRepository, outbox *mockOutboxRepository) {
+				repo.On("GetByID", "payment-123").Return(pending, nil)
+				sagaStates.On("GetByPaymentID", "payment-123").Return(domain.SagaState{SagaProgress: domain.SagaProgress{Step: domain.SagaStepReleasingFunds}}, nil)
+			},
+			expectedError: domain.ErrPaymentNotCancelable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockPaymentRepository)
+			sagaStates := new(mockSagaStateRepository)
+			outbox := new(mockOutboxRepository)
+			tt.setupMocks(repo, sagaStates, outbox)
+
+			uc := NewCancelPaymentUseCase(repo, sagaStates, outbox)
+			_, _, err := uc.Execute(context.Background(), "payment-123")
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			repo.AssertExpectations(t)
+			sagaStates.AssertExpectations(t)
+			outbox.AssertExpectations(t)
+		})
+	}
+}
@@ -5,80 +5,91 @@ import (
 	"encoding/json"
 	"time"
 
-	"github.com/avast/retry-go"
 	"github.com/google/uuid"
 	"github.com/mmarias/golearn/internal/domain"
-	"github.com/mmarias/golearn/internal/infraestructure/publisher"
 )
 
 type createPaymentUseCase struct {
 	repository domain.PaymentRepository
-	publisher  publisher.Client
+	wallets    domain.WalletRepository
+	rates      domain.FXRateProvider
 }
 
 func NewCreatePaymentUseCase(
 	repository domain.PaymentRepository,
-	publisher publisher.Client,
+	wallets domain.WalletRepository,
+	rates domain.FXRateProvider,
 ) *createPaymentUseCase {
 	return &createPaymentUseCase{
 		repository,
-		publisher,
+		wallets,
+		rates,
 	}
 }
 
+// Execute stores the payment together with its payment.created event in the
+// outbox; the outbox relay publishes the event and starts the saga. A payment
+// in another currency than its wallet is converted first, and the conversion
+// is recorded on the payment.
 func (uc *createPaymentUseCase) Execute(ctx context.Context, pay domain.Payment) (string, error) {
 	traceID := uuid.NewString() // extracted from context implementation of otel for example
 
+	wallet, err := uc.wallets.GetByID(pay.WalletID)
+	if err != nil {
+		return "", err
+	}
+	if pay.Amount.Currency != wallet.Currency {
+		conversion, err := domain.Convert(uc.rates, pay.Amount, wallet.Currency)
+		if err != nil {
+			return "", err
+		}
+		pay.Conversion = &conversion
+	}
+
 	pay.SetID()
 	pay.SetCreatedAt()
-	pay.SetStatus(domain.PaymentStatusPending)
-
-	err := uc.repository.Create(pay)
-	if err != nil {
+	if err := pay.Transition(domain.PaymentStatusPending); err != nil {
 		return "", err
 	}
 
-	event := uc.buildEventV1(traceID, pay.ID, pay.Status)
+	event := uc.buildEventV1(traceID, pay)
 
 	b, err := json.Marshal(event)
 	if err != nil {
-		//publish metric errors
+		return "", err
 	}
 
-	err = retry.Do(
-		func() error {
-			return uc.publisher.Publish(ctx, domain.TopicPaymentCreated, b)
-		},
-		retry.Attempts(3),
-		retry.DelayType(retry.BackOffDelay),
-		retry.Delay(100*time.Millisecond),
-	)
-
+	err = uc.repository.Create(pay, domain.NewOutboxMessage(domain.TopicPaymentCreated, b))
 	if err != nil {
-		// publish metric error
+		return "", err
 	}
 
 	return pay.ID, nil
 }
 
-func (c *createPaymentUseCase) buildEventV1(traceID, paymentId string, status domain.PaymentStatus) domain.PaymentUpdateStatusEvent {
-	return domain.PaymentUpdateStatusEvent{
+func (c *createPaymentUseCase) buildEventV1(traceID string, pay domain.Payment) domain.PaymentCreatedEvent {
+	return domain.PaymentCreatedEvent{
 		CommandEvent: domain.CommandEvent{
 			EventType:    domain.TopicPaymentCreated,
 			EventVersion: "1",
 			Timestamp:    time.Now().UTC().Format(time.RFC3339),
 			CommandEventMetadata: domain.CommandEventMetadata{
 				TraceID:        traceID,
-				MessageGroupID: paymentId,
+				MessageGroupID: pay.ID,
 				MessageDeduplicationId: domain.BuildDeduplicationId(
 					domain.TopicPaymentCreated,
-					paymentId,
+					pay.ID,
 				),
 			},
 		},
-		PaymentUpdateStatusEventPayload: domain.PaymentUpdateStatusEventPayload{
-			PaymentID: paymentId,
-			Status:    status,
+		PaymentCreatedEventPayload: domain.PaymentCreatedEventPayload{
+			PaymentID:    pay.ID,
+			WalletID:     pay.WalletID,
+			Amount:       pay.Amount,
+			WalletAmount: pay.WalletAmount(),
+			Token:        pay.Token,
+			Method:       pay.Method,
+			Status:       pay.Status,
 		},
 	}
 }
@@ -2,22 +2,46 @@ package v1
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"testing"
 
 	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/database"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type mockPaymentRepository struct {
 	mock.Mock
 }
 
-func (m *mockPaymentRepository) Create(payment domain.Payment) error {
-	args := m.Called(payment)
+func (m *mockPaymentRepository) Create(payment domain.Payment, outbox ...domain.OutboxMessage) error {
+	args := m.Called(payment, outbox)
 	return args.Error(0)
 }
 
+func (m *mockPaymentRepository) GetByID(id string) (domain.Payment, error) {
+	args := m.Called(id)
+	return args.Get(0).(domain.Payment), args.Error(1)
+}
+
+func (m *mockPaymentRepository) UpdateStatus(id string, status domain.PaymentStatus, expectedVersion int) error {
+	args := m.Called(id, status, expectedVersion)
+	return args.Error(0)
+}
+
+func (m *mockPaymentRepository) ListByWallet(walletId string) ([]domain.Payment, error) {
+	args := m.Called(walletId)
+	return args.Get(0).([]domain.Payment), args.Error(1)
+}
+
+func (m *mockPaymentRepository) Search(filter domain.PaymentFilter) (domain.PaymentPage, error) {
+	args := m.Called(filter)
+	return args.Get(0).(domain.PaymentPage), args.Error(1)
+}
+
 type mockPublisher struct {
 	mock.Mock
 }
@@ -27,25 +51,146 @@ func (m *mockPublisher) Publish(ctx context.Context, topic string, msg []byte) e
 	return args.Error(0)
 }
 
+type mockRateProvider struct {
+	mock.Mock
+}
+
+func (m *mockRateProvider) Rate(from, to string) (domain.FXRate, error) {
+	args := m.Called(from, to)
+	return args.Get(0).(domain.FXRate), args.Error(1)
+}
+
+// newTestWallets holds the USD wallet user-123.
+func newTestWallets(t *testing.T) domain.WalletRepository {
+	wallets := database.NewInMemoryWalletRepository()
+	require.NoError(t, wallets.Create(domain.NewWallet("user-123", "USD")))
+	return wallets
+}
+
 func TestCreatePaymentUseCase_Execute(t *testing.T) {
 	mockRepo := new(mockPaymentRepository)
-	mockPub := new(mockPublisher)
+	mockRates := new(mockRateProvider)
 
-	uc := NewCreatePaymentUseCase(mockRepo, mockPub)
+	uc := NewCreatePaymentUseCase(mockRepo, newTestWallets(t), mockRates)
 
 	payment := domain.Payment{
-		Amount:   100,
+		Amount:   domain.NewMoney(100_00, "USD"),
 		WalletID: "user-123",
 	}
 
-	mockRepo.On("Create", mock.AnythingOfType("domain.Payment")).Return(nil)
-	mockPub.On("Publish", context.Background(), domain.TopicPaymentCreated, mock.AnythingOfType("[]uint8")).Return(nil)
+	var outbox []domain.OutboxMessage
+	mockRepo.On("Create", mock.AnythingOfType("domain.Payment"), mock.Anything).
+		Run(func(args mock.Arguments) { outbox = args.Get(1).([]domain.OutboxMessage) }).
+		Return(nil)
 
 	id, err := uc.Execute(context.Background(), payment)
 
 	assert.NoError(t, err)
 	assert.NotEmpty(t, id)
 
+	if assert.Len(t, outbox, 1) {
+		assert.Equal(t, domain.TopicPaymentCreated, outbox[0].Topic)
+
+		var event domain.PaymentCreatedEvent
+		assert.NoError(t, json.Unmarshal(outbox[0].Payload, &event))
+		assert.Equal(t, id, event.PaymentID)
+		assert.Equal(t, "user-123", event.WalletID)
+		assert.Equal(t, domain.NewMoney(100_00, "USD"), event.WalletAmount)
+	}
+
 	mockRepo.AssertExpectations(t)
-	mockPub.AssertExpectations(t)
+	mockRates.AssertNotCalled(t, "Rate", mock.Anything, mock.Anything)
+}
+
+func TestCreatePaymentUseCase_Execute_ConvertsToWalletCurrency(t *testing.T) {
+	mockRepo := new(mockPaymentRepository)
+	mockRates := new(mockRateProvider)
+
+	uc := NewCreatePaymentUseCase(mockRepo, newTestWallets(t), mockRates)
+
+	rate, err := domain.NewFXRate("EUR", "USD", "1.08", "test")
+	require.NoError(t, err)
+	mockRates.On("Rate", "EUR", "USD").Return(rate, nil).Once()
+
+	var stored domain.Payment
+	var outbox []domain.OutboxMessage
+	mockRepo.On("Create", mock.AnythingOfType("domain.Payment"), mock.Anything).
+		Run(func(args mock.Arguments) {
+			stored = args.Get(0).(domain.Payment)
+			outbox = args.Get(1).([]domain.OutboxMessage)
+		}).
+		Return(nil)
+
+	_, err = uc.Execute(context.Background(), domain.Payment{Amount: domain.NewMoney(50_00, "EUR"), WalletID: "user-123"})
+	require.NoError(t, err)
+
+	assert.Equal(t, domain.NewMoney(50_00, "EUR"), stored.Amount)
+	if assert.NotNil(t, stored.Conversion) {
+		assert.Equal(t, domain.NewMoney(54_00, "USD"), stored.Conversion.To)
+		assert.Equal(t, "1.08", stored.Conversion.Rate)
+		assert.Equal(t, "test", stored.Conversion.Provider)
+	}
+
+	if assert.Len(t, outbox, 1) {
+		var event domain.PaymentCreatedEvent
+		require.NoError(t, json.Unmarshal(outbox[0].Payload, &event))
+		assert.Equal(t, domain.NewMoney(50_00, "EUR"), event.Amount)
+		assert.Equal(t, domain.NewMoney(54_00, "USD"), event.WalletAmount)
+	}
+
+	mockRepo.AssertExpectations(t)
+	mockRates.AssertExpectations(t)
+}
+
+func TestCreatePaymentUseCase_Execute_Rejected(t *testing.T) {
+	tests := []struct {
+		name          string
+		payment       domain.Payment
+		setupMocks    func(rates *mockRateProvider)
+		expectedError error
+	}{
+		{
+			name:          "unknown wallet",
+			payment:       domain.Payment{Amount: domain.NewMoney(100_00, "USD"), WalletID: "user-456"},
+			setupMocks:    func(rates *mockRateProvider) {},
+			expectedError: domain.ErrWalletNotFound,
+		},
+		{
+			name:    "no rate for the currency pair",
+			payment: domain.Payment{Amount: domain.NewMoney(100_00, "GBP"), WalletID: "user-123"},
+			setupMocks: func(rates *mockRateProvider) {
+				rates.On("Rate", "GBP", "USD").Return(domain.FXRate{}, domain.ErrRateNotFound)
+			},
+			expectedError: domain.ErrRateNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(mockPaymentRepository)
+			mockRates := new(mockRateProvider)
+			tt.setupMocks(mockRates)
+
+			uc := NewCreatePaymentUseCase(mockRepo, newTestWallets(t), mockRates)
+
+			id, err := uc.Execute(context.Background(), tt.payment)
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			assert.Empty(t, id)
+			mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCreatePaymentUseCase_Execute_RepositoryError(t *testing.T) {
+	mockRepo := new(mockPaymentRepository)
+
+	uc := NewCreatePaymentUseCase(mockRepo, newTestWallets(t), new(mockRateProvider))
+
+	mockRepo.On("Create", mock.AnythingOfType("domain.Payment"), mock.Anything).Return(errors.New("db down"))
+
+	id, err := uc.Execute(context.Background(), domain.Payment{Amount: domain.NewMoney(100_00, "USD"), WalletID: "user-123"})
+
+	assert.Error(t, err)
+	assert.Empty(t, id)
 }
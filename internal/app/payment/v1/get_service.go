@@ -0,0 +1,42 @@
+package v1
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+type getPaymentUseCase struct {
+	repository domain.PaymentRepository
+	sagaStates domain.SagaStateRepository
+}
+
+func NewGetPaymentUseCase(
+	repository domain.PaymentRepository,
+	sagaStates domain.SagaStateRepository,
+) *getPaymentUseCase {
+	return &getPaymentUseCase{
+		repository,
+		sagaStates,
+	}
+}
+
+// Execute returns the payment together with the step its saga is in. The step is
+// empty while the orchestrator has not picked the payment up yet.
+func (uc *getPaymentUseCase) Execute(ctx context.Context, paymentId string) (domain.Payment, domain.SagaStep, error) {
+	pay, err := uc.repository.GetByID(paymentId)
+	if err != nil {
+		return domain.Payment{}, "", err
+	}
+
+	state, err := uc.sagaStates.GetByPaymentID(paymentId)
+	if errors.Is(err, domain.ErrSagaStateNotFound) {
+		return pay, "", nil
+	}
+	if err != nil {
+		return domain.Payment{}, "", err
+	}
+
+	return pay, state.Step, nil
+}
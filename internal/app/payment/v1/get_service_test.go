@@ -0,0 +1,84 @@
+package v1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockSagaStateRepository struct {
+	mock.Mock
+}
+
+func (m *mockSagaStateRepository) Save(state domain.SagaState) error {
+	args := m.Called(state)
+	return args.Error(0)
+}
+
+func (m *mockSagaStateRepository) GetByPaymentID(paymentId string) (domain.SagaState, error) {
+	args := m.Called(paymentId)
+	return args.Get(0).(domain.SagaState), args.Error(1)
+}
+
+func (m *mockSagaStateRepository) ListExpired(now time.Time) ([]domain.SagaState, error) {
+	args := m.Called(now)
+	return args.Get(0).([]domain.SagaState), args.Error(1)
+}
+
+func TestGetPaymentUseCase_Execute(t *testing.T) {
+	tests := []struct {
+		name          string
+		setupMocks    func(repo *mockPaymentRepository, sagaStates *mockSagaStateRepository)
+		expectedStep  domain.SagaStep
+		expectedError error
+	}{
+		{
+			name: "payment not found",
+			setupMocks: func(repo *mockPaymentRepository, sagaStates *mockSagaStateRepository) {
+				repo.On("GetByID", "payment-123").Return(domain.Payment{}, domain.ErrPaymentNotFound)
+			},
+			expectedError: domain.ErrPaymentNotFound,
+		},
+		{
+			name: "saga not started yet",
+			setupMocks: func(repo *mockPaymentRepository, sagaStates *mockSagaStateRepository) {
+				repo.On("GetByID", "payment-123").Return(domain.Payment{ID: "payment-123"}, nil)
+				sagaStates.On("GetByPaymentID", "payment-123").Return(domain.SagaState{}, domain.ErrSagaStateNotFound)
+			},
+		},
+		{
+			name: "saga in progress",
+			setupMocks: func(repo *mockPaymentRepository, sagaStates *mockSagaStateRepository) {
+				repo.On("GetByID", "payment-123").Return(domain.Payment{ID: "payment-123"}, nil)
+				sagaStates.On("GetByPaymentID", "payment-123").Return(domain.SagaState{SagaProgress: domain.SagaProgress{Step: domain.SagaStepDebiting}}, nil)
+			},
+			expectedStep: domain.SagaStepDebiting,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockPaymentRepository)
+			sagaStates := new(mockSagaStateRepository)
+			tt.setupMocks(repo, sagaStates)
+
+			uc := NewGetPaymentUseCase(repo, sagaStates)
+			pay, step, err := uc.Execute(context.Background(), "payment-123")
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "payment-123", pay.ID)
+				assert.Equal(t, tt.expectedStep, step)
+			}
+
+			repo.AssertExpectations(t)
+			sagaStates.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+type listPaymentsUseCase struct {
+	repository domain.PaymentRepository
+}
+
+func NewListPaymentsUseCase(
+	repository domain.PaymentRepository,
+) *listPaymentsUseCase {
+	return &listPaymentsUseCase{
+		repository,
+	}
+}
+
+func (uc *listPaymentsUseCase) Execute(ctx context.Context, filter domain.PaymentFilter) (domain.PaymentPage, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultListLimit
+	}
+	if filter.Limit > maxListLimit {
+		filter.Limit = maxListLimit
+	}
+
+	return uc.repository.Search(filter)
+}
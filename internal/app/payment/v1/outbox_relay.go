@@ -0,0 +1,79 @@
+package v1
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/publisher"
+)
+
+const outboxBatchSize = 100
+
+type outboxRelay struct {
+	repository domain.OutboxRepository
+	publisher  publisher.Client
+	interval   time.Duration
+}
+
+func NewOutboxRelay(
+	repository domain.OutboxRepository,
+	publisher publisher.Client,
+	interval time.Duration,
+) *outboxRelay {
+	return &outboxRelay{
+		repository,
+		publisher,
+		interval,
+	}
+}
+
+// Run drains the outbox every interval until ctx is done.
+func (r *outboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Drain(ctx); err != nil {
+			log.Printf("ERROR: [OutboxRelay] %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain publishes the pending messages in the order they were written. It stops
+// at the first failure, so a message is never published ahead of an older one;
+// the failed message is retried on the next pass.
+func (r *outboxRelay) Drain(ctx context.Context) error {
+	for {
+		messages, err := r.repository.ListPending(outboxBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range messages {
+			if err := r.publisher.Publish(ctx, msg.Topic, msg.Payload); err != nil {
+				if markErr := r.repository.MarkFailed(msg.ID, err); markErr != nil {
+					log.Printf("ERROR: [OutboxRelay] could not record failure of %s: %v", msg.ID, markErr)
+				}
+				return err
+			}
+
+			// A crash before this point publishes the message again; consumers
+			// deduplicate by the event deduplication id.
+			if err := r.repository.MarkPublished(msg.ID); err != nil {
+				return err
+			}
+		}
+
+		if len(messages) < outboxBatchSize {
+			return nil
+		}
+	}
+}
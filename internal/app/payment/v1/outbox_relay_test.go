@@ -0,0 +1,92 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockOutboxRepository struct {
+	mock.Mock
+}
+
+func (m *mockOutboxRepository) Add(messages ...domain.OutboxMessage) error {
+	args := m.Called(messages)
+	return args.Error(0)
+}
+
+func (m *mockOutboxRepository) ListPending(limit int) ([]domain.OutboxMessage, error) {
+	args := m.Called(limit)
+	return args.Get(0).([]domain.OutboxMessage), args.Error(1)
+}
+
+func (m *mockOutboxRepository) MarkPublished(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *mockOutboxRepository) MarkFailed(id string, cause error) error {
+	args := m.Called(id, cause)
+	return args.Error(0)
+}
+
+func TestOutboxRelay_Drain(t *testing.T) {
+	publishErr := errors.New("bus down")
+	messages := []domain.OutboxMessage{
+		{ID: "m-1", Topic: domain.TopicPaymentCreated, Payload: []byte("1")},
+		{ID: "m-2", Topic: domain.TopicPaymentCreated, Payload: []byte("2")},
+	}
+
+	tests := []struct {
+		name          string
+		setupMocks    func(repo *mockOutboxRepository, pub *mockPublisher)
+		expectedError error
+	}{
+		{
+			name: "publishes pending messages in order",
+			setupMocks: func(repo *mockOutboxRepository, pub *mockPublisher) {
+				repo.On("ListPending", outboxBatchSize).Return(messages, nil)
+				pub.On("Publish", mock.Anything, domain.TopicPaymentCreated, []byte("1")).Return(nil).Once()
+				repo.On("MarkPublished", "m-1").Return(nil).Once()
+				pub.On("Publish", mock.Anything, domain.TopicPaymentCreated, []byte("2")).Return(nil).Once()
+				repo.On("MarkPublished", "m-2").Return(nil).Once()
+			},
+		},
+		{
+			name: "stops at the first failed message",
+			setupMocks: func(repo *mockOutboxRepository, pub *mockPublisher) {
+				repo.On("ListPending", outboxBatchSize).Return(messages, nil)
+				pub.On("Publish", mock.Anything, domain.TopicPaymentCreated, []byte("1")).Return(publishErr).Once()
+				repo.On("MarkFailed", "m-1", publishErr).Return(nil).Once()
+			},
+			expectedError: publishErr,
+		},
+		{
+			name: "nothing pending",
+			setupMocks: func(repo *mockOutboxRepository, pub *mockPublisher) {
+				repo.On("ListPending", outboxBatchSize).Return([]domain.OutboxMessage{}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockOutboxRepository)
+			pub := new(mockPublisher)
+			tt.setupMocks(repo, pub)
+
+			err := NewOutboxRelay(repo, pub, 0).Drain(context.Background())
+
+			assert.ErrorIs(t, err, tt.expectedError)
+			if tt.expectedError == nil {
+				assert.NoError(t, err)
+			}
+			repo.AssertExpectations(t)
+			pub.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+type updatePaymentStatusUseCase struct {
+	repository domain.PaymentRepository
+}
+
+func NewUpdatePaymentStatusUseCase(
+	repository domain.PaymentRepository,
+) *updatePaymentStatusUseCase {
+	return &updatePaymentStatusUseCase{
+		repository,
+	}
+}
+
+// Execute moves the payment to the given status, which must be allowed from its
+// current one, otherwise it returns ErrIllegalPaymentTransition. Concurrent writers are detected
+// through the payment version, in which case the payment is read again and the
+// update retried.
+func (uc *updatePaymentStatusUseCase) Execute(ctx context.Context, paymentId string, status domain.PaymentStatus) (domain.Payment, error) {
+	var pay domain.Payment
+
+	err := retry.Do(
+		func() error {
+			var err error
+			pay, err = uc.repository.GetByID(paymentId)
+			if err != nil {
+				return err
+			}
+
+			if pay.Status == status {
+				// Redelivered update, nothing to do.
+				return nil
+			}
+
+			// Illegal transitions are not retried, the payment would not move.
+			next := pay
+			if err := next.Transition(status); err != nil {
+				return err
+			}
+
+			if err := uc.repository.UpdateStatus(paymentId, status, pay.Version); err != nil {
+				return err
+			}
+
+			pay = next
+			pay.Version++
+			return nil
+		},
+		retry.Context(ctx),
+		retry.Attempts(3),
+		retry.DelayType(retry.BackOffDelay),
+		retry.Delay(10*time.Millisecond),
+		retry.LastErrorOnly(true),
+		retry.RetryIf(func(err error) bool {
+			return errors.Is(err, domain.ErrPaymentVersionConflict)
+		}),
+	)
+
+	return pay, err
+}
@@ -0,0 +1,75 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdatePaymentStatusUseCase_Execute(t *testing.T) {
+	tests := []struct {
+		name          string
+		setupMocks    func(repo *mockPaymentRepository)
+		expectedError error
+	}{
+		{
+			name: "payment not found",
+			setupMocks: func(repo *mockPaymentRepository) {
+				repo.On("GetByID", "payment-123").Return(domain.Payment{}, domain.ErrPaymentNotFound).Once()
+			},
+			expectedError: domain.ErrPaymentNotFound,
+		},
+		{
+			name: "status already applied",
+			setupMocks: func(repo *mockPaymentRepository) {
+				repo.On("GetByID", "payment-123").Return(domain.Payment{ID: "payment-123", Status: domain.PaymentStatusCompleted, Version: 2}, nil).Once()
+			},
+		},
+		{
+			name: "retries on version conflict",
+			setupMocks: func(repo *mockPaymentRepository) {
+				repo.On("GetByID", "payment-123").Return(domain.Payment{ID: "payment-123", Status: domain.PaymentStatusDebited, Version: 1}, nil).Once()
+				repo.On("UpdateStatus", "payment-123", domain.PaymentStatusCompleted, 1).Return(domain.ErrPaymentVersionConflict).Once()
+				repo.On("GetByID", "payment-123").Return(domain.Payment{ID: "payment-123", Status: domain.PaymentStatusDebited, Version: 2}, nil).Once()
+				repo.On("UpdateStatus", "payment-123", domain.PaymentStatusCompleted, 2).Return(nil).Once()
+			},
+		},
+		{
+			name: "illegal transition is not retried",
+			setupMocks: func(repo *mockPaymentRepository) {
+				repo.On("GetByID", "payment-123").Return(domain.Payment{ID: "payment-123", Status: domain.PaymentStatusCanceled, Version: 2}, nil).Once()
+			},
+			expectedError: domain.ErrIllegalPaymentTransition,
+		},
+		{
+			name: "repository error is not retried",
+			setupMocks: func(repo *mockPaymentRepository) {
+				repo.On("GetByID", "payment-123").Return(domain.Payment{ID: "payment-123", Status: domain.PaymentStatusDebited, Version: 1}, nil).Once()
+				repo.On("UpdateStatus", "payment-123", domain.PaymentStatusCompleted, 1).Return(errors.New("disk full")).Once()
+			},
+			expectedError: errors.New("disk full"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockPaymentRepository)
+			tt.setupMocks(repo)
+
+			uc := NewUpdatePaymentStatusUseCase(repo)
+			pay, err := uc.Execute(context.Background(), "payment-123", domain.PaymentStatusCompleted)
+
+			if tt.expectedError != nil {
+				assert.ErrorContains(t, err, tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, domain.PaymentStatusCompleted, pay.Status)
+			}
+
+			repo.AssertExpectations(t)
+		})
+	}
+}
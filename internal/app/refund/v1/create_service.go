@@ -0,0 +1,91 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+type createRefundUseCase struct {
+	payments domain.RefundablePaymentRepository
+	refunds  domain.RefundRepository
+}
+
+func NewCreateRefundUseCase(
+	payments domain.RefundablePaymentRepository,
+	refunds domain.RefundRepository,
+) *createRefundUseCase {
+	return &createRefundUseCase{
+		payments,
+		refunds,
+	}
+}
+
+// Execute refunds amount of the payment, in the payment currency, or whatever
+// is left to refund when amount is empty. The refund is stored together with
+// its refund.created event, which starts the refund saga.
+func (uc *createRefundUseCase) Execute(ctx context.Context, paymentId, amount string) (domain.Refund, error) {
+	traceID := uuid.NewString()
+
+	pay, err := uc.payments.GetByID(paymentId)
+	if err != nil {
+		return domain.Refund{}, err
+	}
+	refunds, err := uc.refunds.ListByPayment(paymentId)
+	if err != nil {
+		return domain.Refund{}, err
+	}
+
+	var money domain.Money
+	if amount == "" {
+		money, _ = domain.Refundable(pay, refunds)
+		if money.IsZero() {
+			return domain.Refund{}, fmt.Errorf("%w: nothing left", domain.ErrRefundExceedsPayment)
+		}
+	} else if money, err = domain.ParseMoney(amount, pay.Amount.Currency); err != nil {
+		return domain.Refund{}, err
+	}
+
+	refund, err := domain.NewRefund(pay, refunds, money)
+	if err != nil {
+		return domain.Refund{}, err
+	}
+
+	b, err := json.Marshal(uc.buildEventV1(traceID, pay.Method, refund))
+	if err != nil {
+		return domain.Refund{}, err
+	}
+
+	if err := uc.refunds.Create(refund, domain.NewOutboxMessage(domain.TopicRefundCreated, b)); err != nil {
+		return domain.Refund{}, err
+	}
+
+	return refund, nil
+}
+
+func (uc *createRefundUseCase) buildEventV1(traceID, method string, refund domain.Refund) domain.RefundCommandEvent {
+	payload := domain.NewRefundCommandEventPayload(refund)
+	// The refund goes to the provider of the payment.
+	payload.Method = method
+
+	return domain.RefundCommandEvent{
+		CommandEvent: domain.CommandEvent{
+			EventType:    domain.TopicRefundCreated,
+			EventVersion: "1",
+			Timestamp:    time.Now().UTC().Format(time.RFC3339),
+			CommandEventMetadata: domain.CommandEventMetadata{
+				TraceID:        traceID,
+				MessageGroupID: refund.PaymentID,
+				MessageDeduplicationId: domain.BuildDeduplicationId(
+					domain.TopicRefundCreated,
+					refund.ID,
+				),
+			},
+		},
+		RefundCommandEventPayload: payload,
+	}
+}
@@ -0,0 +1,84 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestRefundStores holds payment-123, a completed payment of 100.00 EUR
+// charged as 108.00 USD to its wallet.
+func newTestRefundStores(t *testing.T) (domain.RefundablePaymentRepository, domain.RefundRepository, domain.OutboxRepository) {
+	db := database.OpenInMemory()
+	payments := database.NewRefundablePaymentRepository(db)
+	require.NoError(t, payments.Add(domain.RefundablePayment{
+		ID:           "payment-123",
+		WalletID:     "user-123",
+		Method:       "card",
+		Amount:       domain.NewMoney(100_00, "EUR"),
+		WalletAmount: domain.NewMoney(108_00, "USD"),
+		Status:       domain.PaymentStatusCompleted,
+	}))
+	return payments, database.NewRefundRepository(db), database.NewOutboxRepository(db)
+}
+
+func TestCreateRefundUseCase_Execute(t *testing.T) {
+	payments, refunds, outbox := newTestRefundStores(t)
+	uc := NewCreateRefundUseCase(payments, refunds)
+
+	refund, err := uc.Execute(context.Background(), "payment-123", "25.00")
+	require.NoError(t, err)
+	assert.Equal(t, domain.NewMoney(25_00, "EUR"), refund.Amount)
+	assert.Equal(t, domain.NewMoney(27_00, "USD"), refund.WalletAmount)
+	assert.Equal(t, domain.RefundStatusPending, refund.Status)
+
+	pending, err := outbox.ListPending(10)
+	require.NoError(t, err)
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, domain.TopicRefundCreated, pending[0].Topic)
+
+		var event domain.RefundCommandEvent
+		require.NoError(t, json.Unmarshal(pending[0].Payload, &event))
+		assert.Equal(t, refund.ID, event.RefundID)
+		assert.Equal(t, "user-123", event.WalletID)
+		assert.Equal(t, domain.NewMoney(27_00, "USD"), event.WalletAmount)
+	}
+
+	// Without an amount, the rest of the payment is refunded.
+	rest, err := uc.Execute(context.Background(), "payment-123", "")
+	require.NoError(t, err)
+	assert.Equal(t, domain.NewMoney(75_00, "EUR"), rest.Amount)
+	assert.Equal(t, domain.NewMoney(81_00, "USD"), rest.WalletAmount)
+
+	_, err = uc.Execute(context.Background(), "payment-123", "")
+	assert.ErrorIs(t, err, domain.ErrRefundExceedsPayment)
+}
+
+func TestCreateRefundUseCase_Execute_Rejects(t *testing.T) {
+	tests := []struct {
+		name          string
+		paymentId     string
+		amount        string
+		expectedError error
+	}{
+		{name: "unknown payment", paymentId: "missing", amount: "1.00", expectedError: domain.ErrPaymentNotFound},
+		{name: "more than the payment", paymentId: "payment-123", amount: "100.01", expectedError: domain.ErrRefundExceedsPayment},
+		{name: "too many decimals", paymentId: "payment-123", amount: "1.001", expectedError: domain.ErrTooManyDecimals},
+		{name: "negative amount", paymentId: "payment-123", amount: "-1", expectedError: domain.ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payments, refunds, _ := newTestRefundStores(t)
+			uc := NewCreateRefundUseCase(payments, refunds)
+
+			_, err := uc.Execute(context.Background(), tt.paymentId, tt.amount)
+			assert.ErrorIs(t, err, tt.expectedError)
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+type getRefundUseCase struct {
+	refunds domain.RefundRepository
+}
+
+func NewGetRefundUseCase(
+	refunds domain.RefundRepository,
+) *getRefundUseCase {
+	return &getRefundUseCase{
+		refunds,
+	}
+}
+
+func (uc *getRefundUseCase) Execute(ctx context.Context, refundId string) (domain.Refund, error) {
+	return uc.refunds.GetByID(refundId)
+}
@@ -0,0 +1,30 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+type trackPaymentUseCase struct {
+	payments domain.RefundablePaymentRepository
+}
+
+func NewTrackPaymentUseCase(
+	payments domain.RefundablePaymentRepository,
+) *trackPaymentUseCase {
+	return &trackPaymentUseCase{
+		payments,
+	}
+}
+
+// Created keeps what the refunds need of a created payment.
+func (uc *trackPaymentUseCase) Created(ctx context.Context, event domain.PaymentCreatedEvent) error {
+	return uc.payments.Add(domain.NewRefundablePayment(event))
+}
+
+// Completed makes the payment refundable. It returns ErrPaymentNotFound while
+// its payment.created is not handled yet, so the bus delivers it again.
+func (uc *trackPaymentUseCase) Completed(ctx context.Context, paymentId string) error {
+	return uc.payments.UpdateStatus(paymentId, domain.PaymentStatusCompleted)
+}
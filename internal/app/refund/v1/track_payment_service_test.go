@@ -0,0 +1,38 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackPaymentUseCase(t *testing.T) {
+	ctx := context.Background()
+	db := database.OpenInMemory()
+	payments := database.NewRefundablePaymentRepository(db)
+	uc := NewTrackPaymentUseCase(payments)
+	create := NewCreateRefundUseCase(payments, database.NewRefundRepository(db))
+
+	// payment.completed may arrive before payment.created was handled.
+	assert.ErrorIs(t, uc.Completed(ctx, "payment-123"), domain.ErrPaymentNotFound)
+
+	require.NoError(t, uc.Created(ctx, domain.PaymentCreatedEvent{
+		PaymentCreatedEventPayload: domain.PaymentCreatedEventPayload{
+			PaymentID: "payment-123",
+			WalletID:  "wallet-456",
+			Amount:    domain.NewMoney(10_00, "USD"),
+			Method:    "card",
+		},
+	}))
+	_, err := create.Execute(ctx, "payment-123", "")
+	assert.ErrorIs(t, err, domain.ErrPaymentNotRefundable)
+
+	require.NoError(t, uc.Completed(ctx, "payment-123"))
+	refund, err := create.Execute(ctx, "payment-123", "")
+	require.NoError(t, err)
+	assert.Equal(t, domain.NewMoney(10_00, "USD"), refund.WalletAmount)
+}
@@ -0,0 +1,51 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+type updateRefundStatusUseCase struct {
+	payments domain.RefundablePaymentRepository
+	refunds  domain.RefundRepository
+}
+
+func NewUpdateRefundStatusUseCase(
+	payments domain.RefundablePaymentRepository,
+	refunds domain.RefundRepository,
+) *updateRefundStatusUseCase {
+	return &updateRefundStatusUseCase{
+		payments,
+		refunds,
+	}
+}
+
+// Execute moves the refund to the given status and returns the status its
+// payment reaches: REFUNDED or PARTIALLY_REFUNDED once the refund completed,
+// depending on what was given back so far. The payment service applies it;
+// both are safe to repeat on a redelivered update.
+func (uc *updateRefundStatusUseCase) Execute(ctx context.Context, refundId string, status domain.RefundStatus, reason string) (domain.Refund, domain.PaymentStatus, error) {
+	refund, err := uc.refunds.GetByID(refundId)
+	if err != nil {
+		return domain.Refund{}, "", err
+	}
+
+	if refund.Status != status {
+		if err := uc.refunds.UpdateStatus(refundId, status, reason); err != nil {
+			return domain.Refund{}, "", err
+		}
+		refund.SetStatus(status, reason)
+	}
+
+	pay, err := uc.payments.GetByID(refund.PaymentID)
+	if err != nil {
+		return domain.Refund{}, "", err
+	}
+	refunds, err := uc.refunds.ListByPayment(refund.PaymentID)
+	if err != nil {
+		return domain.Refund{}, "", err
+	}
+
+	return refund, domain.RefundedStatus(pay, refunds), nil
+}
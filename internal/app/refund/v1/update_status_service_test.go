@@ -0,0 +1,62 @@
+package v1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateRefundStatusUseCase_Execute(t *testing.T) {
+	ctx := context.Background()
+	payments, refunds, _ := newTestRefundStores(t)
+	create := NewCreateRefundUseCase(payments, refunds)
+	uc := NewUpdateRefundStatusUseCase(payments, refunds)
+
+	partial, err := create.Execute(ctx, "payment-123", "40.00")
+	require.NoError(t, err)
+	rest, err := create.Execute(ctx, "payment-123", "")
+	require.NoError(t, err)
+
+	refund, status, err := uc.Execute(ctx, partial.ID, domain.RefundStatusCompleted, "")
+	require.NoError(t, err)
+	assert.Equal(t, domain.RefundStatusCompleted, refund.Status)
+	assert.Equal(t, domain.PaymentStatusPartiallyRefunded, status)
+
+	// A redelivered update changes nothing.
+	_, status, err = uc.Execute(ctx, partial.ID, domain.RefundStatusCompleted, "")
+	require.NoError(t, err)
+	assert.Equal(t, domain.PaymentStatusPartiallyRefunded, status)
+
+	_, status, err = uc.Execute(ctx, rest.ID, domain.RefundStatusCompleted, "")
+	require.NoError(t, err)
+	assert.Equal(t, domain.PaymentStatusRefunded, status)
+}
+
+func TestUpdateRefundStatusUseCase_Execute_FailedRefundFreesTheAmount(t *testing.T) {
+	ctx := context.Background()
+	payments, refunds, _ := newTestRefundStores(t)
+	create := NewCreateRefundUseCase(payments, refunds)
+	uc := NewUpdateRefundStatusUseCase(payments, refunds)
+
+	failed, err := create.Execute(ctx, "payment-123", "")
+	require.NoError(t, err)
+
+	refund, status, err := uc.Execute(ctx, failed.ID, domain.RefundStatusFailed, "declined")
+	require.NoError(t, err)
+	assert.Equal(t, "declined", refund.Reason)
+	assert.Equal(t, domain.PaymentStatusCompleted, status)
+
+	_, err = create.Execute(ctx, "payment-123", "")
+	assert.NoError(t, err)
+}
+
+func TestUpdateRefundStatusUseCase_Execute_UnknownRefund(t *testing.T) {
+	payments, refunds, _ := newTestRefundStores(t)
+	uc := NewUpdateRefundStatusUseCase(payments, refunds)
+
+	_, _, err := uc.Execute(context.Background(), "missing", domain.RefundStatusCompleted, "")
+	assert.ErrorIs(t, err, domain.ErrRefundNotFound)
+}
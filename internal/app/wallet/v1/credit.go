@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+// Credit adds funds to the wallet. The reference identifies the credit (e.g. a
+// refund id), so crediting the same reference twice only applies it once.
+func (s *walletService) Credit(ctx context.Context, walletId string, amount domain.Money, reference string) (domain.Wallet, error) {
+	transactionId := "credit." + reference
+
+	err := s.save(ctx, func() (*domain.WalletChange, error) {
+		wallet, err := s.repository.GetByID(walletId)
+		if err != nil {
+			return nil, err
+		}
+
+		credited, err := s.repository.HasLedgerTransaction(transactionId)
+		if err != nil || credited {
+			return nil, err
+		}
+
+		if err := validateAmount(wallet, amount); err != nil {
+			return nil, err
+		}
+		if err := wallet.Credit(amount); err != nil {
+			return nil, err
+		}
+
+		return &domain.WalletChange{
+			Wallets: []domain.Wallet{wallet},
+			Entries: domain.NewLedgerTransfer(
+				transactionId,
+				domain.LedgerAccountFunding,
+				domain.LedgerAccountAvailable(walletId),
+				amount,
+			),
+		}, nil
+	})
+
+	if err != nil {
+		return domain.Wallet{}, err
+	}
+
+	return s.repository.GetByID(walletId)
+}
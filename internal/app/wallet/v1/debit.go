@@ -0,0 +1,51 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+// Debit settles the held funds, taking them out of the wallet. Debiting a
+// debited hold is a no-op.
+func (s *walletService) Debit(ctx context.Context, holdId string) (domain.Hold, error) {
+	var hold domain.Hold
+
+	err := s.save(ctx, func() (*domain.WalletChange, error) {
+		var err error
+		hold, err = s.repository.GetHold(holdId)
+		if err != nil {
+			return nil, err
+		}
+
+		switch hold.Status {
+		case domain.HoldStatusDebited:
+			return nil, nil
+		case domain.HoldStatusReleased:
+			return nil, domain.ErrHoldNotActive
+		}
+
+		wallet, err := s.repository.GetByID(hold.WalletID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := wallet.DebitHeld(hold.Amount); err != nil {
+			return nil, err
+		}
+		hold.SetStatus(domain.HoldStatusDebited)
+
+		return &domain.WalletChange{
+			Wallets: []domain.Wallet{wallet},
+			Hold:    &hold,
+			Entries: domain.NewLedgerTransfer(
+				"debit."+holdId,
+				domain.LedgerAccountHeld(wallet.ID),
+				domain.LedgerAccountSettlement,
+				hold.Amount,
+			),
+		}, nil
+	})
+
+	return hold, err
+}
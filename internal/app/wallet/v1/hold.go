@@ -0,0 +1,68 @@
+package v1
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+// Hold reserves amount of the wallet under holdId. Holding again with the same
+// id and amount returns the existing hold, so redelivered commands are safe.
+func (s *walletService) Hold(ctx context.Context, holdId, walletId string, amount domain.Money) (domain.Hold, error) {
+	return s.hold(ctx, holdId, walletId, amount, "")
+}
+
+// HoldFunds reserves a decimal amount of the wallet, in its own currency, under a new hold.
+func (s *walletService) HoldFunds(ctx context.Context, walletId string, amount string, reason string) (domain.Hold, error) {
+	money, err := s.parseAmount(walletId, amount)
+	if err != nil {
+		return domain.Hold{}, err
+	}
+
+	return s.hold(ctx, uuid.NewString(), walletId, money, reason)
+}
+
+func (s *walletService) hold(ctx context.Context, holdId, walletId string, amount domain.Money, reason string) (domain.Hold, error) {
+	var hold domain.Hold
+
+	err := s.save(ctx, func() (*domain.WalletChange, error) {
+		existing, err := s.repository.GetHold(holdId)
+		switch {
+		case err == nil:
+			if existing.WalletID != walletId || existing.Amount != amount {
+				return nil, domain.ErrHoldAlreadyExists
+			}
+			hold = existing
+			return nil, nil
+		case !errors.Is(err, domain.ErrHoldNotFound):
+			return nil, err
+		}
+
+		wallet, err := s.repository.GetByID(walletId)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateAmount(wallet, amount); err != nil {
+			return nil, err
+		}
+		if err := wallet.Hold(amount); err != nil {
+			return nil, err
+		}
+
+		hold = domain.NewHold(holdId, walletId, amount, reason)
+		return &domain.WalletChange{
+			Wallets: []domain.Wallet{wallet},
+			Hold:    &hold,
+			Entries: domain.NewLedgerTransfer(
+				"hold."+holdId,
+				domain.LedgerAccountAvailable(walletId),
+				domain.LedgerAccountHeld(walletId),
+				amount,
+			),
+		}, nil
+	})
+
+	return hold, err
+}
@@ -0,0 +1,50 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+// Release gives the held funds back to the wallet. Releasing a released hold is a no-op.
+func (s *walletService) Release(ctx context.Context, holdId string) (domain.Hold, error) {
+	var hold domain.Hold
+
+	err := s.save(ctx, func() (*domain.WalletChange, error) {
+		var err error
+		hold, err = s.repository.GetHold(holdId)
+		if err != nil {
+			return nil, err
+		}
+
+		switch hold.Status {
+		case domain.HoldStatusReleased:
+			return nil, nil
+		case domain.HoldStatusDebited:
+			return nil, domain.ErrHoldNotActive
+		}
+
+		wallet, err := s.repository.GetByID(hold.WalletID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := wallet.ReleaseHeld(hold.Amount); err != nil {
+			return nil, err
+		}
+		hold.SetStatus(domain.HoldStatusReleased)
+
+		return &domain.WalletChange{
+			Wallets: []domain.Wallet{wallet},
+			Hold:    &hold,
+			Entries: domain.NewLedgerTransfer(
+				"release."+holdId,
+				domain.LedgerAccountHeld(wallet.ID),
+				domain.LedgerAccountAvailable(wallet.ID),
+				hold.Amount,
+			),
+		}, nil
+	})
+
+	return hold, err
+}
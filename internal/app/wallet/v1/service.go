@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/avast/retry-go"
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+type walletService struct {
+	repository domain.WalletRepository
+}
+
+func NewWalletService(
+	repository domain.WalletRepository,
+) *walletService {
+	return &walletService{
+		repository,
+	}
+}
+
+// Open creates an empty wallet in an ISO-4217 currency, or returns the existing one.
+func (s *walletService) Open(ctx context.Context, walletId, currency string) (domain.Wallet, error) {
+	if _, err := domain.LookupCurrency(currency); err != nil {
+		return domain.Wallet{}, err
+	}
+
+	err := s.repository.Create(domain.NewWallet(walletId, currency))
+	if err != nil && !errors.Is(err, domain.ErrWalletAlreadyExists) {
+		return domain.Wallet{}, err
+	}
+
+	return s.repository.GetByID(walletId)
+}
+
+// save runs an operation until its change is stored. The operation reads what
+// it needs on every attempt, so a concurrent writer (a version conflict or a
+// transaction recorded in the meantime) makes it decide again on fresh data.
+func (s *walletService) save(ctx context.Context, operation func() (*domain.WalletChange, error)) error {
+	return retry.Do(
+		func() error {
+			change, err := operation()
+			if err != nil || change == nil {
+				return err
+			}
+			return s.repository.Save(*change)
+		},
+		retry.Context(ctx),
+		retry.Attempts(10),
+		// Jitter spreads writers racing on a hot wallet.
+		retry.DelayType(retry.CombineDelay(retry.BackOffDelay, retry.RandomDelay)),
+		retry.Delay(5*time.Millisecond),
+		retry.MaxJitter(10*time.Millisecond),
+		retry.MaxDelay(200*time.Millisecond),
+		retry.LastErrorOnly(true),
+		retry.RetryIf(func(err error) bool {
+			return errors.Is(err, domain.ErrWalletVersionConflict) || errors.Is(err, domain.ErrLedgerTransactionExists)
+		}),
+	)
+}
+
+func validateAmount(wallet domain.Wallet, amount domain.Money) error {
+	if !amount.IsPositive() {
+		return domain.ErrInvalidAmount
+	}
+	if amount.Currency != wallet.Currency {
+		return domain.ErrCurrencyMismatch
+	}
+	return nil
+}
+
+// parseAmount reads a decimal amount in the currency of the wallet.
+func (s *walletService) parseAmount(walletId, amount string) (domain.Money, error) {
+	wallet, err := s.repository.GetByID(walletId)
+	if err != nil {
+		return domain.Money{}, err
+	}
+
+	return domain.ParseMoney(amount, wallet.Currency)
+}
@@ -0,0 +1,242 @@
+package v1
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// usd builds an amount in cents.
+func usd(cents int64) domain.Money {
+	return domain.NewMoney(cents, "USD")
+}
+
+func newTestWalletService(t *testing.T, balance int64) (*walletService, domain.WalletRepository) {
+	repo := database.NewInMemoryWalletRepository()
+	svc := NewWalletService(repo)
+
+	_, err := svc.Open(context.Background(), "wal-1", "USD")
+	require.NoError(t, err)
+	if balance > 0 {
+		_, err = svc.Credit(context.Background(), "wal-1", usd(balance), "seed")
+		require.NoError(t, err)
+	}
+
+	return svc, repo
+}
+
+// assertBalanced checks the wallet balances match its ledger accounts.
+func assertBalanced(t *testing.T, repo domain.WalletRepository, walletId string) {
+	wallet, err := repo.GetByID(walletId)
+	require.NoError(t, err)
+	entries, err := repo.ListLedgerEntries(walletId)
+	require.NoError(t, err)
+
+	balances := map[string]int64{}
+	for _, e := range entries {
+		if e.Direction == domain.LedgerCredit {
+			balances[e.Account] += e.Amount.Minor
+		} else {
+			balances[e.Account] -= e.Amount.Minor
+		}
+	}
+
+	assert.Equal(t, wallet.Available.Minor, balances[domain.LedgerAccountAvailable(walletId)])
+	assert.Equal(t, wallet.Held.Minor, balances[domain.LedgerAccountHeld(walletId)])
+}
+
+func TestWalletService_Hold(t *testing.T) {
+	tests := []struct {
+		name          string
+		amount        domain.Money
+		walletId      string
+		expectedError error
+		available     int64
+		held          int64
+	}{
+		{name: "holds funds", amount: usd(400_00), walletId: "wal-1", available: 600_00, held: 400_00},
+		{name: "holds the whole balance", amount: usd(1000_00), walletId: "wal-1", available: 0, held: 1000_00},
+		{name: "insufficient funds", amount: usd(1000_01), walletId: "wal-1", expectedError: domain.ErrInsufficientFunds, available: 1000_00},
+		{name: "currency mismatch", amount: domain.NewMoney(10_00, "EUR"), walletId: "wal-1", expectedError: domain.ErrCurrencyMismatch, available: 1000_00},
+		{name: "invalid amount", amount: usd(0), walletId: "wal-1", expectedError: domain.ErrInvalidAmount, available: 1000_00},
+		{name: "unknown wallet", amount: usd(10_00), walletId: "wal-2", expectedError: domain.ErrWalletNotFound, available: 1000_00},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo := newTestWalletService(t, 1000_00)
+
+			hold, err := svc.Hold(context.Background(), "pay-1", tt.walletId, tt.amount)
+
+			wallet, getErr := repo.GetByID("wal-1")
+			require.NoError(t, getErr)
+			assert.Equal(t, usd(tt.available), wallet.Available)
+			assert.Equal(t, usd(tt.held), wallet.Held)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, domain.HoldStatusActive, hold.Status)
+			assertBalanced(t, repo, "wal-1")
+		})
+	}
+}
+
+func TestWalletService_HoldIsIdempotent(t *testing.T) {
+	svc, repo := newTestWalletService(t, 1000_00)
+
+	_, err := svc.Hold(context.Background(), "pay-1", "wal-1", usd(400_00))
+	require.NoError(t, err)
+	_, err = svc.Hold(context.Background(), "pay-1", "wal-1", usd(400_00))
+	require.NoError(t, err)
+
+	_, err = svc.Hold(context.Background(), "pay-1", "wal-1", usd(300_00))
+	assert.ErrorIs(t, err, domain.ErrHoldAlreadyExists)
+
+	wallet, err := repo.GetByID("wal-1")
+	require.NoError(t, err)
+	assert.Equal(t, usd(600_00), wallet.Available)
+	assert.Equal(t, usd(400_00), wallet.Held)
+}
+
+func TestWalletService_ConcurrentHoldsNeverOverdraw(t *testing.T) {
+	svc, repo := newTestWalletService(t, 1000_00)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _ = svc.Hold(context.Background(), "pay-"+string(rune('a'+i)), "wal-1", usd(300_00))
+		}(i)
+	}
+	wg.Wait()
+
+	wallet, err := repo.GetByID("wal-1")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, wallet.Available.Minor, int64(0))
+	assert.Equal(t, int64(1000_00), wallet.Available.Minor+wallet.Held.Minor)
+	assertBalanced(t, repo, "wal-1")
+}
+
+func TestWalletService_ReleaseAndDebit(t *testing.T) {
+	tests := []struct {
+		name          string
+		run           func(svc *walletService) error
+		expectedError error
+		available     int64
+		held          int64
+	}{
+		{
+			name: "release returns the funds",
+			run: func(svc *walletService) error {
+				_, err := svc.Release(context.Background(), "pay-1")
+				return err
+			},
+			available: 1000_00,
+		},
+		{
+			name: "debit takes the held funds",
+			run: func(svc *walletService) error {
+				_, err := svc.Debit(context.Background(), "pay-1")
+				return err
+			},
+			available: 600_00,
+		},
+		{
+			name: "release twice is a no-op",
+			run: func(svc *walletService) error {
+				if _, err := svc.Release(context.Background(), "pay-1"); err != nil {
+					return err
+				}
+				_, err := svc.Release(context.Background(), "pay-1")
+				return err
+			},
+			available: 1000_00,
+		},
+		{
+			name: "debit twice is a no-op",
+			run: func(svc *walletService) error {
+				if _, err := svc.Debit(context.Background(), "pay-1"); err != nil {
+					return err
+				}
+				_, err := svc.Debit(context.Background(), "pay-1")
+				return err
+			},
+			available: 600_00,
+		},
+		{
+			name: "release after debit fails",
+			run: func(svc *walletService) error {
+				if _, err := svc.Debit(context.Background(), "pay-1"); err != nil {
+					return err
+				}
+				_, err := svc.Release(context.Background(), "pay-1")
+				return err
+			},
+			expectedError: domain.ErrHoldNotActive,
+			available:     600_00,
+		},
+		{
+			name: "unknown hold",
+			run: func(svc *walletService) error {
+				_, err := svc.Debit(context.Background(), "pay-2")
+				return err
+			},
+			expectedError: domain.ErrHoldNotFound,
+			available:     600_00,
+			held:          400_00,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo := newTestWalletService(t, 1000_00)
+			_, err := svc.Hold(context.Background(), "pay-1", "wal-1", usd(400_00))
+			require.NoError(t, err)
+
+			err = tt.run(svc)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			wallet, err := repo.GetByID("wal-1")
+			require.NoError(t, err)
+			assert.Equal(t, usd(tt.available), wallet.Available)
+			assert.Equal(t, usd(tt.held), wallet.Held)
+			assertBalanced(t, repo, "wal-1")
+		})
+	}
+}
+
+func TestWalletService_CreditIsIdempotentByReference(t *testing.T) {
+	svc, _ := newTestWalletService(t, 0)
+
+	wallet, err := svc.Credit(context.Background(), "wal-1", usd(50_00), "refund-1")
+	require.NoError(t, err)
+	assert.Equal(t, usd(50_00), wallet.Available)
+
+	wallet, err = svc.Credit(context.Background(), "wal-1", usd(50_00), "refund-1")
+	require.NoError(t, err)
+	assert.Equal(t, usd(50_00), wallet.Available)
+
+	wallet, err = svc.Credit(context.Background(), "wal-1", usd(25_00), "refund-2")
+	require.NoError(t, err)
+	assert.Equal(t, usd(75_00), wallet.Available)
+}
+
+func TestWalletService_OpenRejectsUnknownCurrencies(t *testing.T) {
+	svc := NewWalletService(database.NewInMemoryWalletRepository())
+
+	_, err := svc.Open(context.Background(), "wal-1", "XYZ")
+	assert.ErrorIs(t, err, domain.ErrUnknownCurrency)
+}
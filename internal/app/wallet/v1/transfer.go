@@ -0,0 +1,104 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+// Transfer moves a decimal amount, in the currency of the origin, between two
+// wallets of the same currency.
+func (s *walletService) Transfer(ctx context.Context, originWalletId, destinationWalletId string, amount string) (domain.Transaction, error) {
+	if originWalletId == destinationWalletId {
+		return domain.Transaction{}, domain.ErrSameWallet
+	}
+
+	money, err := s.parseAmount(originWalletId, amount)
+	if err != nil {
+		return domain.Transaction{}, err
+	}
+
+	transaction := domain.Transaction{}
+
+	err = s.save(ctx, func() (*domain.WalletChange, error) {
+		origin, err := s.repository.GetByID(originWalletId)
+		if err != nil {
+			return nil, err
+		}
+		destination, err := s.repository.GetByID(destinationWalletId)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := validateAmount(destination, money); err != nil {
+			return nil, err
+		}
+		if err := origin.Withdraw(money); err != nil {
+			return nil, err
+		}
+		if err := destination.Credit(money); err != nil {
+			return nil, err
+		}
+
+		transaction = domain.NewTransaction(originWalletId, destinationWalletId, money)
+		return &domain.WalletChange{
+			Wallets:     []domain.Wallet{origin, destination},
+			Transaction: &transaction,
+			Entries: domain.NewLedgerTransfer(
+				"transfer."+transaction.ID,
+				domain.LedgerAccountAvailable(originWalletId),
+				domain.LedgerAccountAvailable(destinationWalletId),
+				money,
+			),
+		}, nil
+	})
+
+	return transaction, err
+}
+
+// Revert gives the funds of a transaction back to its origin. It fails when
+// the destination already spent them or the transaction was reverted.
+func (s *walletService) Revert(ctx context.Context, transactionId string) (domain.Transaction, error) {
+	var transaction domain.Transaction
+
+	err := s.save(ctx, func() (*domain.WalletChange, error) {
+		var err error
+		transaction, err = s.repository.GetTransaction(transactionId)
+		if err != nil {
+			return nil, err
+		}
+		if transaction.Status == domain.TransactionStatusReverted {
+			return nil, domain.ErrTransactionReverted
+		}
+
+		origin, err := s.repository.GetByID(transaction.OriginWalletID)
+		if err != nil {
+			return nil, err
+		}
+		destination, err := s.repository.GetByID(transaction.DestinationWalletID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := destination.Withdraw(transaction.Amount); err != nil {
+			return nil, err
+		}
+		if err := origin.Credit(transaction.Amount); err != nil {
+			return nil, err
+		}
+		transaction.Revert()
+
+		return &domain.WalletChange{
+			Wallets:     []domain.Wallet{origin, destination},
+			Transaction: &transaction,
+			Entries: domain.NewLedgerTransfer(
+				"revert."+transaction.ID,
+				domain.LedgerAccountAvailable(transaction.DestinationWalletID),
+				domain.LedgerAccountAvailable(transaction.OriginWalletID),
+				transaction.Amount,
+			),
+		}, nil
+	})
+
+	return transaction, err
+}
@@ -0,0 +1,113 @@
+package v1
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWalletService_Transfer(t *testing.T) {
+	tests := []struct {
+		name          string
+		origin        string
+		destination   string
+		amount        string
+		expectedError error
+		originBalance int64
+		destBalance   int64
+	}{
+		{name: "moves the funds", origin: "wal-1", destination: "wal-2", amount: "300", originBalance: 700_00, destBalance: 300_00},
+		{name: "moves cents", origin: "wal-1", destination: "wal-2", amount: "0.10", originBalance: 999_90, destBalance: 10},
+		{name: "insufficient funds", origin: "wal-1", destination: "wal-2", amount: "1000.01", expectedError: domain.ErrInsufficientFunds, originBalance: 1000_00},
+		{name: "too many decimals", origin: "wal-1", destination: "wal-2", amount: "0.001", expectedError: domain.ErrTooManyDecimals, originBalance: 1000_00},
+		{name: "same wallet", origin: "wal-1", destination: "wal-1", amount: "10", expectedError: domain.ErrSameWallet, originBalance: 1000_00},
+		{name: "different currency", origin: "wal-1", destination: "wal-eur", amount: "10", expectedError: domain.ErrCurrencyMismatch, originBalance: 1000_00},
+		{name: "unknown destination", origin: "wal-1", destination: "wal-3", amount: "10", expectedError: domain.ErrWalletNotFound, originBalance: 1000_00},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, repo := newTestWalletService(t, 1000_00)
+			_, err := svc.Open(context.Background(), "wal-2", "USD")
+			require.NoError(t, err)
+			_, err = svc.Open(context.Background(), "wal-eur", "EUR")
+			require.NoError(t, err)
+
+			transaction, err := svc.Transfer(context.Background(), tt.origin, tt.destination, tt.amount)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, domain.TransactionStatusCompleted, transaction.Status)
+			}
+
+			origin, err := repo.GetByID("wal-1")
+			require.NoError(t, err)
+			destination, err := repo.GetByID("wal-2")
+			require.NoError(t, err)
+			assert.Equal(t, usd(tt.originBalance), origin.Available)
+			assert.Equal(t, usd(tt.destBalance), destination.Available)
+			assertBalanced(t, repo, "wal-1")
+			assertBalanced(t, repo, "wal-2")
+		})
+	}
+}
+
+func TestWalletService_ConcurrentRevertsApplyOnce(t *testing.T) {
+	svc, repo := newTestWalletService(t, 1000_00)
+	_, err := svc.Open(context.Background(), "wal-2", "USD")
+	require.NoError(t, err)
+
+	transaction, err := svc.Transfer(context.Background(), "wal-1", "wal-2", "100")
+	require.NoError(t, err)
+
+	var reverted, rejected atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.Revert(context.Background(), transaction.ID)
+			switch {
+			case err == nil:
+				reverted.Add(1)
+			case assert.ErrorIs(t, err, domain.ErrTransactionReverted):
+				rejected.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), reverted.Load())
+	assert.Equal(t, int32(4), rejected.Load())
+
+	origin, err := repo.GetByID("wal-1")
+	require.NoError(t, err)
+	assert.Equal(t, usd(1000_00), origin.Available)
+	assertBalanced(t, repo, "wal-1")
+	assertBalanced(t, repo, "wal-2")
+}
+
+func TestWalletService_RevertNeedsTheDestinationFunds(t *testing.T) {
+	svc, _ := newTestWalletService(t, 1000_00)
+	_, err := svc.Open(context.Background(), "wal-2", "USD")
+	require.NoError(t, err)
+	_, err = svc.Open(context.Background(), "wal-3", "USD")
+	require.NoError(t, err)
+
+	transaction, err := svc.Transfer(context.Background(), "wal-1", "wal-2", "100")
+	require.NoError(t, err)
+	_, err = svc.Transfer(context.Background(), "wal-2", "wal-3", "60")
+	require.NoError(t, err)
+
+	_, err = svc.Revert(context.Background(), transaction.ID)
+	assert.ErrorIs(t, err, domain.ErrInsufficientFunds)
+
+	_, err = svc.Revert(context.Background(), "unknown")
+	assert.ErrorIs(t, err, domain.ErrTransactionNotFound)
+}
@@ -0,0 +1,106 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+var (
+	ErrRateNotFound = errors.New("exchange rate not found")
+	ErrInvalidRate  = errors.New("invalid exchange rate")
+)
+
+// FXRateProvider quotes the rate to convert between two currencies.
+type FXRateProvider interface {
+	Rate(from, to string) (FXRate, error)
+}
+
+// FXRate is how many units of To one unit of From buys, as a decimal string
+// (e.g. "0.92") so it is applied exactly.
+type FXRate struct {
+	From     string
+	To       string
+	Rate     string
+	Provider string
+}
+
+// NewFXRate validates both currencies and that rate is a positive decimal.
+func NewFXRate(from, to, rate, provider string) (FXRate, error) {
+	for _, code := range []string{from, to} {
+		if _, err := LookupCurrency(code); err != nil {
+			return FXRate{}, err
+		}
+	}
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok || r.Sign() <= 0 {
+		return FXRate{}, fmt.Errorf("%w: %s/%s %q", ErrInvalidRate, from, to, rate)
+	}
+
+	return FXRate{From: from, To: to, Rate: rate, Provider: provider}, nil
+}
+
+// Convert applies the rate to amount, rounding half away from zero to the
+// minor unit of the target currency.
+func (r FXRate) Convert(amount Money) (Money, error) {
+	if amount.Currency != r.From {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, amount.Currency, r.From)
+	}
+	rate, ok := new(big.Rat).SetString(r.Rate)
+	if !ok {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidRate, r.Rate)
+	}
+
+	converted := new(big.Rat).Mul(new(big.Rat).SetInt64(amount.Minor), rate)
+	converted.Mul(converted, new(big.Rat).SetFrac(pow10(CurrencyExponent(r.To)), pow10(CurrencyExponent(r.From))))
+
+	minor := round(converted)
+	if !minor.IsInt64() {
+		return Money{}, fmt.Errorf("%w: %s converted to %s overflows", ErrInvalidMoney, amount, r.To)
+	}
+
+	return NewMoney(minor.Int64(), r.To), nil
+}
+
+// round rounds r half away from zero.
+func round(r *big.Rat) *big.Int {
+	quo, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(r.Denom()) >= 0 {
+		quo.Add(quo, big.NewInt(int64(r.Sign())))
+	}
+	return quo
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// FXConversion records the conversion of a payment into the currency of its wallet.
+type FXConversion struct {
+	From        Money
+	To          Money
+	Rate        string
+	Provider    string
+	ConvertedAt time.Time
+}
+
+// Convert quotes the rate from amount to currency and applies it.
+func Convert(rates FXRateProvider, amount Money, currency string) (FXConversion, error) {
+	rate, err := rates.Rate(amount.Currency, currency)
+	if err != nil {
+		return FXConversion{}, err
+	}
+	converted, err := rate.Convert(amount)
+	if err != nil {
+		return FXConversion{}, err
+	}
+
+	return FXConversion{
+		From:        amount,
+		To:          converted,
+		Rate:        rate.Rate,
+		Provider:    rate.Provider,
+		ConvertedAt: time.Now().UTC(),
+	}, nil
+}
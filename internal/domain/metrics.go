@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Metrics published on TopicMetrics. The event type of a metric event is its
+// metric name.
+const (
+	MetricPaymentSuccess  = "metric.payment_success"
+	MetricPaymentFailure  = "metric.payment_failure"
+	MetricPaymentCanceled = "metric.payment_canceled"
+	// MetricPaymentCancelRejected counts cancellations that arrived too late.
+	MetricPaymentCancelRejected = "metric.payment_cancel_rejected"
+	MetricRefundSuccess         = "metric.refund_success"
+	MetricRefundFailure         = "metric.refund_failure"
+	MetricSagaStepTimeout       = "metric.saga_step_timeout"
+
+	MetricWalletHoldFundsFailure = "metric.wallet_hold_funds_failure"
+	MetricWalletDebitFailure     = "metric.wallet_debit_failure"
+	MetricWalletCreditFailure    = "metric.wallet_credit_failure"
+	MetricGatewayUnauthorize     = "metric.gateway_unauthorize"
+)
+
+// Labels of the metric events.
+const (
+	MetricLabelSaga    = "saga"
+	MetricLabelStep    = "step"
+	MetricLabelOutcome = "outcome"
+	MetricLabelReason  = "reason"
+)
+
+type MetricEvent struct {
+	CommandEvent
+	MetricEventPayload `json:"payload"`
+}
+
+type MetricEventPayload struct {
+	Metric string            `json:"metric"`
+	Labels map[string]string `json:"labels,omitempty"`
+	// Value is the observation of the metric, e.g. the duration of a saga in
+	// seconds. Metrics that only count occurrences leave it empty.
+	Value float64 `json:"value,omitempty"`
+}
+
+// NewMetricEvent builds the metric of the operation id. It is deduplicated by
+// metric and id, so an operation is counted once however often it is retried.
+func NewMetricEvent(metric, id string, labels map[string]string, value float64) MetricEvent {
+	return MetricEvent{
+		CommandEvent: CommandEvent{
+			EventType:    metric,
+			EventVersion: "1",
+			Timestamp:    time.Now().UTC().Format(time.RFC3339),
+			CommandEventMetadata: CommandEventMetadata{
+				TraceID:                uuid.NewString(),
+				MessageGroupID:         id,
+				MessageDeduplicationId: BuildDeduplicationId(metric, id),
+			},
+		},
+		MetricEventPayload: MetricEventPayload{
+			Metric: metric,
+			Labels: labels,
+			Value:  value,
+		},
+	}
+}
+
+// MetricsRecorder aggregates the metric events.
+type MetricsRecorder interface {
+	Record(metric MetricEventPayload)
+}
@@ -0,0 +1,179 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidMoney     = errors.New("invalid amount")
+	ErrTooManyDecimals  = errors.New("amount has more decimals than the currency allows")
+	ErrCurrencyMismatch = errors.New("currencies do not match")
+)
+
+// Money is an amount in the minor unit of its currency (cents for USD), so
+// arithmetic is exact. It is encoded in JSON as {"amount":"12.30","currency":"USD"}.
+type Money struct {
+	Minor    int64
+	Currency string
+}
+
+func NewMoney(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: currency}
+}
+
+// ParseMoney reads a decimal amount such as "12.30" or "12.3". Amounts with
+// more decimals than the currency allows are rejected instead of rounded.
+func ParseMoney(amount, currency string) (Money, error) {
+	c, err := LookupCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	return parseMoney(amount, c.Code, c.Exponent)
+}
+
+func parseMoney(amount, currency string, exp int) (Money, error) {
+	s := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if whole == "" || (hasFrac && frac == "") || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, amount)
+	}
+
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > exp {
+		return Money{}, fmt.Errorf("%w: %q in %s", ErrTooManyDecimals, amount, currency)
+	}
+
+	minor, err := strconv.ParseInt(whole+frac+strings.Repeat("0", exp-len(frac)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidMoney, amount)
+	}
+	if negative {
+		minor = -minor
+	}
+
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Decimal formats the amount with the decimals of its currency, e.g. "12.30".
+func (m Money) Decimal() string {
+	exp := CurrencyExponent(m.Currency)
+
+	minor := m.Minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+
+	digits := strconv.FormatInt(minor, 10)
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-exp] + "." + digits[len(digits)-exp:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+func (m Money) IsPositive() bool {
+	return m.Minor > 0
+}
+
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	return Money{Minor: m.Minor + o.Minor, Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	return m.Add(Money{Minor: -o.Minor, Currency: o.Currency})
+}
+
+// LessThan reports whether m is smaller than o; both must share the currency.
+func (m Money) LessThan(o Money) (bool, error) {
+	diff, err := m.Sub(o)
+	if err != nil {
+		return false, err
+	}
+	return diff.Minor < 0, nil
+}
+
+// Prorate returns the share part/whole of m, rounded half away from zero to
+// the minor unit.
+func (m Money) Prorate(part, whole int64) Money {
+	if whole == 0 {
+		return NewMoney(0, m.Currency)
+	}
+	share := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Minor), big.NewRat(part, whole))
+	return NewMoney(round(share).Int64(), m.Currency)
+}
+
+type moneyJSON struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{Amount: m.Decimal(), Currency: m.Currency})
+}
+
+// UnmarshalJSON accepts the amount as a decimal string or a JSON number.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	var raw moneyJSON
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	amount := string(raw.Amount)
+	if strings.HasPrefix(amount, `"`) {
+		if err := json.Unmarshal(raw.Amount, &amount); err != nil {
+			return err
+		}
+	}
+
+	// The zero Money, e.g. an amount that was never set, has no currency.
+	if raw.Currency == "" {
+		if zero, err := parseMoney(amount, "", CurrencyExponent("")); err == nil && zero.IsZero() {
+			*m = Money{}
+			return nil
+		}
+	}
+
+	parsed, err := ParseMoney(amount, raw.Currency)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,110 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount        string
+		currency      string
+		expected      Money
+		expectedError error
+	}{
+		{amount: "12.30", currency: "USD", expected: NewMoney(1230, "USD")},
+		{amount: "12.3", currency: "USD", expected: NewMoney(1230, "USD")},
+		{amount: "1230", currency: "USD", expected: NewMoney(123000, "USD")},
+		{amount: "0.01", currency: "USD", expected: NewMoney(1, "USD")},
+		{amount: "12.300", currency: "USD", expected: NewMoney(1230, "USD")},
+		{amount: "-5.5", currency: "EUR", expected: NewMoney(-550, "EUR")},
+		{amount: "1000", currency: "JPY", expected: NewMoney(1000, "JPY")},
+		{amount: "1.234", currency: "KWD", expected: NewMoney(1234, "KWD")},
+		{amount: "12.345", currency: "USD", expectedError: ErrTooManyDecimals},
+		{amount: "10.5", currency: "JPY", expectedError: ErrTooManyDecimals},
+		{amount: "1e3", currency: "USD", expectedError: ErrInvalidMoney},
+		{amount: "abc", currency: "USD", expectedError: ErrInvalidMoney},
+		{amount: "", currency: "USD", expectedError: ErrInvalidMoney},
+		{amount: "12.", currency: "USD", expectedError: ErrInvalidMoney},
+		{amount: ".5", currency: "USD", expectedError: ErrInvalidMoney},
+		{amount: "99999999999999999999", currency: "USD", expectedError: ErrInvalidMoney},
+		{amount: "10", currency: "XYZ", expectedError: ErrUnknownCurrency},
+		{amount: "10", currency: "usd", expectedError: ErrUnknownCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.currency, func(t *testing.T) {
+			m, err := ParseMoney(tt.amount, tt.currency)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, m)
+		})
+	}
+}
+
+func TestMoney_Decimal(t *testing.T) {
+	assert.Equal(t, "12.30", NewMoney(1230, "USD").Decimal())
+	assert.Equal(t, "0.05", NewMoney(5, "USD").Decimal())
+	assert.Equal(t, "-0.50", NewMoney(-50, "USD").Decimal())
+	assert.Equal(t, "1000", NewMoney(1000, "JPY").Decimal())
+	assert.Equal(t, "0.001", NewMoney(1, "KWD").Decimal())
+	assert.Equal(t, "12.30 USD", NewMoney(1230, "USD").String())
+}
+
+func TestMoney_Arithmetic(t *testing.T) {
+	sum, err := NewMoney(1230, "USD").Add(NewMoney(70, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, NewMoney(1300, "USD"), sum)
+
+	diff, err := NewMoney(1230, "USD").Sub(NewMoney(1300, "USD"))
+	require.NoError(t, err)
+	assert.Equal(t, NewMoney(-70, "USD"), diff)
+
+	less, err := NewMoney(1, "USD").LessThan(NewMoney(2, "USD"))
+	require.NoError(t, err)
+	assert.True(t, less)
+
+	_, err = NewMoney(1, "USD").Add(NewMoney(1, "EUR"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+}
+
+func TestMoney_JSON(t *testing.T) {
+	b, err := json.Marshal(NewMoney(1230, "USD"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"12.30","currency":"USD"}`, string(b))
+
+	tests := []struct {
+		in            string
+		expected      Money
+		expectedError error
+	}{
+		{in: `{"amount":"12.30","currency":"USD"}`, expected: NewMoney(1230, "USD")},
+		{in: `{"amount":12.3,"currency":"USD"}`, expected: NewMoney(1230, "USD")},
+		{in: `{"amount":500,"currency":"JPY"}`, expected: NewMoney(500, "JPY")},
+		{in: `{"amount":"12.345","currency":"USD"}`, expectedError: ErrTooManyDecimals},
+		{in: `{"amount":0.1234,"currency":"USD"}`, expectedError: ErrTooManyDecimals},
+		{in: `{"amount":true,"currency":"USD"}`, expectedError: ErrInvalidMoney},
+		{in: `{"amount":"10.00","currency":"XYZ"}`, expectedError: ErrUnknownCurrency},
+		{in: `{"amount":"0.00","currency":""}`, expected: Money{}},
+		{in: `{"amount":"1.00","currency":""}`, expectedError: ErrUnknownCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var m Money
+			err := json.Unmarshal([]byte(tt.in), &m)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, m)
+		})
+	}
+}
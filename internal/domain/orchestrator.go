@@ -2,19 +2,16 @@ package domain
 
 import (
 	"context"
+	"strings"
 )
 
-const (
-	TopicOrchestratorWallet       = "orchestrator.wallet"
-	TopicOrchestratorPayment      = "orchestrator.payment"
-	TopicOrchestratorGateway      = "orchestrator.gateway"
-	TopicOrchestratorNotification = "orchestrator.notification"
-)
-
+// WalletCommands are the operations the wallet service offers to the saga.
+// Holds are keyed by the payment they reserve funds for.
 type WalletCommands interface {
-	Debit(ctx context.Context, walletId string, amount float64, currency string) error
-	Credit(ctx context.Context, walletId string, amount float64, currency string) error
-	Release(ctx context.Context, walletId string, amount float64, currency string) error
+	Hold(ctx context.Context, holdId, walletId string, amount Money) (Hold, error)
+	Release(ctx context.Context, holdId string) (Hold, error)
+	Debit(ctx context.Context, holdId string) (Hold, error)
+	Credit(ctx context.Context, walletId string, amount Money, reference string) (Wallet, error)
 }
 
 const (
@@ -24,37 +21,47 @@ const (
 )
 
 const (
-	PaymentUpdateStatusEventType = "payment_update_status"
-	AuthorizeGatewayEventType    = "authorize_gateway"
-	NotifyUserEventType          = "notify_user"
+	PaymentUpdateStatusEventType  = "payment_update_status"
+	AuthorizeGatewayEventType     = "authorize_gateway"
+	CaptureAuthorizationEventType = "capture_authorization"
+	VoidAuthorizationEventType    = "void_authorization"
+	RefundAuthorizationEventType  = "refund_authorization"
+	NotifyUserEventType           = "notify_user"
 )
 
 // Event from Payment Service
 type PaymentCreatedEvent struct {
-	ID       string  `json:"id"`
-	WalletID string  `json:"wallet_id"`
-	Amount   float64 `json:"amount"`
-	Currency string  `json:"currency"`
-	Token    string  `json:"token"`
+	CommandEvent
+	PaymentCreatedEventPayload `json:"payload"`
+}
+
+type PaymentCreatedEventPayload struct {
+	PaymentID string `json:"payment_id"`
+	WalletID  string `json:"wallet_id"`
+	Amount    Money  `json:"amount"`
+	// WalletAmount is Amount in the wallet currency, after any FX conversion.
+	WalletAmount Money         `json:"wallet_amount"`
+	Token        string        `json:"token"`
+	Method       string        `json:"method,omitempty"`
+	Status       PaymentStatus `json:"status"`
 }
 
-// Event from Gateway Service
+// Event from Gateway Service. Amounts the gateway does not send back are
+// left out rather than sent as a zero amount without currency.
 type GatewayAuthorizedEvent struct {
 	CommandEvent
-	PaymentID string  `json:"payment_id"`
-	WalletID  string  `json:"wallet_id"`
-	Amount    float64 `json:"amount"`
-	Currency  string  `json:"currency"`
+	PaymentID string `json:"payment_id"`
+	WalletID  string `json:"wallet_id"`
+	Amount    Money  `json:"amount,omitzero"`
 }
 
 // Event from Gateway Service on failure
 type GatewayAuthorizationFailedEvent struct {
 	CommandEvent
-	PaymentID string  `json:"payment_id"`
-	WalletID  string  `json:"wallet_id"`
-	Amount    float64 `json:"amount"`
-	Currency  string  `json:"currency"`
-	Reason    string  `json:"reason"`
+	PaymentID string `json:"payment_id"`
+	WalletID  string `json:"wallet_id"`
+	Amount    Money  `json:"amount,omitzero"`
+	Reason    string `json:"reason"`
 }
 
 type CommandEvent struct {
@@ -64,8 +71,10 @@ type CommandEvent struct {
 	CommandEventMetadata `json:"metadata"`
 }
 
-func BuildDeduplicationId(action, id string) string {
-	return action + "." + id
+// BuildDeduplicationId identifies a message by its action and the ids that make
+// it unique, e.g. the payment and the status it moves to.
+func BuildDeduplicationId(action string, ids ...string) string {
+	return strings.Join(append([]string{action}, ids...), ".")
 }
 
 type CommandEventMetadata struct {
@@ -74,17 +83,27 @@ type CommandEventMetadata struct {
 	MessageDeduplicationId string `json:"message_deduplication_id"`
 }
 
+// WalletCommandEventVersion is the version of WalletCommandEvent. Version 2
+// carries the amount as Money instead of a float and a separate currency;
+// version 1 messages are upcast when consumed, see the schema package.
+const WalletCommandEventVersion = "2"
+
 type WalletCommandEvent struct {
 	CommandEvent
 	WalletCommandEventPayload `json:"payload"`
 }
 
+// WalletCommandEventPayload leaves Amount out of the commands without one,
+// such as voids.
 type WalletCommandEventPayload struct {
-	WalletID  string  `json:"wallet_id"`
-	PaymentID string  `json:"payment_id"`
-	Amount    float64 `json:"amount"`
-	Currency  string  `json:"currency"`
-	Token     string  `json:"token"`
+	WalletID  string `json:"wallet_id"`
+	PaymentID string `json:"payment_id"`
+	Amount    Money  `json:"amount,omitzero"`
+	Token     string `json:"token"`
+	// Method selects the payment provider of gateway commands.
+	Method string `json:"method,omitempty"`
+	// Reason explains a failed wallet operation.
+	Reason string `json:"reason,omitempty"`
 }
 
 type PaymentUpdateStatusEvent struct {
@@ -104,32 +123,29 @@ type NotifyUserEvent struct {
 
 type NotifyUserEventPayload struct {
 	PaymentID    string       `json:"payment_id"`
+	RefundID     string       `json:"refund_id,omitempty"`
 	Notification Notification `json:"notification"`
 }
 
-type MetricEvent struct {
-	CommandEvent
-	MetricEventPayload `json:"payload"`
-}
-
-type MetricEventPayload struct {
-	Metric string `json:"metric"`
-}
-
 type PaymentCommands interface {
 	UpdateStatus(ctx context.Context, paymentId string, status PaymentStatus) error
 }
 
+// GatewayCommands selects the payment provider handling each payment method.
 type GatewayCommands interface {
-	Authorize(ctx context.Context, paymentId string, amount float64, currency string, token string) error
-	Refund(ctx context.Context, paymentId string) error
+	// Provider returns ErrUnsupportedPaymentMethod when no provider handles method.
+	Provider(method string) (PaymentProvider, error)
 }
 
 type Notification string
 
 const (
-	PaymentFailure Notification = "payment_failure"
-	PaymentSuccess Notification = "payment_success"
+	PaymentFailure  Notification = "payment_failure"
+	PaymentSuccess  Notification = "payment_success"
+	PaymentCanceled Notification = "payment_canceled"
+	// PaymentCancelRejected tells the user a cancellation the API accepted
+	// arrived once the payment could no longer be canceled.
+	PaymentCancelRejected Notification = "payment_cancel_rejected"
 
 	RefundFailure Notification = "refund_failure"
 	RefundSuccess Notification = "refund_success"
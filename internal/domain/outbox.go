@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrOutboxMessageNotFound = errors.New("outbox message not found")
+
+// OutboxMessage is an event stored in the same transaction as the change that
+// produced it, waiting to be relayed to the event bus.
+type OutboxMessage struct {
+	ID        string
+	Topic     string
+	Payload   []byte
+	CreatedAt time.Time
+	Attempts  int
+	LastError string
+}
+
+type OutboxRepository interface {
+	// Add stores messages that are not tied to any other change.
+	Add(messages ...OutboxMessage) error
+	// ListPending returns up to limit messages in the order they were written.
+	ListPending(limit int) ([]OutboxMessage, error)
+	MarkPublished(id string) error
+	MarkFailed(id string, cause error) error
+}
+
+func NewOutboxMessage(topic string, payload []byte) OutboxMessage {
+	return OutboxMessage{
+		// Version 7 UUIDs sort by creation time, which keeps the outbox ordered.
+		ID:        uuid.Must(uuid.NewV7()).String(),
+		Topic:     topic,
+		Payload:   payload,
+		CreatedAt: time.Now().UTC(),
+	}
+}
@@ -1,45 +1,94 @@
 package domain
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-const (
-	TopicPaymentCreated   = "payment.created"
-	TopicPaymentCompleted = "payment.completed"
-
-	TopicMetrics           = "metrics"
-	MetricPaymentSuccess = "metric.payment_success"
+var (
+	ErrPaymentNotFound        = errors.New("payment not found")
+	ErrPaymentAlreadyExists   = errors.New("payment already exists")
+	ErrPaymentVersionConflict = errors.New("payment was modified concurrently")
+	ErrInvalidPaymentCursor   = errors.New("invalid payment cursor")
+	ErrPaymentNotCancelable   = errors.New("payment can no longer be canceled")
+	ErrPaymentAlreadyDebited  = errors.New("payment was already debited")
 )
 
 type PaymentRepository interface {
-	Create(payment Payment) error
+	// Create stores the payment and the outbox messages in a single transaction.
+	Create(payment Payment, outbox ...OutboxMessage) error
+	GetByID(id string) (Payment, error)
+	// UpdateStatus only applies when the stored payment is still at expectedVersion,
+	// otherwise it returns ErrPaymentVersionConflict.
+	UpdateStatus(id string, status PaymentStatus, expectedVersion int) error
+	ListByWallet(walletId string) ([]Payment, error)
+	// Search returns the payments matching filter, newest first, one page at a time.
+	Search(filter PaymentFilter) (PaymentPage, error)
+}
+
+// PaymentFilter narrows a payment search. Zero values are ignored.
+type PaymentFilter struct {
+	WalletID string
+	Status   PaymentStatus
+	From     time.Time
+	To       time.Time
+	Cursor   string
+	Limit    int
+}
+
+type PaymentPage struct {
+	Payments   []Payment
+	NextCursor string
 }
 
 type Payment struct {
 	ID        string
 	WalletID  string
 	ServiceID string
-	Amount    float64
-	Currency  string
-	Method    string
-	Status    PaymentStatus
+	Amount    Money
+	// Conversion is set when the payment currency differs from the wallet's.
+	Conversion *FXConversion
+	Method     string
+	Token      string
+	Status     PaymentStatus
+	// History lists the status transitions of the payment, oldest first.
+	History   []PaymentTransition
+	Version   int
 	CreatedAt time.Time
 	UpdatedAt *time.Time
 }
 
+// WalletAmount is the amount charged to the wallet, in its currency.
+func (p Payment) WalletAmount() Money {
+	if p.Conversion != nil {
+		return p.Conversion.To
+	}
+	return p.Amount
+}
+
 type PaymentStatus string
 
 const (
-	PaymentStatusPending   PaymentStatus = "PENDING"
-	PaymentStatusFailed    PaymentStatus = "FAILED"
-	PaymentStatusCompleted PaymentStatus = "COMPLETED"
+	PaymentStatusPending    PaymentStatus = "PENDING"
+	PaymentStatusFundsHeld  PaymentStatus = "FUNDS_HELD"
+	PaymentStatusAuthorized PaymentStatus = "AUTHORIZED"
+	PaymentStatusDebited    PaymentStatus = "DEBITED"
+	PaymentStatusFailed     PaymentStatus = "FAILED"
+	PaymentStatusCompleted  PaymentStatus = "COMPLETED"
+	PaymentStatusCanceled   PaymentStatus = "CANCELED"
+	// PaymentStatusRequiresReview is a payment whose saga could not undo what
+	// it did, e.g. the gateway declined refunding its authorization.
+	PaymentStatusRequiresReview PaymentStatus = "REQUIRES_REVIEW"
+
+	PaymentStatusRefunded          PaymentStatus = "REFUNDED"
+	PaymentStatusPartiallyRefunded PaymentStatus = "PARTIALLY_REFUNDED"
 )
 
-func (p *Payment) SetStatus(status PaymentStatus) {
-	p.Status = status
+func (s PaymentStatus) IsValid() bool {
+	_, ok := paymentTransitions[s]
+	return ok && s != ""
 }
 
 func (p *Payment) SetID() {
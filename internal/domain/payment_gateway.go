@@ -1,6 +1,96 @@
 package domain
 
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrGatewayDeclined is returned by a PaymentProvider when it refuses the
+	// operation; retrying it would give the same answer.
+	ErrGatewayDeclined = errors.New("declined by the payment gateway")
+	// ErrGatewayPending is returned by a PaymentProvider when it accepted the
+	// operation and confirms it later with a callback.
+	ErrGatewayPending = errors.New("pending confirmation of the payment gateway")
+	// ErrGatewayUnavailable is a transient failure of the provider; the
+	// operation can be retried.
+	ErrGatewayUnavailable = errors.New("payment gateway unavailable")
+	// ErrUnsupportedPaymentMethod is returned when no provider handles the
+	// method of a payment.
+	ErrUnsupportedPaymentMethod = errors.New("unsupported payment method")
+)
+
+// PaymentProvider is an external payment provider. Every operation is
+// idempotent for the payment, or the refund, it names.
+type PaymentProvider interface {
+	Authorize(ctx context.Context, paymentId string, amount Money, token string) error
+	// Capture collects an authorized payment, before the wallet debits its
+	// held funds.
+	Capture(ctx context.Context, paymentId string, amount Money) error
+	// Void cancels the authorization of a payment that will not be debited.
+	Void(ctx context.Context, paymentId string) error
+	// Refund gives amount of an authorized payment back; refundId makes it idempotent.
+	Refund(ctx context.Context, refundId, paymentId string, amount Money) error
+}
+
+var (
+	ErrUnknownGatewayProvider   = errors.New("unknown payment gateway provider")
+	ErrInvalidCallbackSignature = errors.New("invalid gateway callback signature")
+	ErrInvalidCallback          = errors.New("invalid gateway callback")
+)
+
+type GatewayCallbackType string
+
 const (
-	TopicGatewayAuthorized          = "gateway.authorized"
-	TopicGatewayAuthorizationFailed = "gateway.authorization_failed"
+	GatewayCallbackAuthorized          GatewayCallbackType = "authorized"
+	GatewayCallbackAuthorizationFailed GatewayCallbackType = "authorization_failed"
+	GatewayCallbackCaptured            GatewayCallbackType = "captured"
+	GatewayCallbackCaptureFailed       GatewayCallbackType = "capture_failed"
+	GatewayCallbackRefunded            GatewayCallbackType = "refunded"
+	GatewayCallbackRefundFailed        GatewayCallbackType = "refund_failed"
 )
+
+// AuthorizationRefundID is the refund id the gateway is sent when the payment
+// saga refunds the capture of a payment. A payment has a single capture, so
+// the refund is keyed by the payment.
+func AuthorizationRefundID(paymentId string) string {
+	return "authorization." + paymentId
+}
+
+// GatewayCallback is the confirmation of an operation sent by a payment
+// provider, mapped from the payload of that provider.
+type GatewayCallback struct {
+	// ID identifies the notification at the provider, which keeps it when it
+	// delivers the notification again.
+	ID        string
+	Type      GatewayCallbackType
+	PaymentID string
+	// RefundID is only set on refund callbacks.
+	RefundID string
+	Amount   Money
+	// Reason explains a failed operation.
+	Reason string
+}
+
+// AuthorizationRefund reports whether the callback confirms the refund of the
+// capture of its payment rather than a refund of the refunds service.
+func (c GatewayCallback) AuthorizationRefund() bool {
+	return c.RefundID == AuthorizationRefundID(c.PaymentID)
+}
+
+// Validate checks the callback names the operation it confirms.
+func (c GatewayCallback) Validate() error {
+	if c.ID == "" || c.PaymentID == "" {
+		return ErrInvalidCallback
+	}
+	switch c.Type {
+	case GatewayCallbackAuthorized, GatewayCallbackAuthorizationFailed, GatewayCallbackCaptured, GatewayCallbackCaptureFailed:
+		return nil
+	case GatewayCallbackRefunded, GatewayCallbackRefundFailed:
+		if c.RefundID == "" {
+			return ErrInvalidCallback
+		}
+		return nil
+	}
+	return ErrInvalidCallback
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var ErrIllegalPaymentTransition = errors.New("illegal payment status transition")
+
+// paymentTransitions are the statuses each payment status can move to. A
+// payment follows the saga, PENDING to COMPLETED, and can only fail or be
+// canceled until its funds are debited.
+var paymentTransitions = map[PaymentStatus][]PaymentStatus{
+	"":                             {PaymentStatusPending},
+	PaymentStatusPending:           {PaymentStatusFundsHeld, PaymentStatusFailed, PaymentStatusCanceled},
+	PaymentStatusFundsHeld:         {PaymentStatusAuthorized, PaymentStatusFailed, PaymentStatusCanceled},
+	PaymentStatusAuthorized:        {PaymentStatusDebited, PaymentStatusFailed, PaymentStatusCanceled, PaymentStatusRequiresReview},
+	PaymentStatusDebited:           {PaymentStatusCompleted},
+	PaymentStatusCompleted:         {PaymentStatusPartiallyRefunded, PaymentStatusRefunded},
+	PaymentStatusPartiallyRefunded: {PaymentStatusRefunded},
+	PaymentStatusFailed:            nil,
+	PaymentStatusCanceled:          nil,
+	PaymentStatusRefunded:          nil,
+	PaymentStatusRequiresReview:    nil,
+}
+
+// PaymentTransition is a status change in the history of a payment.
+type PaymentTransition struct {
+	From PaymentStatus
+	To   PaymentStatus
+	At   time.Time
+}
+
+func (s PaymentStatus) CanTransitionTo(to PaymentStatus) bool {
+	return slices.Contains(paymentTransitions[s], to)
+}
+
+// Debited reports whether the money of the payment already left the wallet.
+func (s PaymentStatus) Debited() bool {
+	switch s {
+	case PaymentStatusDebited, PaymentStatusCompleted, PaymentStatusPartiallyRefunded, PaymentStatusRefunded:
+		return true
+	}
+	return false
+}
+
+// Transition moves the payment to status and records it in its history. It
+// returns ErrIllegalPaymentTransition when the current status cannot move there.
+func (p *Payment) Transition(status PaymentStatus) error {
+	if !p.Status.CanTransitionTo(status) {
+		return fmt.Errorf("%w: %q to %q", ErrIllegalPaymentTransition, p.Status, status)
+	}
+
+	p.History = append(p.History, PaymentTransition{From: p.Status, To: status, At: time.Now().UTC()})
+	p.Status = status
+	return nil
+}
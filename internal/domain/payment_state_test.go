@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayment_Transition(t *testing.T) {
+	tests := []struct {
+		name          string
+		path          []PaymentStatus
+		expectedError error
+	}{
+		{
+			name: "saga happy path",
+			path: []PaymentStatus{PaymentStatusPending, PaymentStatusFundsHeld, PaymentStatusAuthorized, PaymentStatusDebited, PaymentStatusCompleted},
+		},
+		{
+			name: "refunded in parts",
+			path: []PaymentStatus{PaymentStatusPending, PaymentStatusFundsHeld, PaymentStatusAuthorized, PaymentStatusDebited, PaymentStatusCompleted, PaymentStatusPartiallyRefunded, PaymentStatusRefunded},
+		},
+		{
+			name: "canceled while authorizing",
+			path: []PaymentStatus{PaymentStatusPending, PaymentStatusFundsHeld, PaymentStatusCanceled},
+		},
+		{
+			name:          "skips the debit",
+			path:          []PaymentStatus{PaymentStatusPending, PaymentStatusFundsHeld, PaymentStatusCompleted},
+			expectedError: ErrIllegalPaymentTransition,
+		},
+		{
+			name:          "fails after the debit",
+			path:          []PaymentStatus{PaymentStatusPending, PaymentStatusFundsHeld, PaymentStatusAuthorized, PaymentStatusDebited, PaymentStatusFailed},
+			expectedError: ErrIllegalPaymentTransition,
+		},
+		{
+			name:          "leaves a terminal status",
+			path:          []PaymentStatus{PaymentStatusPending, PaymentStatusFailed, PaymentStatusPending},
+			expectedError: ErrIllegalPaymentTransition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payment Payment
+			var err error
+			for _, status := range tt.path {
+				if err = payment.Transition(status); err != nil {
+					break
+				}
+			}
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Len(t, payment.History, len(tt.path)-1)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.path[len(tt.path)-1], payment.Status)
+			require.Len(t, payment.History, len(tt.path))
+			for i, transition := range payment.History {
+				assert.Equal(t, tt.path[i], transition.To)
+			}
+		})
+	}
+}
+
+func TestPaymentStatus_IsValid(t *testing.T) {
+	assert.True(t, PaymentStatusAuthorized.IsValid())
+	assert.False(t, PaymentStatus("").IsValid())
+	assert.False(t, PaymentStatus("SETTLED").IsValid())
+}
@@ -0,0 +1,17 @@
+package domain
+
+import "errors"
+
+var ErrAlreadyProcessed = errors.New("message already processed")
+
+// ProcessedMessageStore remembers the messages a handler processed, so a
+// redelivery, also after a restart, is not applied twice.
+type ProcessedMessageStore interface {
+	// Claim atomically marks key as being processed. It returns
+	// ErrAlreadyProcessed when key was processed or is being processed.
+	Claim(key string) error
+	// Complete marks a claimed key as processed.
+	Complete(key string) error
+	// Release gives up a claim, so the redelivery of key is processed.
+	Release(key string) error
+}
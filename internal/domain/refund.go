@@ -0,0 +1,211 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RefundGatewayEventType      = "init_refund"
+	CreditFundsEventType        = "credit_funds"
+	RefundUpdateStatusEventType = "refund_update_status"
+)
+
+var (
+	ErrRefundNotFound       = errors.New("refund not found")
+	ErrRefundAlreadyExists  = errors.New("refund already exists")
+	ErrPaymentNotRefundable = errors.New("only completed payments can be refunded")
+	ErrRefundExceedsPayment = errors.New("refund exceeds the amount left to refund")
+)
+
+type RefundRepository interface {
+	// Create stores the refund and the outbox messages in a single transaction.
+	// It returns ErrRefundExceedsPayment when, together with the other refunds
+	// of the payment, the refund is more than the payment amount.
+	Create(refund Refund, outbox ...OutboxMessage) error
+	GetByID(id string) (Refund, error)
+	// ListByPayment returns the refunds of a payment, oldest first.
+	ListByPayment(paymentId string) ([]Refund, error)
+	UpdateStatus(id string, status RefundStatus, reason string) error
+}
+
+// RefundablePaymentRepository keeps the refundable payments of the refunds
+// service.
+type RefundablePaymentRepository interface {
+	// Add stores a payment unless it is already known, so a redelivered
+	// payment.created does not undo its later updates.
+	Add(payment RefundablePayment) error
+	// GetByID returns ErrPaymentNotFound for payments it does not know.
+	GetByID(id string) (RefundablePayment, error)
+	UpdateStatus(id string, status PaymentStatus) error
+}
+
+// RefundablePayment is what the refunds service knows of a payment: a
+// snapshot kept from the payment events, so it never reads the payments of
+// the payment service. The payment service applies the refunds to its own
+// payment through commands.
+type RefundablePayment struct {
+	ID           string
+	WalletID     string
+	Method       string
+	Amount       Money
+	WalletAmount Money
+	Status       PaymentStatus
+}
+
+// NewRefundablePayment takes the snapshot of a payment from its creation.
+func NewRefundablePayment(event PaymentCreatedEvent) RefundablePayment {
+	payment := RefundablePayment{
+		ID:           event.PaymentID,
+		WalletID:     event.WalletID,
+		Method:       event.Method,
+		Amount:       event.Amount,
+		WalletAmount: event.WalletAmount,
+		Status:       PaymentStatusPending,
+	}
+	// Events created before FX conversion carry no wallet amount.
+	if payment.WalletAmount.Currency == "" {
+		payment.WalletAmount = event.Amount
+	}
+	return payment
+}
+
+// Refund gives back part or all of a completed payment. Amount is in the
+// payment currency and WalletAmount in the wallet currency, which differ when
+// the payment was converted.
+type Refund struct {
+	ID           string
+	PaymentID    string
+	WalletID     string
+	Amount       Money
+	WalletAmount Money
+	Status       RefundStatus
+	// Reason explains a failed refund.
+	Reason    string
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+}
+
+type RefundStatus string
+
+const (
+	RefundStatusPending   RefundStatus = "PENDING"
+	RefundStatusCompleted RefundStatus = "COMPLETED"
+	RefundStatusFailed    RefundStatus = "FAILED"
+	// RefundStatusCreditFailed is a refund the gateway gave back but the
+	// wallet could not be credited with. It needs manual review and, as the
+	// money already left the payment, it is not refundable again.
+	RefundStatusCreditFailed RefundStatus = "CREDIT_FAILED"
+)
+
+// NewRefund refunds amount of payment, given the refunds it already has. The
+// refund that completes the payment gets whatever is left of the wallet
+// amount, so rounding never leaves cents behind.
+func NewRefund(payment RefundablePayment, refunds []Refund, amount Money) (Refund, error) {
+	if payment.Status != PaymentStatusCompleted && payment.Status != PaymentStatusPartiallyRefunded {
+		return Refund{}, ErrPaymentNotRefundable
+	}
+	if amount.Currency != payment.Amount.Currency {
+		return Refund{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, amount.Currency, payment.Amount.Currency)
+	}
+	if !amount.IsPositive() {
+		return Refund{}, ErrInvalidAmount
+	}
+
+	left, walletLeft := Refundable(payment, refunds)
+	if exceeds, _ := left.LessThan(amount); exceeds {
+		return Refund{}, fmt.Errorf("%w: %s left", ErrRefundExceedsPayment, left)
+	}
+
+	walletAmount := walletLeft
+	if amount != left {
+		walletAmount = payment.WalletAmount.Prorate(amount.Minor, payment.Amount.Minor)
+	}
+
+	return Refund{
+		ID:           uuid.NewString(),
+		PaymentID:    payment.ID,
+		WalletID:     payment.WalletID,
+		Amount:       amount,
+		WalletAmount: walletAmount,
+		Status:       RefundStatusPending,
+		CreatedAt:    time.Now().UTC(),
+	}, nil
+}
+
+// Refundable is what is left to refund of payment, in the payment and in the
+// wallet currency. Refunds the gateway rejected give nothing back, so they do
+// not count.
+func Refundable(payment RefundablePayment, refunds []Refund) (Money, Money) {
+	left, walletLeft := payment.Amount, payment.WalletAmount
+	for _, r := range refunds {
+		if r.Status == RefundStatusFailed {
+			continue
+		}
+		left, _ = left.Sub(r.Amount)
+		walletLeft, _ = walletLeft.Sub(r.WalletAmount)
+	}
+	return left, walletLeft
+}
+
+// RefundedStatus is the status of payment once its completed refunds are applied.
+func RefundedStatus(payment RefundablePayment, refunds []Refund) PaymentStatus {
+	refunded := NewMoney(0, payment.Amount.Currency)
+	for _, r := range refunds {
+		if r.Status == RefundStatusCompleted {
+			refunded, _ = refunded.Add(r.Amount)
+		}
+	}
+
+	switch {
+	case refunded.IsZero():
+		return payment.Status
+	case refunded == payment.Amount:
+		return PaymentStatusRefunded
+	}
+	return PaymentStatusPartiallyRefunded
+}
+
+func (r *Refund) SetStatus(status RefundStatus, reason string) {
+	r.Status = status
+	r.Reason = reason
+	now := time.Now().UTC()
+	r.UpdatedAt = &now
+}
+
+// RefundCommandEvent is exchanged by every step of the refund saga.
+type RefundCommandEvent struct {
+	CommandEvent
+	RefundCommandEventPayload `json:"payload"`
+}
+
+// RefundCommandEventPayload leaves the amounts out when unset, as the answers
+// to the refund commands only need the refund id.
+type RefundCommandEventPayload struct {
+	RefundID     string       `json:"refund_id"`
+	PaymentID    string       `json:"payment_id"`
+	WalletID     string       `json:"wallet_id"`
+	Amount       Money        `json:"amount,omitzero"`
+	WalletAmount Money        `json:"wallet_amount,omitzero"`
+	Status       RefundStatus `json:"status,omitempty"`
+	// Method is the payment method, which selects the provider refunding it.
+	Method string `json:"method,omitempty"`
+	// PaymentStatus is the status of the payment once the refund completed.
+	PaymentStatus PaymentStatus `json:"payment_status,omitempty"`
+	// Reason explains a failed step.
+	Reason string `json:"reason,omitempty"`
+}
+
+func NewRefundCommandEventPayload(refund Refund) RefundCommandEventPayload {
+	return RefundCommandEventPayload{
+		RefundID:     refund.ID,
+		PaymentID:    refund.PaymentID,
+		WalletID:     refund.WalletID,
+		Amount:       refund.Amount,
+		WalletAmount: refund.WalletAmount,
+		Status:       refund.Status,
+	}
+}
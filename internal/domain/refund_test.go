@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func convertedPayment() RefundablePayment {
+	return RefundablePayment{
+		ID:           "p-1",
+		WalletID:     "w-1",
+		Amount:       NewMoney(100_00, "EUR"),
+		WalletAmount: NewMoney(108_01, "USD"),
+		Status:       PaymentStatusCompleted,
+	}
+}
+
+func TestNewRefund(t *testing.T) {
+	payment := convertedPayment()
+
+	tests := []struct {
+		name          string
+		status        PaymentStatus
+		refunds       []Refund
+		amount        Money
+		expected      Money
+		expectedError error
+	}{
+		{name: "full refund", amount: NewMoney(100_00, "EUR"), expected: NewMoney(108_01, "USD")},
+		{name: "partial refund is prorated", amount: NewMoney(33_33, "EUR"), expected: NewMoney(36_00, "USD")},
+		{
+			name: "last refund takes what is left",
+			refunds: []Refund{
+				{Amount: NewMoney(33_33, "EUR"), WalletAmount: NewMoney(36_00, "USD"), Status: RefundStatusCompleted},
+				{Amount: NewMoney(33_33, "EUR"), WalletAmount: NewMoney(36_00, "USD"), Status: RefundStatusPending},
+			},
+			amount:   NewMoney(33_34, "EUR"),
+			expected: NewMoney(36_01, "USD"),
+		},
+		{
+			name:     "failed refunds do not count",
+			refunds:  []Refund{{Amount: NewMoney(100_00, "EUR"), WalletAmount: NewMoney(108_01, "USD"), Status: RefundStatusFailed}},
+			amount:   NewMoney(100_00, "EUR"),
+			expected: NewMoney(108_01, "USD"),
+		},
+		{
+			name:          "refunds with a failed credit count",
+			refunds:       []Refund{{Amount: NewMoney(100_00, "EUR"), WalletAmount: NewMoney(108_01, "USD"), Status: RefundStatusCreditFailed}},
+			amount:        NewMoney(1_00, "EUR"),
+			expectedError: ErrRefundExceedsPayment,
+		},
+		{
+			name:          "more than what is left",
+			refunds:       []Refund{{Amount: NewMoney(80_00, "EUR"), WalletAmount: NewMoney(86_41, "USD"), Status: RefundStatusCompleted}},
+			amount:        NewMoney(20_01, "EUR"),
+			expectedError: ErrRefundExceedsPayment,
+		},
+		{name: "pending payment", status: PaymentStatusPending, amount: NewMoney(1_00, "EUR"), expectedError: ErrPaymentNotRefundable},
+		{name: "refunded payment", status: PaymentStatusRefunded, amount: NewMoney(1_00, "EUR"), expectedError: ErrPaymentNotRefundable},
+		{name: "other currency", amount: NewMoney(1_00, "USD"), expectedError: ErrCurrencyMismatch},
+		{name: "zero amount", amount: NewMoney(0, "EUR"), expectedError: ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := payment
+			if tt.status != "" {
+				p.Status = tt.status
+			}
+
+			refund, err := NewRefund(p, tt.refunds, tt.amount)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.amount, refund.Amount)
+			assert.Equal(t, tt.expected, refund.WalletAmount)
+			assert.Equal(t, RefundStatusPending, refund.Status)
+			assert.Equal(t, "w-1", refund.WalletID)
+		})
+	}
+}
+
+func TestRefundedStatus(t *testing.T) {
+	payment := convertedPayment()
+	partial := Refund{Amount: NewMoney(40_00, "EUR"), Status: RefundStatusCompleted}
+	rest := Refund{Amount: NewMoney(60_00, "EUR"), Status: RefundStatusCompleted}
+	pending := Refund{Amount: NewMoney(60_00, "EUR"), Status: RefundStatusPending}
+
+	assert.Equal(t, PaymentStatusCompleted, RefundedStatus(payment, nil))
+	assert.Equal(t, PaymentStatusPartiallyRefunded, RefundedStatus(payment, []Refund{partial}))
+	assert.Equal(t, PaymentStatusPartiallyRefunded, RefundedStatus(payment, []Refund{partial, pending}))
+	assert.Equal(t, PaymentStatusRefunded, RefundedStatus(payment, []Refund{partial, rest}))
+}
@@ -0,0 +1,187 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrSagaStateNotFound = errors.New("saga state not found")
+
+// SagaStateRepository keeps the data the orchestrator needs between saga steps,
+// since the events exchanged with the services only carry part of it.
+type SagaStateRepository interface {
+	Save(state SagaState) error
+	GetByPaymentID(paymentId string) (SagaState, error)
+	// ListExpired returns the sagas whose step deadline is before now.
+	ListExpired(now time.Time) ([]SagaState, error)
+}
+
+type SagaState struct {
+	PaymentID string
+	WalletID  string
+	Amount    Money
+	// WalletAmount is what the wallet commands hold, release and debit.
+	WalletAmount Money
+	Token        string
+	Method       string
+	SagaProgress
+}
+
+// SagaProgress is where a saga instance stands. The saga engine keeps it, so it
+// is shared by every saga state.
+type SagaProgress struct {
+	Step SagaStep
+	// Compensating is set once the saga undoes its completed steps.
+	Compensating bool
+	// CancelRequested makes the saga compensate once the step in flight answers.
+	CancelRequested bool
+	// Reason explains why the saga failed.
+	Reason string
+	// LastEvent is the last event that moved the saga, so a redelivered one
+	// resends the commands it issued instead of being dropped.
+	LastEvent string
+	// Ended is set once the action of the end step ran, so the saga ignores
+	// every later event, including a redelivery of the one that ended it.
+	Ended bool
+	// Deadline is when the step in flight times out, nil if it waits forever.
+	Deadline  *time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (p *SagaProgress) Progress() *SagaProgress {
+	return p
+}
+
+func (p *SagaProgress) SetStep(step SagaStep) {
+	p.Step = step
+	p.UpdatedAt = time.Now().UTC()
+}
+
+// Expired reports whether the step in flight is past its deadline.
+func (p *SagaProgress) Expired(now time.Time) bool {
+	return p.Deadline != nil && p.Deadline.Before(now)
+}
+
+const SagaTimedOutEventType = "saga_timed_out"
+
+// SagaTimedOutEvent reports a saga step that was not answered before its
+// deadline. The saga handles it as a failure of the step.
+type SagaTimedOutEvent struct {
+	CommandEvent
+	SagaTimedOutEventPayload `json:"payload"`
+}
+
+type SagaTimedOutEventPayload struct {
+	Saga     string    `json:"saga"`
+	SagaID   string    `json:"saga_id"`
+	Step     SagaStep  `json:"step"`
+	Deadline time.Time `json:"deadline"`
+}
+
+type SagaStep string
+
+const (
+	SagaStepHoldingFunds   SagaStep = "HOLDING_FUNDS"
+	SagaStepAuthorizing    SagaStep = "AUTHORIZING"
+	SagaStepCapturing      SagaStep = "CAPTURING"
+	SagaStepDebiting       SagaStep = "DEBITING"
+	SagaStepCompleting     SagaStep = "COMPLETING"
+	SagaStepCompleted      SagaStep = "COMPLETED"
+	SagaStepReleasingFunds SagaStep = "RELEASING_FUNDS"
+	SagaStepVoidingAuth    SagaStep = "VOIDING_AUTHORIZATION"
+	SagaStepRefundingAuth  SagaStep = "REFUNDING_AUTHORIZATION"
+	SagaStepFailed         SagaStep = "FAILED"
+	SagaStepCanceled       SagaStep = "CANCELED"
+	SagaStepRequiresReview SagaStep = "REQUIRES_REVIEW"
+)
+
+// Cancelable reports whether the payment saga can still be aborted without
+// giving money back: the gateway was not asked to capture the payment yet and
+// the saga is not already winding down. The cancel API and the payment saga
+// both check it.
+func (s SagaState) Cancelable() error {
+	switch s.Step {
+	case SagaStepHoldingFunds, SagaStepAuthorizing:
+		return nil
+	case SagaStepDebiting, SagaStepCompleting, SagaStepCompleted:
+		return ErrPaymentAlreadyDebited
+	}
+	// A capture in flight may already have collected the payment.
+	return ErrPaymentNotCancelable
+}
+
+func (s *SagaState) SagaID() string {
+	return s.PaymentID
+}
+
+func NewSagaState(event PaymentCreatedEvent) SagaState {
+	now := time.Now().UTC()
+	state := SagaState{
+		PaymentID:    event.PaymentID,
+		WalletID:     event.WalletID,
+		Amount:       event.Amount,
+		WalletAmount: event.WalletAmount,
+		Token:        event.Token,
+		Method:       event.Method,
+		SagaProgress: SagaProgress{
+			Step:      SagaStepHoldingFunds,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+	// Events created before FX conversion carry no wallet amount.
+	if state.WalletAmount.Currency == "" {
+		state.WalletAmount = event.Amount
+	}
+	return state
+}
+
+var ErrRefundSagaStateNotFound = errors.New("refund saga state not found")
+
+// RefundSagaStateRepository keeps the refund saga states, keyed by refund.
+type RefundSagaStateRepository interface {
+	Save(state RefundSagaState) error
+	GetByRefundID(refundId string) (RefundSagaState, error)
+	// ListExpired returns the sagas whose step deadline is before now.
+	ListExpired(now time.Time) ([]RefundSagaState, error)
+}
+
+type RefundSagaState struct {
+	RefundID     string
+	PaymentID    string
+	WalletID     string
+	Amount       Money
+	WalletAmount Money
+	Method       string
+	SagaProgress
+}
+
+const (
+	RefundSagaStepRefundingGateway SagaStep = "REFUNDING_GATEWAY"
+	RefundSagaStepCreditingWallet  SagaStep = "CREDITING_WALLET"
+	RefundSagaStepCompleting       SagaStep = "COMPLETING"
+	RefundSagaStepCompleted        SagaStep = "COMPLETED"
+	RefundSagaStepFailed           SagaStep = "FAILED"
+)
+
+func (s *RefundSagaState) SagaID() string {
+	return s.RefundID
+}
+
+func NewRefundSagaState(event RefundCommandEvent) RefundSagaState {
+	now := time.Now().UTC()
+	return RefundSagaState{
+		RefundID:     event.RefundID,
+		PaymentID:    event.PaymentID,
+		WalletID:     event.WalletID,
+		Amount:       event.Amount,
+		WalletAmount: event.WalletAmount,
+		Method:       event.Method,
+		SagaProgress: SagaProgress{
+			Step:      RefundSagaStepRefundingGateway,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+	}
+}
@@ -0,0 +1,241 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Services publishing and subscribing to topics. Consumers subscribe with
+// their service name.
+const (
+	ServiceAPI          = "api"
+	ServiceOrchestrator = "orchestrator"
+	ServiceWallet       = "wallet_consumer"
+	ServicePayment      = "payment_consumer"
+	ServiceGateway      = "gateway_consumer"
+	ServiceNotification = "notification_consumer"
+	ServiceRefund       = "refund_consumer"
+	ServiceMetrics      = "metrics_consumer"
+)
+
+// Topics published by the API through the outbox.
+const (
+	TopicPaymentCreated = "payment.created"
+	// TopicPaymentCancelRequested asks the orchestrator to abort the saga of a payment.
+	TopicPaymentCancelRequested = "payment.cancel_requested"
+	TopicRefundCreated          = "refund.created"
+)
+
+// Topics the orchestrator sends its commands on.
+const (
+	TopicOrchestratorWallet       = "orchestrator.wallet"
+	TopicOrchestratorPayment      = "orchestrator.payment"
+	TopicOrchestratorRefund       = "orchestrator.refund"
+	TopicOrchestratorGateway      = "orchestrator.gateway"
+	TopicOrchestratorNotification = "orchestrator.notification"
+	// TopicPaymentSagaTimedOut is published by the saga sweeper when a payment
+	// saga step was not answered in time.
+	TopicPaymentSagaTimedOut = "saga.payment.timed_out"
+	TopicMetrics             = "metrics"
+)
+
+// Topics the services answer the orchestrator commands on.
+const (
+	TopicWalletFunds           = "wallet.hold_funds"
+	TopicWalletHoldFundsFailed = "wallet.hold_funds_failed"
+	TopicWalletDebitFunds      = "wallet.debit_funds"
+	TopicWalletDebitFailed     = "wallet.debit_failure"
+	TopicWalletFundsReleased   = "wallet.funds_released"
+	TopicWalletFundsCredited   = "wallet.credit"
+	TopicWalletCreditFailed    = "wallet.credit_failure"
+
+	TopicGatewayAuthorized          = "gateway.authorized"
+	TopicGatewayAuthorizationFailed = "gateway.authorization_failed"
+	TopicGatewayRefunded            = "gateway.refunded"
+	TopicGatewayRefundFailed        = "gateway.refund_failed"
+
+	// The gateway answers these to the capture of an authorized payment.
+	TopicGatewayCaptured      = "gateway.captured"
+	TopicGatewayCaptureFailed = "gateway.capture_failed"
+
+	// The gateway answers these when the saga refunds the capture of a
+	// payment it could not debit.
+	TopicGatewayAuthorizationRefunded     = "gateway.authorization_refunded"
+	TopicGatewayAuthorizationRefundFailed = "gateway.authorization_refund_failed"
+
+	TopicPaymentCompleted = "payment.completed"
+	TopicPaymentFailed    = "payment.failed"
+	TopicPaymentCanceled  = "payment.canceled"
+
+	// TopicRefundCompleted is published by the refunds service once the
+	// refund and its payment are updated.
+	TopicRefundCompleted = "refund.completed"
+)
+
+// Former names of renamed topics. Nothing publishes on them anymore, but the
+// messages published before the rename may still wait on them, see
+// TopicRoute.RenamedTo.
+const (
+	TopicRefundRequestedV1 = "refund.requested"
+	TopicPaymentRefundedV1 = "payment.refunded"
+)
+
+var (
+	ErrUnregisteredTopic = errors.New("topic is not registered")
+	ErrTopologyMismatch  = errors.New("topic subscriptions do not match the registry")
+)
+
+// TopicRoute is a topic with the services publishing to it and the ones
+// subscribed to it.
+type TopicRoute struct {
+	Topic       string
+	Publishers  []string
+	Subscribers []string
+	// External topics are consumed outside this system, so they need no
+	// subscriber here.
+	External bool
+	// RenamedTo is the current name of a renamed topic. Its subscribers drain
+	// what was published on it before the rename and handle it as RenamedTo,
+	// so it needs no publisher.
+	RenamedTo string
+}
+
+// TopicRegistry lists every topic exchanged between the services. A topic
+// missing here cannot be published, see CheckTopology.
+var TopicRegistry = []TopicRoute{
+	{Topic: TopicPaymentCreated, Publishers: []string{ServiceAPI}, Subscribers: []string{ServiceOrchestrator, ServiceRefund}},
+	{Topic: TopicPaymentCancelRequested, Publishers: []string{ServiceAPI}, Subscribers: []string{ServiceOrchestrator}},
+	{Topic: TopicRefundCreated, Publishers: []string{ServiceAPI}, Subscribers: []string{ServiceOrchestrator}},
+
+	{Topic: TopicOrchestratorWallet, Publishers: []string{ServiceOrchestrator}, Subscribers: []string{ServiceWallet}},
+	// The refunds service sends the payment service the status its refunds
+	// move the payment to.
+	{Topic: TopicOrchestratorPayment, Publishers: []string{ServiceOrchestrator, ServiceRefund}, Subscribers: []string{ServicePayment}},
+	// The payment service forwards the refund commands it got before refunds
+	// had their own service.
+	{Topic: TopicOrchestratorRefund, Publishers: []string{ServiceOrchestrator, ServicePayment}, Subscribers: []string{ServiceRefund}},
+	{Topic: TopicOrchestratorGateway, Publishers: []string{ServiceOrchestrator}, Subscribers: []string{ServiceGateway}},
+	{Topic: TopicOrchestratorNotification, Publishers: []string{ServiceOrchestrator}, Subscribers: []string{ServiceNotification}},
+	{Topic: TopicPaymentSagaTimedOut, Publishers: []string{ServiceOrchestrator}, Subscribers: []string{ServiceOrchestrator}},
+	{Topic: TopicMetrics, Publishers: []string{ServiceOrchestrator, ServiceWallet, ServiceGateway}, Subscribers: []string{ServiceMetrics}},
+
+	{Topic: TopicWalletFunds, Publishers: []string{ServiceWallet}, Subscribers: []string{ServiceOrchestrator}},
+	{Topic: TopicWalletHoldFundsFailed, Publishers: []string{ServiceWallet}, Subscribers: []string{ServiceOrchestrator}},
+	{Topic: TopicWalletDebitFunds, Publishers: []string{ServiceWallet}, Subscribers: []string{ServiceOrchestrator}},
+	{Topic: TopicWalletDebitFailed, Publishers: []string{ServiceWallet}, Subscribers: []string{ServiceOrchestrator}},
+	{Topic: TopicWalletFundsReleased, Publishers: []string{ServiceWallet}, Subscribers: []string{ServiceOrchestrator}},
+	{Topic: TopicWalletFundsCredited, Publishers: []string{ServiceWallet}, Subscribers: []string{ServiceOrchestrator}},
+	{Topic: TopicWalletCreditFailed, Publishers: []string{ServiceWallet}, Subscribers: []string{ServiceOrchestrator}},
+
+	// The API publishes the gateway answers too, from the provider callbacks.
+	{Topic: TopicGatewayAuthorized, Publishers: []string{ServiceGateway, ServiceAPI}, Subscribers: []string{ServiceOrchestrator}},
+	{Topic: TopicGatewayAuthorizationFailed, Publishers: []string{ServiceGateway, ServiceAPI}, Subscribers: []string{ServiceOrchestrator}},
+	{Topic: TopicGatewayRefunded, Publishers: []string{ServiceGateway, ServiceAPI}, Subscribers: []string{ServiceOrchestrator}},
+	{Topic: TopicGatewayRefundFailed, Publishers: []string{ServiceGateway, ServiceAPI}, Subscribers: []string{ServiceOrchestrator}},
+	{Topic: TopicGatewayCaptured, Publishers: []string{ServiceGateway, ServiceAPI}, Subscribers: []string{ServiceOrchestrator}},
+	{Topic: TopicGatewayCaptureFailed, Publishers: []string{ServiceGateway, ServiceAPI}, Subscribers: []string{ServiceOrchestrator}},
+	{Topic: TopicGatewayAuthorizationRefunded, Publishers: []string{ServiceGateway, ServiceAPI}, Subscribers: []string{ServiceOrchestrator}},
+	{Topic: TopicGatewayAuthorizationRefundFailed, Publishers: []string{ServiceGateway, ServiceAPI}, Subscribers: []string{ServiceOrchestrator}},
+
+	{Topic: TopicPaymentCompleted, Publishers: []string{ServicePayment}, Subscribers: []string{ServiceOrchestrator, ServiceRefund}},
+	{Topic: TopicPaymentFailed, Publishers: []string{ServicePayment}, Subscribers: []string{ServiceNotification}},
+	{Topic: TopicPaymentCanceled, Publishers: []string{ServicePayment}, Subscribers: []string{ServiceNotification}},
+	{Topic: TopicRefundCompleted, Publishers: []string{ServiceRefund}, Subscribers: []string{ServiceOrchestrator}},
+
+	{Topic: TopicRefundRequestedV1, RenamedTo: TopicRefundCreated, Subscribers: []string{ServiceOrchestrator}},
+	{Topic: TopicPaymentRefundedV1, RenamedTo: TopicRefundCompleted, Subscribers: []string{ServiceOrchestrator}},
+}
+
+// CurrentTopic returns the name topic has now, which is topic itself unless
+// it was renamed.
+func CurrentTopic(topic string) string {
+	if route, ok := LookupTopic(topic); ok && route.RenamedTo != "" {
+		return route.RenamedTo
+	}
+	return topic
+}
+
+// FormerTopics returns the names topic had before it was renamed.
+func FormerTopics(topic string) []string {
+	var former []string
+	for _, route := range TopicRegistry {
+		if route.RenamedTo == topic {
+			former = append(former, route.Topic)
+		}
+	}
+	return former
+}
+
+// LookupTopic returns the route of a registered topic.
+func LookupTopic(topic string) (TopicRoute, bool) {
+	return lookupTopic(TopicRegistry, topic)
+}
+
+func lookupTopic(registry []TopicRoute, topic string) (TopicRoute, bool) {
+	for _, route := range registry {
+		if route.Topic == topic {
+			return route, true
+		}
+	}
+	return TopicRoute{}, false
+}
+
+// CheckTopology compares the subscriptions made at startup, subscribers by
+// topic, with the registry. It fails when a topic is published but nobody
+// subscribes to it, when a subscription has no publisher, and when the
+// subscribers differ from the registered ones. Renamed topics need no
+// publisher, only their subscribers. A registered publisher only
+// counts when it runs, that is when it subscribes to some topic; the API
+// publishes without subscribing.
+func CheckTopology(subscriptions map[string][]string) error {
+	return checkTopology(TopicRegistry, subscriptions)
+}
+
+func checkTopology(registry []TopicRoute, subscriptions map[string][]string) error {
+	var errs []error
+	mismatch := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrTopologyMismatch}, args...)...))
+	}
+
+	running := map[string]bool{ServiceAPI: true}
+	for _, subscribers := range subscriptions {
+		for _, subscriber := range subscribers {
+			running[subscriber] = true
+		}
+	}
+
+	for _, route := range registry {
+		subscribers := slices.Sorted(slices.Values(subscriptions[route.Topic]))
+		subscribers = slices.Compact(subscribers)
+		registered := slices.Sorted(slices.Values(route.Subscribers))
+
+		switch {
+		case route.RenamedTo != "":
+		case len(route.Publishers) == 0:
+			mismatch("%s has no publisher", route.Topic)
+		case !slices.ContainsFunc(route.Publishers, func(service string) bool { return running[service] }):
+			mismatch("%s is published by %s, which do not run", route.Topic, strings.Join(route.Publishers, ", "))
+		case len(registered) == 0 && !route.External:
+			mismatch("%s is published by %s but has no subscriber", route.Topic, strings.Join(route.Publishers, ", "))
+		}
+		if !slices.Equal(subscribers, registered) {
+			mismatch("%s is subscribed by [%s], registered [%s]", route.Topic, strings.Join(subscribers, ", "), strings.Join(registered, ", "))
+		}
+	}
+
+	var unregistered []string
+	for topic := range subscriptions {
+		if _, ok := lookupTopic(registry, topic); !ok {
+			unregistered = append(unregistered, topic)
+		}
+	}
+	sort.Strings(unregistered)
+	for _, topic := range unregistered {
+		errs = append(errs, fmt.Errorf("%w: %s is subscribed but has no publisher", ErrUnregisteredTopic, topic))
+	}
+
+	return errors.Join(errs...)
+}
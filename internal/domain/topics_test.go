@@ -0,0 +1,129 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// registeredSubscriptions are the subscriptions the registry expects.
+func registeredSubscriptions() map[string][]string {
+	subscriptions := map[string][]string{}
+	for _, route := range TopicRegistry {
+		if len(route.Subscribers) > 0 {
+			subscriptions[route.Topic] = append([]string(nil), route.Subscribers...)
+		}
+	}
+	return subscriptions
+}
+
+func TestCheckTopology(t *testing.T) {
+	tests := []struct {
+		name          string
+		change        func(subscriptions map[string][]string)
+		expectedError error
+	}{
+		{
+			name:   "matches the registry",
+			change: func(map[string][]string) {},
+		},
+		{
+			name: "a topic subscribed twice by the same service",
+			change: func(subscriptions map[string][]string) {
+				subscriptions[TopicPaymentCreated] = append(subscriptions[TopicPaymentCreated], ServiceOrchestrator)
+			},
+		},
+		{
+			name: "published topic nobody subscribes to",
+			change: func(subscriptions map[string][]string) {
+				delete(subscriptions, TopicPaymentFailed)
+			},
+			expectedError: ErrTopologyMismatch,
+		},
+		{
+			name: "subscriber missing from the registry",
+			change: func(subscriptions map[string][]string) {
+				subscriptions[TopicPaymentCompleted] = append(subscriptions[TopicPaymentCompleted], ServiceNotification)
+			},
+			expectedError: ErrTopologyMismatch,
+		},
+		{
+			name: "subscription to a topic nobody publishes",
+			change: func(subscriptions map[string][]string) {
+				subscriptions["wallet.release_funds"] = []string{ServiceOrchestrator}
+			},
+			expectedError: ErrUnregisteredTopic,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subscriptions := registeredSubscriptions()
+			tt.change(subscriptions)
+
+			err := CheckTopology(subscriptions)
+
+			if tt.expectedError == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.expectedError)
+		})
+	}
+}
+
+func TestCheckTopology_UnusedTopics(t *testing.T) {
+	tests := []struct {
+		name  string
+		route TopicRoute
+	}{
+		{
+			name:  "external topic without publisher",
+			route: TopicRoute{Topic: "audit", External: true},
+		},
+		{
+			name:  "external topic only published by a service that does not run",
+			route: TopicRoute{Topic: "audit", Publishers: []string{"ledger_consumer"}, External: true},
+		},
+		{
+			name:  "topic without subscriber",
+			route: TopicRoute{Topic: "audit", Publishers: []string{ServiceAPI}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := []TopicRoute{
+				{Topic: TopicPaymentCreated, Publishers: []string{ServiceAPI}, Subscribers: []string{ServiceOrchestrator}},
+				tt.route,
+			}
+
+			err := checkTopology(registry, map[string][]string{TopicPaymentCreated: {ServiceOrchestrator}})
+
+			assert.ErrorIs(t, err, ErrTopologyMismatch)
+		})
+	}
+}
+
+func TestTopicRegistry_EveryTopicIsPublished(t *testing.T) {
+	seen := map[string]bool{}
+	for _, route := range TopicRegistry {
+		assert.False(t, seen[route.Topic], "%s registered twice", route.Topic)
+		seen[route.Topic] = true
+		if route.RenamedTo == "" {
+			assert.NotEmpty(t, route.Publishers, route.Topic)
+			continue
+		}
+		renamed, ok := LookupTopic(route.RenamedTo)
+		assert.True(t, ok, route.RenamedTo)
+		assert.Equal(t, route.Subscribers, renamed.Subscribers, route.Topic)
+	}
+}
+
+func TestCurrentTopic(t *testing.T) {
+	assert.Equal(t, TopicRefundCreated, CurrentTopic(TopicRefundRequestedV1))
+	assert.Equal(t, TopicRefundCompleted, CurrentTopic(TopicPaymentRefundedV1))
+	assert.Equal(t, TopicRefundCreated, CurrentTopic(TopicRefundCreated))
+	assert.Equal(t, []string{TopicPaymentRefundedV1}, FormerTopics(TopicRefundCompleted))
+	assert.Empty(t, FormerTopics(TopicPaymentCreated))
+}
@@ -1,8 +1,254 @@
 package domain
 
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrWalletNotFound          = errors.New("wallet not found")
+	ErrWalletAlreadyExists     = errors.New("wallet already exists")
+	ErrWalletVersionConflict   = errors.New("wallet was modified concurrently")
+	ErrInsufficientFunds       = errors.New("insufficient funds")
+	ErrInvalidAmount           = errors.New("amount must be greater than zero")
+	ErrHoldNotFound            = errors.New("hold not found")
+	ErrHoldAlreadyExists       = errors.New("hold already exists for another wallet or amount")
+	ErrHoldNotActive           = errors.New("hold is not active")
+	ErrLedgerTransactionExists = errors.New("ledger transaction already recorded")
+	ErrSameWallet              = errors.New("origin and destination accounts must be different")
+	ErrTransactionNotFound     = errors.New("transaction not found")
+	ErrTransactionReverted     = errors.New("transaction already reverted")
+)
+
+type WalletRepository interface {
+	Create(wallet Wallet) error
+	GetByID(id string) (Wallet, error)
+	GetHold(id string) (Hold, error)
+	GetTransaction(id string) (Transaction, error)
+	HasLedgerTransaction(id string) (bool, error)
+	// ListLedgerEntries returns the entries posted to the accounts of a wallet, oldest first.
+	ListLedgerEntries(walletId string) ([]LedgerEntry, error)
+	// Save writes the change atomically, only when every stored wallet is still
+	// at the version of change.Wallets; otherwise it returns ErrWalletVersionConflict.
+	Save(change WalletChange) error
+}
+
+// WalletChange is everything a wallet operation writes: the new balances, the
+// hold or transaction it touched (if any) and the ledger entries that explain
+// the movement.
+type WalletChange struct {
+	Wallets     []Wallet
+	Hold        *Hold
+	Transaction *Transaction
+	Entries     []LedgerEntry
+}
+
+// Wallet balances are split in funds the owner can use and funds reserved by
+// holds. Both are mirrored by ledger accounts, see LedgerAccountAvailable and
+// LedgerAccountHeld.
+type Wallet struct {
+	ID        string
+	Currency  string
+	Available Money
+	Held      Money
+	Version   int
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+}
+
+func NewWallet(id, currency string) Wallet {
+	return Wallet{
+		ID:        id,
+		Currency:  currency,
+		Available: NewMoney(0, currency),
+		Held:      NewMoney(0, currency),
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// Hold moves amount from the available to the held balance.
+func (w *Wallet) Hold(amount Money) error {
+	if err := w.Withdraw(amount); err != nil {
+		return err
+	}
+	held, err := w.Held.Add(amount)
+	if err != nil {
+		return err
+	}
+	w.Held = held
+	return nil
+}
+
+// ReleaseHeld moves amount from the held back to the available balance.
+func (w *Wallet) ReleaseHeld(amount Money) error {
+	if err := w.DebitHeld(amount); err != nil {
+		return err
+	}
+	return w.Credit(amount)
+}
+
+// DebitHeld takes amount out of the held balance.
+func (w *Wallet) DebitHeld(amount Money) error {
+	held, err := w.Held.Sub(amount)
+	if err != nil {
+		return err
+	}
+	w.Held = held
+	return nil
+}
+
+func (w *Wallet) Credit(amount Money) error {
+	available, err := w.Available.Add(amount)
+	if err != nil {
+		return err
+	}
+	w.Available = available
+	return nil
+}
+
+// Withdraw takes amount out of the available balance, which never goes negative.
+func (w *Wallet) Withdraw(amount Money) error {
+	insufficient, err := w.Available.LessThan(amount)
+	if err != nil {
+		return err
+	}
+	if insufficient {
+		return ErrInsufficientFunds
+	}
+	w.Available, _ = w.Available.Sub(amount)
+	return nil
+}
+
+func (w *Wallet) SetUpdatedAt() {
+	now := time.Now().UTC()
+	w.UpdatedAt = &now
+}
+
+type HoldStatus string
+
 const (
-	TopicWalletFunds           = "wallet.hold_funds"
-	TopicWalletDebitFunds      = "wallet.debit_funds"
-	TopicWalletHoldFundsFailed = "wallet.hold_funds_failed"
-	TopicWalletFundsReleased   = "wallet.funds_released"
+	HoldStatusActive   HoldStatus = "ACTIVE"
+	HoldStatusReleased HoldStatus = "RELEASED"
+	HoldStatusDebited  HoldStatus = "DEBITED"
 )
+
+// Hold reserves funds of a wallet until they are debited or released.
+type Hold struct {
+	ID        string
+	WalletID  string
+	Amount    Money
+	Reason    string
+	Status    HoldStatus
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+}
+
+func NewHold(id, walletId string, amount Money, reason string) Hold {
+	return Hold{
+		ID:        id,
+		WalletID:  walletId,
+		Amount:    amount,
+		Reason:    reason,
+		Status:    HoldStatusActive,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+func (h *Hold) SetStatus(status HoldStatus) {
+	h.Status = status
+	now := time.Now().UTC()
+	h.UpdatedAt = &now
+}
+
+type TransactionStatus string
+
+const (
+	TransactionStatusCompleted TransactionStatus = "COMPLETED"
+	TransactionStatusReverted  TransactionStatus = "REVERTED"
+)
+
+// Transaction moves available funds from one wallet to another.
+type Transaction struct {
+	ID                  string
+	OriginWalletID      string
+	DestinationWalletID string
+	Amount              Money
+	Status              TransactionStatus
+	CreatedAt           time.Time
+	RevertedAt          *time.Time
+}
+
+func NewTransaction(originWalletId, destinationWalletId string, amount Money) Transaction {
+	return Transaction{
+		ID:                  uuid.NewString(),
+		OriginWalletID:      originWalletId,
+		DestinationWalletID: destinationWalletId,
+		Amount:              amount,
+		Status:              TransactionStatusCompleted,
+		CreatedAt:           time.Now().UTC(),
+	}
+}
+
+func (t *Transaction) Revert() {
+	now := time.Now().UTC()
+	t.Status = TransactionStatusReverted
+	t.RevertedAt = &now
+}
+
+type LedgerDirection string
+
+const (
+	LedgerDebit  LedgerDirection = "DEBIT"
+	LedgerCredit LedgerDirection = "CREDIT"
+)
+
+const (
+	// LedgerAccountFunding is the counterpart of money entering the wallets.
+	LedgerAccountFunding = "external:funding"
+	// LedgerAccountSettlement is the counterpart of money leaving the wallets.
+	LedgerAccountSettlement = "external:settlement"
+)
+
+func LedgerAccountAvailable(walletId string) string {
+	return "wallet:" + walletId + ":available"
+}
+
+func LedgerAccountHeld(walletId string) string {
+	return "wallet:" + walletId + ":held"
+}
+
+// LedgerEntry is one side of a ledger transaction. Every transaction has a debit
+// and a credit of the same amount, so the ledger always balances.
+type LedgerEntry struct {
+	ID            string
+	TransactionID string
+	Account       string
+	Direction     LedgerDirection
+	Amount        Money
+	CreatedAt     time.Time
+}
+
+// NewLedgerTransfer moves amount from one account to another as a balanced pair of entries.
+func NewLedgerTransfer(transactionId, from, to string, amount Money) []LedgerEntry {
+	now := time.Now().UTC()
+	return []LedgerEntry{
+		{
+			ID:            uuid.Must(uuid.NewV7()).String(),
+			TransactionID: transactionId,
+			Account:       from,
+			Direction:     LedgerDebit,
+			Amount:        amount,
+			CreatedAt:     now,
+		},
+		{
+			ID:            uuid.Must(uuid.NewV7()).String(),
+			TransactionID: transactionId,
+			Account:       to,
+			Direction:     LedgerCredit,
+			Amount:        amount,
+			CreatedAt:     now,
+		},
+	}
+}
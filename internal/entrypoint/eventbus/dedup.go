@@ -0,0 +1,84 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/eventbus"
+)
+
+// Deduplicator drops messages a subscriber already processed, using the
+// MessageDeduplicationId of the event metadata. Processed ids are kept in a
+// durable store, so duplicates redelivered after a restart are detected too.
+type Deduplicator struct {
+	processed domain.ProcessedMessageStore
+
+	mu   sync.Mutex
+	hits map[string]*atomic.Int64
+}
+
+func NewDeduplicator(processed domain.ProcessedMessageStore) *Deduplicator {
+	return &Deduplicator{
+		processed: processed,
+		hits:      make(map[string]*atomic.Int64),
+	}
+}
+
+// Wrap returns a handler that runs next at most once per deduplication id for
+// subscriber. Messages without a deduplication id are always handled. When next
+// fails the id is released, so the redelivery is processed.
+func (d *Deduplicator) Wrap(subscriber string, next eventbus.HandlerFunc) eventbus.HandlerFunc {
+	return func(ctx context.Context, msg []byte) error {
+		var genericEvent domain.CommandEvent
+		if err := json.Unmarshal(msg, &genericEvent); err != nil || genericEvent.MessageDeduplicationId == "" {
+			return next(ctx, msg)
+		}
+
+		// The event type is part of the key because some consumers forward the
+		// metadata of the command they handled.
+		key := subscriber + "|" + genericEvent.EventType + "|" + genericEvent.MessageDeduplicationId
+		if err := d.processed.Claim(key); err != nil {
+			if !errors.Is(err, domain.ErrAlreadyProcessed) {
+				return err
+			}
+			d.counter(subscriber).Add(1)
+			log.Printf("[Dedup] '%s' dropped duplicate %s of %s", subscriber, genericEvent.MessageDeduplicationId, genericEvent.EventType)
+			return nil
+		}
+
+		if err := next(ctx, msg); err != nil {
+			if releaseErr := d.processed.Release(key); releaseErr != nil {
+				log.Printf("[Dedup] '%s' could not release %s: %v", subscriber, key, releaseErr)
+			}
+			return err
+		}
+
+		// The message was handled; failing here would only redeliver it.
+		if err := d.processed.Complete(key); err != nil {
+			log.Printf("[Dedup] '%s' could not mark %s processed: %v", subscriber, key, err)
+		}
+		return nil
+	}
+}
+
+// Hits returns how many duplicates were dropped for subscriber.
+func (d *Deduplicator) Hits(subscriber string) int64 {
+	return d.counter(subscriber).Load()
+}
+
+func (d *Deduplicator) counter(subscriber string) *atomic.Int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	c, ok := d.hits[subscriber]
+	if !ok {
+		c = &atomic.Int64{}
+		d.hits[subscriber] = c
+	}
+	return c
+}
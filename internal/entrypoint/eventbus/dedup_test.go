@@ -0,0 +1,136 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dedupMessage(t *testing.T, eventType, dedupId string) []byte {
+	msg, err := json.Marshal(domain.CommandEvent{
+		EventType: eventType,
+		CommandEventMetadata: domain.CommandEventMetadata{
+			MessageDeduplicationId: dedupId,
+		},
+	})
+	assert.NoError(t, err)
+	return msg
+}
+
+func TestDeduplicator_Wrap(t *testing.T) {
+	handlerErr := errors.New("handler error")
+
+	tests := []struct {
+		name          string
+		messages      [][]byte
+		failFirst     bool
+		expectedCalls int
+		expectedHits  int64
+	}{
+		{
+			name: "drops a redelivered message",
+			messages: [][]byte{
+				dedupMessage(t, domain.DebitFundsEventType, "debit_funds.p-1"),
+				dedupMessage(t, domain.DebitFundsEventType, "debit_funds.p-1"),
+			},
+			expectedCalls: 1,
+			expectedHits:  1,
+		},
+		{
+			name: "handles different ids and event types",
+			messages: [][]byte{
+				dedupMessage(t, domain.DebitFundsEventType, "debit_funds.p-1"),
+				dedupMessage(t, domain.DebitFundsEventType, "debit_funds.p-2"),
+				dedupMessage(t, "wallet.debit_funds", "debit_funds.p-1"),
+			},
+			expectedCalls: 3,
+		},
+		{
+			name: "handles messages without deduplication id",
+			messages: [][]byte{
+				dedupMessage(t, "gateway.authorized", ""),
+				dedupMessage(t, "gateway.authorized", ""),
+			},
+			expectedCalls: 2,
+		},
+		{
+			name: "processes the redelivery of a failed message",
+			messages: [][]byte{
+				dedupMessage(t, domain.DebitFundsEventType, "debit_funds.p-1"),
+				dedupMessage(t, domain.DebitFundsEventType, "debit_funds.p-1"),
+			},
+			failFirst:     true,
+			expectedCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dedup := NewDeduplicator(database.NewProcessedMessageRepository(database.OpenInMemory(), time.Minute))
+
+			calls := 0
+			handler := dedup.Wrap("wallet_consumer", func(ctx context.Context, msg []byte) error {
+				calls++
+				if tt.failFirst && calls == 1 {
+					return handlerErr
+				}
+				return nil
+			})
+
+			for _, msg := range tt.messages {
+				_ = handler(context.Background(), msg)
+			}
+
+			assert.Equal(t, tt.expectedCalls, calls)
+			assert.Equal(t, tt.expectedHits, dedup.Hits("wallet_consumer"))
+		})
+	}
+}
+
+func TestDeduplicator_IsolatesSubscribers(t *testing.T) {
+	dedup := NewDeduplicator(database.NewProcessedMessageRepository(database.OpenInMemory(), time.Minute))
+	msg := dedupMessage(t, domain.NotifyUserEventType, "notify_user.p-1.payment_success")
+
+	calls := 0
+	handler := func(ctx context.Context, msg []byte) error {
+		calls++
+		return nil
+	}
+
+	assert.NoError(t, dedup.Wrap("a", handler)(context.Background(), msg))
+	assert.NoError(t, dedup.Wrap("b", handler)(context.Background(), msg))
+
+	assert.Equal(t, 2, calls)
+	assert.Zero(t, dedup.Hits("a"))
+}
+
+func TestDeduplicator_DropsRedeliveryAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golearn.db")
+	msg := dedupMessage(t, domain.DebitFundsEventType, "debit_funds.p-1")
+
+	calls := 0
+	handler := func(ctx context.Context, msg []byte) error {
+		calls++
+		return nil
+	}
+
+	db, err := database.Open(path)
+	require.NoError(t, err)
+	assert.NoError(t, NewDeduplicator(database.NewProcessedMessageRepository(db, time.Minute)).Wrap("wallet_consumer", handler)(context.Background(), msg))
+
+	reopened, err := database.Open(path)
+	require.NoError(t, err)
+	dedup := NewDeduplicator(database.NewProcessedMessageRepository(reopened, time.Minute))
+	assert.NoError(t, dedup.Wrap("wallet_consumer", handler)(context.Background(), msg))
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, int64(1), dedup.Hits("wallet_consumer"))
+}
@@ -2,95 +2,43 @@ package eventbus
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"log"
 
+	"github.com/mmarias/golearn/internal/app/orchestrator/saga"
 	"github.com/mmarias/golearn/internal/domain"
 	"github.com/mmarias/golearn/internal/infraestructure/eventbus"
 )
 
-func SetupSagaDispatcher(bus eventbus.Client, handler *OrchestratorSagaHandler) {
-	// The dispatcher is a single function that knows how to route events.
-	dispatcher := func(ctx context.Context, msg []byte) {
-		var genericEvent domain.CommandEvent
-		if err := json.Unmarshal(msg, &genericEvent); err != nil {
-			log.Printf("ERROR: could not unmarshal generic event: %v", err)
-			return
-		}
-
-		// Route events
-		switch genericEvent.EventType {
-		// Events that consume orchestrator from payment service
-		case domain.TopicPaymentCreated:
-			var ev domain.PaymentUpdateStatusEvent
-			if err := json.Unmarshal(msg, &ev); err != nil {
-				log.Printf("ERROR: could not unmarshal PaymentUpdateStatusEvent for %s: %v", domain.TopicPaymentCreated, err)
-				return
-			}
-			handler.HandlePaymentCreated(ctx, ev)
-		case domain.TopicPaymentCompleted:
-			var ev domain.PaymentUpdateStatusEvent
-			if err := json.Unmarshal(msg, &ev); err != nil {
-				log.Printf("ERROR: could not unmarshal PaymentUpdateStatusEvent for %s: %v", domain.TopicPaymentCompleted, err)
-				return
-			}
-			handler.HandlePaymentCompleted(ctx, ev)
+// OrchestratorSubscriber is the name the orchestrator subscribes with.
+const OrchestratorSubscriber = domain.ServiceOrchestrator
 
-		// Events that consume orchestrator from gateway service
-		case domain.TopicGatewayAuthorized:
-			var ev domain.GatewayAuthorizedEvent
-			if err := json.Unmarshal(msg, &ev); err != nil {
-				log.Printf("ERROR: could not unmarshal GatewayAuthorizedEvent for %s: %v", domain.TopicGatewayAuthorized, err)
-				return
-			}
-			handler.HandleGatewayAuthorized(ctx, ev)
-		case domain.TopicGatewayAuthorizationFailed:
-			var ev domain.GatewayAuthorizationFailedEvent
-			if err := json.Unmarshal(msg, &ev); err != nil {
-				log.Printf("ERROR: could not unmarshal GatewayAuthorizationFailedEvent for %s: %v", domain.TopicGatewayAuthorizationFailed, err)
-				return
-			}
-			handler.HandleGatewayAuthorizationFailed(ctx, ev)
+// Saga is a saga engine: it knows the topics it listens to and routes their
+// events to the saga they belong to.
+type Saga interface {
+	Name() string
+	Topics() []string
+	Handle(ctx context.Context, topic string, msg []byte) error
+}
 
-		// Events that consume orchestrator from wallet service
-		case domain.TopicWalletFunds:
-			var ev domain.WalletCommandEvent
-			if err := json.Unmarshal(msg, &ev); err != nil {
-				log.Printf("ERROR: could not unmarshal WalletCommandEvent for %s: %v", domain.TopicWalletFunds, err)
-				return
-			}
-			handler.HandleFundsHeld(ctx, ev)
-		case domain.TopicWalletDebitFunds:
-			var ev domain.WalletCommandEvent
-			if err := json.Unmarshal(msg, &ev); err != nil {
-				log.Printf("ERROR: could not unmarshal WalletCommandEvent for %s: %v", domain.TopicWalletDebitFunds, err)
-				return
-			}
-			handler.HandleFundsDebited(ctx, ev)
-		case domain.TopicWalletHoldFundsFailed:
-			var ev domain.WalletCommandEvent
-			if err := json.Unmarshal(msg, &ev); err != nil {
-				log.Printf("ERROR: could not unmarshal WalletCommandEvent for %s: %v", domain.TopicWalletHoldFundsFailed, err)
-				return
+// SetupSagaDispatcher subscribes every saga to its topics, and to their former
+// names until the messages published before the rename are drained. Returning
+// an error hands the message back to the bus for redelivery, except for
+// messages the saga cannot read.
+func SetupSagaDispatcher(bus eventbus.Client, sagas []Saga, dedup *Deduplicator) {
+	for _, s := range sagas {
+		for _, topic := range s.Topics() {
+			handle := func(ctx context.Context, msg []byte) error {
+				err := s.Handle(ctx, topic, msg)
+				if errors.Is(err, saga.ErrInvalidEvent) {
+					log.Printf("ERROR: %s saga could not read event: %v", s.Name(), err)
+					return eventbus.Permanent(err)
+				}
+				return err
+			}
+			for _, name := range append([]string{topic}, domain.FormerTopics(topic)...) {
+				bus.Subscribe(name, OrchestratorSubscriber, dedup.Wrap(OrchestratorSubscriber, handle))
 			}
-			handler.HandleFundsHoldFailed(ctx, ev)
-		case domain.TopicWalletFundsReleased:
-			var ev domain.WalletCommandEvent
-			if err := json.Unmarshal(msg, &ev); err != nil {
-				log.Printf("ERROR: could not unmarshal WalletCommandEvent for %s: %v", domain.TopicWalletFundsReleased, err)
-				return
-			}
-			handler.HandleFundsReleased(ctx, ev)
 		}
 	}
-
-	// Subscribe the dispatcher to all topics the orchestrator listens to.
-	bus.Subscribe(domain.TopicPaymentCreated, dispatcher)
-	bus.Subscribe(domain.TopicGatewayAuthorized, dispatcher)
-	bus.Subscribe(domain.TopicGatewayAuthorizationFailed, dispatcher)
-	bus.Subscribe(domain.TopicPaymentCompleted, dispatcher)
-	bus.Subscribe(domain.TopicWalletFunds, dispatcher)
-	bus.Subscribe(domain.TopicWalletDebitFunds, dispatcher)
-	bus.Subscribe(domain.TopicWalletHoldFundsFailed, dispatcher)
-	bus.Subscribe(domain.TopicWalletFundsReleased, dispatcher)
 }
@@ -0,0 +1,23 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/eventbus"
+)
+
+// PublishMetric publishes a metric of the operation id on domain.TopicMetrics.
+// Metrics are best effort: failing the handler would redeliver the event the
+// metric is about, so errors are only logged.
+func PublishMetric(ctx context.Context, bus eventbus.Client, metric, id string, labels map[string]string) {
+	msgBody, err := json.Marshal(domain.NewMetricEvent(metric, id, labels, 0))
+	if err == nil {
+		err = bus.Publish(ctx, domain.TopicMetrics, msgBody)
+	}
+	if err != nil {
+		log.Printf("WARN: Failed to publish %s of %s: %v", metric, id, err)
+	}
+}
@@ -0,0 +1,48 @@
+package eventbus
+
+import (
+	"context"
+	"log"
+
+	"github.com/mmarias/golearn/internal/infraestructure/eventbus"
+	"github.com/mmarias/golearn/internal/infraestructure/schema"
+)
+
+// SchemaGuard validates every event against the schema registry. Published
+// events must match the schema of their type and version; consumed events are
+// upcast to the latest version of their type first, so handlers only read the
+// current one while older messages are still in flight.
+type SchemaGuard struct {
+	eventbus.Client
+	registry *schema.Registry
+}
+
+func NewSchemaGuard(bus eventbus.Client, registry *schema.Registry) *SchemaGuard {
+	return &SchemaGuard{
+		Client:   bus,
+		registry: registry,
+	}
+}
+
+// Publish rejects events that do not match their schema; publishing them again
+// would not help, so the error is permanent.
+func (g *SchemaGuard) Publish(ctx context.Context, topic string, message []byte) error {
+	if err := g.registry.Validate(message); err != nil {
+		log.Printf("ERROR: [SchemaGuard] rejected event on %s: %v", topic, err)
+		return eventbus.Permanent(err)
+	}
+	return g.Client.Publish(ctx, topic, message)
+}
+
+// Subscribe hands handler the event upcast to its latest version. Events that
+// cannot be read go to the dead letter queue.
+func (g *SchemaGuard) Subscribe(topic, subscriber string, handler eventbus.HandlerFunc) {
+	g.Client.Subscribe(topic, subscriber, func(ctx context.Context, message []byte) error {
+		upcasted, err := g.registry.Upcast(message)
+		if err != nil {
+			log.Printf("ERROR: [SchemaGuard] '%s' could not read event on %s: %v", subscriber, topic, err)
+			return eventbus.Permanent(err)
+		}
+		return handler(ctx, upcasted)
+	})
+}
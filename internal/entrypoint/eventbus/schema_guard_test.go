@@ -0,0 +1,48 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/eventbus"
+	"github.com/mmarias/golearn/internal/infraestructure/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSchemaGuard(t *testing.T) *SchemaGuard {
+	t.Helper()
+	registry, err := schema.NewEventRegistry()
+	require.NoError(t, err)
+	return NewSchemaGuard(eventbus.New(), registry)
+}
+
+func TestSchemaGuard_RejectsInvalidEventsOnPublish(t *testing.T) {
+	guard := newSchemaGuard(t)
+
+	err := guard.Publish(context.Background(), domain.TopicOrchestratorWallet, []byte(`{"event_type": "hold_funds", "event_version": "2", "payload": {}}`))
+
+	assert.ErrorIs(t, err, schema.ErrInvalidEvent)
+	assert.True(t, eventbus.IsPermanent(err))
+}
+
+func TestSchemaGuard_UpcastsOnConsume(t *testing.T) {
+	guard := newSchemaGuard(t)
+	received := make(chan domain.WalletCommandEvent, 1)
+	guard.Subscribe(domain.TopicOrchestratorWallet, domain.ServiceWallet, func(ctx context.Context, msg []byte) error {
+		var ev domain.WalletCommandEvent
+		require.NoError(t, json.Unmarshal(msg, &ev))
+		received <- ev
+		return nil
+	})
+
+	// A version 1 message still in flight, published before the upgrade.
+	v1 := []byte(`{"event_type": "release_funds", "event_version": "1", "payload": {"payment_id": "p-1", "amount": 40, "currency": "JPY"}}`)
+	require.NoError(t, guard.Client.Publish(context.Background(), domain.TopicOrchestratorWallet, v1))
+
+	ev := <-received
+	assert.Equal(t, domain.WalletCommandEventVersion, ev.EventVersion)
+	assert.Equal(t, domain.NewMoney(40, "JPY"), ev.Amount)
+}
@@ -0,0 +1,53 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/eventbus"
+)
+
+// Topology is the bus the services publish and subscribe through. It only
+// publishes topics of domain.TopicRegistry and records the subscriptions, so
+// Check can tell at startup whether they match the registry.
+type Topology struct {
+	eventbus.Client
+
+	mu            sync.Mutex
+	subscriptions map[string][]string
+}
+
+func NewTopology(bus eventbus.Client) *Topology {
+	return &Topology{
+		Client:        bus,
+		subscriptions: map[string][]string{},
+	}
+}
+
+// Publish rejects topics missing from the registry; publishing them again
+// would not help, so the error is permanent. Renamed topics are published
+// under their current name, e.g. outbox messages stored before the rename.
+func (t *Topology) Publish(ctx context.Context, topic string, message []byte) error {
+	if _, ok := domain.LookupTopic(topic); !ok {
+		return eventbus.Permanent(fmt.Errorf("%w: %s", domain.ErrUnregisteredTopic, topic))
+	}
+	return t.Client.Publish(ctx, domain.CurrentTopic(topic), message)
+}
+
+func (t *Topology) Subscribe(topic, subscriber string, handler eventbus.HandlerFunc) {
+	t.mu.Lock()
+	t.subscriptions[topic] = append(t.subscriptions[topic], subscriber)
+	t.mu.Unlock()
+
+	t.Client.Subscribe(topic, subscriber, handler)
+}
+
+// Check fails when the subscriptions made so far do not match the registry.
+func (t *Topology) Check() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return domain.CheckTopology(t.subscriptions)
+}
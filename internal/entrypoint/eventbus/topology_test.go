@@ -0,0 +1,57 @@
+package eventbus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/eventbus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopology_Publish(t *testing.T) {
+	bus := eventbus.New()
+	topology := NewTopology(bus)
+	received := make(chan []byte, 1)
+	topology.Subscribe(domain.TopicPaymentCompleted, domain.ServiceOrchestrator, func(ctx context.Context, msg []byte) error {
+		received <- msg
+		return nil
+	})
+
+	require.NoError(t, topology.Publish(context.Background(), domain.TopicPaymentCompleted, []byte("completed")))
+	assert.Equal(t, []byte("completed"), <-received)
+
+	err := topology.Publish(context.Background(), "payment.settled", []byte("settled"))
+	assert.ErrorIs(t, err, domain.ErrUnregisteredTopic)
+	assert.True(t, eventbus.IsPermanent(err))
+}
+
+func TestTopology_PublishRenamedTopic(t *testing.T) {
+	bus := eventbus.New()
+	topology := NewTopology(bus)
+	received := make(chan []byte, 1)
+	topology.Subscribe(domain.TopicRefundCreated, domain.ServiceOrchestrator, func(ctx context.Context, msg []byte) error {
+		received <- msg
+		return nil
+	})
+
+	require.NoError(t, topology.Publish(context.Background(), domain.TopicRefundRequestedV1, []byte("requested")))
+	assert.Equal(t, []byte("requested"), <-received)
+}
+
+func TestTopology_Check(t *testing.T) {
+	topology := NewTopology(eventbus.New())
+	handler := func(ctx context.Context, msg []byte) error { return nil }
+
+	for _, route := range domain.TopicRegistry {
+		for _, subscriber := range route.Subscribers {
+			topology.Subscribe(route.Topic, subscriber, handler)
+		}
+	}
+	assert.NoError(t, topology.Check())
+
+	// A subscription to a topic nobody publishes.
+	topology.Subscribe("wallet.release_funds", domain.ServiceOrchestrator, handler)
+	assert.ErrorIs(t, topology.Check(), domain.ErrUnregisteredTopic)
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+const (
+	// HeaderGatewayTimestamp carries when the provider sent the callback, in Unix seconds.
+	HeaderGatewayTimestamp = "X-Gateway-Timestamp"
+	// HeaderGatewaySignature carries the hex HMAC-SHA256 of the timestamp and the body.
+	HeaderGatewaySignature = "X-Gateway-Signature"
+
+	maxCallbackBody = 64 << 10
+)
+
+type handleGatewayCallbackImpl interface {
+	Execute(ctx context.Context, provider, timestamp, signature string, body []byte) error
+}
+
+// GatewayCallbackHandler receives the confirmations the payment providers send
+// for the operations they answer asynchronously.
+type GatewayCallbackHandler struct {
+	handleCallback handleGatewayCallbackImpl
+}
+
+func NewGatewayCallbackHandler(handleCallback handleGatewayCallbackImpl) *GatewayCallbackHandler {
+	return &GatewayCallbackHandler{
+		handleCallback: handleCallback,
+	}
+}
+
+// CallbackHandler answers 200 once the callback is handled, also for one
+// already seen, so the provider stops delivering it. Any 5xx makes it retry.
+func (h *GatewayCallbackHandler) CallbackHandler(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCallbackBody))
+	if err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err = h.handleCallback.Execute(r.Context(), r.PathValue("provider"), r.Header.Get(HeaderGatewayTimestamp), r.Header.Get(HeaderGatewaySignature), body)
+	switch {
+	case errors.Is(err, domain.ErrUnknownGatewayProvider):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, domain.ErrInvalidCallbackSignature):
+		// Do not tell which part of the signature was wrong.
+		http.Error(w, domain.ErrInvalidCallbackSignature.Error(), http.StatusUnauthorized)
+		return
+	case errors.Is(err, domain.ErrInvalidCallback):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
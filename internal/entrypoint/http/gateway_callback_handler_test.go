@@ -0,0 +1,61 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockHandleGatewayCallback struct {
+	mock.Mock
+}
+
+func (m *MockHandleGatewayCallback) Execute(ctx context.Context, provider, timestamp, signature string, body []byte) error {
+	args := m.Called(ctx, provider, timestamp, signature, body)
+	return args.Error(0)
+}
+
+func TestGatewayCallbackHandler_CallbackHandler(t *testing.T) {
+	tests := []struct {
+		name               string
+		err                error
+		expectedStatusCode int
+	}{
+		{name: "handled", expectedStatusCode: http.StatusOK},
+		{name: "unknown provider", err: domain.ErrUnknownGatewayProvider, expectedStatusCode: http.StatusNotFound},
+		{name: "invalid signature", err: domain.ErrInvalidCallbackSignature, expectedStatusCode: http.StatusUnauthorized},
+		{name: "invalid payload", err: domain.ErrInvalidCallback, expectedStatusCode: http.StatusBadRequest},
+		{name: "publish failed", err: fmt.Errorf("bus down"), expectedStatusCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"id":"evt-1","event":"payment.authorized","payment_id":"payment-123"}`
+			handleCallbackMock := new(MockHandleGatewayCallback)
+			var err error
+			if tt.err != nil {
+				err = fmt.Errorf("handling callback: %w", tt.err)
+			}
+			handleCallbackMock.On("Execute", mock.Anything, "simulated", "1700000000", "abc123", []byte(body)).Return(err).Once()
+
+			mux := http.NewServeMux()
+			RegisterRoutes(mux, NewPaymentHandler(nil, nil, nil, nil, nil), NewWalletHandler(nil), NewRefundHandler(nil, nil, nil), NewGatewayCallbackHandler(handleCallbackMock), http.NotFoundHandler())
+
+			req := httptest.NewRequest(http.MethodPost, "/gateway/callbacks/simulated", strings.NewReader(body))
+			req.Header.Set(HeaderGatewayTimestamp, "1700000000")
+			req.Header.Set(HeaderGatewaySignature, "abc123")
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			handleCallbackMock.AssertExpectations(t)
+		})
+	}
+}
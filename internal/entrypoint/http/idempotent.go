@@ -0,0 +1,105 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/mmarias/golearn/internal/infraestructure/idempotency"
+)
+
+// idempotent runs handle once per X-Idempotent-Key. A retry of a finished
+// request replays its response, and a key reused with another path or body is
+// rejected. Keys are scoped by resource, which also names it in the errors.
+func idempotent(w http.ResponseWriter, r *http.Request, store idempotency.Store, resource string, handle func(w http.ResponseWriter, r *http.Request, body []byte)) {
+	idempotentKey := r.Header.Get("X-Idempotent-Key")
+
+	if idempotentKey == "" {
+		http.Error(w, "missing idempotent key", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tx := fmt.Sprintf("%s.%s", resource, idempotentKey)
+
+	record, err := store.Begin(tx, fingerprint(r.URL.Path, body))
+	switch {
+	case errors.Is(err, idempotency.ErrFingerprintMismatch):
+		http.Error(w, "idempotent key already used with a different request", http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		http.Error(w, resource+" already in progress", http.StatusConflict)
+		return
+	case record.Completed:
+		// Retry of a finished request: replay the original response.
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(record.StatusCode)
+		w.Write(record.Body)
+		return
+	}
+
+	rec := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+	defer func() {
+		// Server errors are not stored so the client can retry with the same key.
+		if rec.statusCode >= http.StatusInternalServerError {
+			if err := store.Release(tx, record.Claim); err != nil {
+				log.Printf("ERROR: [Idempotency] could not release %s: %v", tx, err)
+			}
+			return
+		}
+		if err := store.Complete(tx, record.Claim, rec.statusCode, rec.body.Bytes()); err != nil {
+			log.Printf("ERROR: [Idempotency] could not store the response of %s: %v", tx, err)
+		}
+	}()
+
+	handle(rec, r, body)
+}
+
+// fingerprint identifies a request by its path, which names the payment of a
+// refund, and its body regardless of insignificant whitespace.
+func fingerprint(path string, body []byte) string {
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, body); err == nil {
+		body = compacted.Bytes()
+	}
+
+	h := sha256.New()
+	h.Write([]byte(path))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder writes through to the client while keeping a copy of the
+// response, so it can be stored for idempotent replays.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	if !r.wroteHeader {
+		r.statusCode = statusCode
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -1,17 +1,25 @@
 package http
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mmarias/golearn/internal/domain"
 )
 
+// PaymentRequest takes the amount as a decimal in the currency, either as a
+// JSON number or a string ("12.30").
 type PaymentRequest struct {
-	WalletID  string  `json:"wallet_id"`
-	ServiceID string  `json:"service_id"`
-	Amount    float64 `json:"amount"`
-	Currency  string  `json:"currency"`
-	Method    string  `json:"method"`
+	WalletID  string      `json:"wallet_id"`
+	ServiceID string      `json:"service_id"`
+	Amount    json.Number `json:"amount"`
+	Currency  string      `json:"currency"`
+	Method    string      `json:"method"`
+	Token     string      `json:"token,omitempty"`
 }
 
 func (t *PaymentRequest) Validate() error {
@@ -23,14 +31,18 @@ func (t *PaymentRequest) Validate() error {
 		return fmt.Errorf("missing service_id")
 	}
 
-	if t.Amount <= 0 {
-		return fmt.Errorf("invalid amount")
-	}
-
 	if t.Currency == "" {
 		return fmt.Errorf("missing currency")
 	}
 
+	if _, err := domain.LookupCurrency(t.Currency); err != nil {
+		return fmt.Errorf("unsupported currency %q", t.Currency)
+	}
+
+	if _, err := parseAmount(t.Amount, t.Currency); err != nil {
+		return err
+	}
+
 	if t.Method == "" {
 		return fmt.Errorf("missing method")
 	}
@@ -39,11 +51,155 @@ func (t *PaymentRequest) Validate() error {
 }
 
 func (t *PaymentRequest) ToDomain() domain.Payment {
+	// Validate already rejected amounts that do not parse.
+	amount, _ := parseAmount(t.Amount, t.Currency)
+
 	return domain.Payment{
 		WalletID:  t.WalletID,
 		ServiceID: t.ServiceID,
-		Amount:    t.Amount,
-		Currency:  t.Currency,
+		Amount:    amount,
 		Method:    t.Method,
+		Token:     t.Token,
+	}
+}
+
+type PaymentResponse struct {
+	ID        string `json:"id"`
+	WalletID  string `json:"wallet_id"`
+	ServiceID string `json:"service_id"`
+	Amount    string `json:"amount"`
+	Currency  string `json:"currency"`
+	// Conversion is present when the wallet is in another currency.
+	Conversion *ConversionResponse  `json:"conversion,omitempty"`
+	Method     string               `json:"method"`
+	Status     string               `json:"status"`
+	SagaStep   string               `json:"saga_step,omitempty"`
+	History    []TransitionResponse `json:"history,omitempty"`
+	Version    int                  `json:"version"`
+	CreatedAt  time.Time            `json:"created_at"`
+	UpdatedAt  *time.Time           `json:"updated_at,omitempty"`
+}
+
+type ConversionResponse struct {
+	Amount      string    `json:"amount"`
+	Currency    string    `json:"currency"`
+	Rate        string    `json:"rate"`
+	Provider    string    `json:"provider"`
+	ConvertedAt time.Time `json:"converted_at"`
+}
+
+func newConversionResponse(c *domain.FXConversion) *ConversionResponse {
+	if c == nil {
+		return nil
+	}
+	return &ConversionResponse{
+		Amount:      c.To.Decimal(),
+		Currency:    c.To.Currency,
+		Rate:        c.Rate,
+		Provider:    c.Provider,
+		ConvertedAt: c.ConvertedAt,
+	}
+}
+
+func NewPaymentResponse(p domain.Payment, step domain.SagaStep) PaymentResponse {
+	return PaymentResponse{
+		ID:         p.ID,
+		WalletID:   p.WalletID,
+		ServiceID:  p.ServiceID,
+		Amount:     p.Amount.Decimal(),
+		Currency:   p.Amount.Currency,
+		Conversion: newConversionResponse(p.Conversion),
+		Method:     p.Method,
+		Status:     string(p.Status),
+		SagaStep:   string(step),
+		History:    newHistoryResponse(p.History),
+		Version:    p.Version,
+		CreatedAt:  p.CreatedAt,
+		UpdatedAt:  p.UpdatedAt,
+	}
+}
+
+// TransitionResponse is a status change of the payment. From is empty for the
+// status the payment was created with.
+type TransitionResponse struct {
+	From string    `json:"from,omitempty"`
+	To   string    `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+func newHistoryResponse(history []domain.PaymentTransition) []TransitionResponse {
+	if len(history) == 0 {
+		return nil
+	}
+	res := make([]TransitionResponse, 0, len(history))
+	for _, t := range history {
+		res = append(res, TransitionResponse{From: string(t.From), To: string(t.To), At: t.At})
+	}
+	return res
+}
+
+type PaymentListResponse struct {
+	Payments   []PaymentResponse `json:"payments"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+func NewPaymentListResponse(page domain.PaymentPage) PaymentListResponse {
+	res := PaymentListResponse{
+		Payments:   make([]PaymentResponse, 0, len(page.Payments)),
+		NextCursor: page.NextCursor,
+	}
+	for _, p := range page.Payments {
+		res.Payments = append(res.Payments, NewPaymentResponse(p, ""))
+	}
+	return res
+}
+
+// ParsePaymentFilter reads the query parameters of GET /payments.
+func ParsePaymentFilter(q url.Values) (domain.PaymentFilter, error) {
+	filter := domain.PaymentFilter{
+		WalletID: q.Get("wallet_id"),
+		Status:   domain.PaymentStatus(strings.ToUpper(q.Get("status"))),
+		Cursor:   q.Get("cursor"),
+	}
+
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return filter, fmt.Errorf("invalid status")
+	}
+
+	var err error
+	if v := q.Get("from"); v != "" {
+		if filter.From, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, fmt.Errorf("invalid from, expected RFC3339")
+		}
+	}
+
+	if v := q.Get("to"); v != "" {
+		if filter.To, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, fmt.Errorf("invalid to, expected RFC3339")
+		}
+	}
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return filter, fmt.Errorf("from must be before to")
+	}
+
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit <= 0 {
+			return filter, fmt.Errorf("invalid limit")
+		}
+	}
+
+	return filter, nil
+}
+
+// parseAmount reads a positive decimal amount in currency.
+func parseAmount(amount json.Number, currency string) (domain.Money, error) {
+	money, err := domain.ParseMoney(amount.String(), currency)
+	if err != nil {
+		return domain.Money{}, err
+	}
+	if !money.IsPositive() {
+		return domain.Money{}, fmt.Errorf("invalid amount")
 	}
+	return money, nil
 }
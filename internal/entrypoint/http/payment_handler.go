@@ -3,70 +3,158 @@ package http
 import (
 	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
 
 	"github.com/mmarias/golearn/internal/domain"
-	"github.com/mmarias/golearn/internal/infraestructure/memcache"
+	"github.com/mmarias/golearn/internal/infraestructure/idempotency"
 )
 
 type createPaymentImpl interface {
 	Execute(ctx context.Context, pay domain.Payment) (string, error)
 }
 
+type getPaymentImpl interface {
+	Execute(ctx context.Context, paymentId string) (domain.Payment, domain.SagaStep, error)
+}
+
+type listPaymentsImpl interface {
+	Execute(ctx context.Context, filter domain.PaymentFilter) (domain.PaymentPage, error)
+}
+
+type cancelPaymentImpl interface {
+	Execute(ctx context.Context, paymentId string) (domain.Payment, domain.SagaStep, error)
+}
+
 // PaymentHandler holds the dependencies for the handlers.
 type PaymentHandler struct {
 	createPayment createPaymentImpl
-	cache         memcache.Cache
+	getPayment    getPaymentImpl
+	listPayments  listPaymentsImpl
+	cancelPayment cancelPaymentImpl
+	idempotency   idempotency.Store
 }
 
-func NewPaymentHandler(createPayment createPaymentImpl, cache memcache.Cache) *PaymentHandler {
+func NewPaymentHandler(
+	createPayment createPaymentImpl,
+	getPayment getPaymentImpl,
+	listPayments listPaymentsImpl,
+	cancelPayment cancelPaymentImpl,
+	idempotency idempotency.Store,
+) *PaymentHandler {
 	return &PaymentHandler{
 		createPayment: createPayment,
-		cache:         cache,
+		getPayment:    getPayment,
+		listPayments:  listPayments,
+		cancelPayment: cancelPayment,
+		idempotency:   idempotency,
 	}
 }
 
 func (h *PaymentHandler) CreatePaymentHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	idempotentKey := r.Header.Get("X-Idempotent-Key")
+	idempotent(w, r, h.idempotency, "payment", h.createPaymentFromBody)
+}
 
-	if idempotentKey == "" {
-		http.Error(w, "missing idempotent key", http.StatusBadRequest)
+func (h *PaymentHandler) createPaymentFromBody(w http.ResponseWriter, r *http.Request, body []byte) {
+	var req PaymentRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	tx := fmt.Sprintf("payment.%s", idempotentKey)
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	if err := h.cache.SetNX(tx); err != nil {
-		http.Error(w, "payment already processed or in progress", http.StatusConflict)
+	id, err := h.createPayment.Execute(r.Context(), req.ToDomain())
+	switch {
+	case errors.Is(err, domain.ErrWalletNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, domain.ErrRateNotFound):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	defer h.cache.Delete(tx)
+	w.WriteHeader(http.StatusCreated)
+	response := map[string]string{"id": id}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		log.Printf("could not encode response: %v", err)
+	}
+}
 
-	var req PaymentRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+func (h *PaymentHandler) GetPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	pay, step, err := h.getPayment.Execute(r.Context(), r.PathValue("id"))
+	if errors.Is(err, domain.ErrPaymentNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if err := req.Validate(); err != nil {
+	if err := json.NewEncoder(w).Encode(NewPaymentResponse(pay, step)); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		log.Printf("could not encode response: %v", err)
+	}
+}
+
+func (h *PaymentHandler) ListPaymentsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	filter, err := ParsePaymentFilter(r.URL.Query())
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	id, err := h.createPayment.Execute(r.Context(), req.ToDomain())
+	page, err := h.listPayments.Execute(r.Context(), filter)
+	if errors.Is(err, domain.ErrInvalidPaymentCursor) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	response := map[string]string{"id": id}
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	if err := json.NewEncoder(w).Encode(NewPaymentListResponse(page)); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		log.Printf("could not encode response: %v", err)
+	}
+}
+
+// CancelPaymentHandler asks to abort a pending payment. The saga compensates in
+// the background, so the response is 202 with the payment as it is now.
+func (h *PaymentHandler) CancelPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	pay, step, err := h.cancelPayment.Execute(r.Context(), r.PathValue("id"))
+	switch {
+	case errors.Is(err, domain.ErrPaymentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, domain.ErrPaymentAlreadyDebited), errors.Is(err, domain.ErrPaymentNotCancelable):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(NewPaymentResponse(pay, step)); err != nil {
 		http.Error(w, "failed to encode response", http.StatusInternalServerError)
 		log.Printf("could not encode response: %v", err)
 	}
@@ -8,8 +8,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/database"
+	"github.com/mmarias/golearn/internal/infraestructure/idempotency"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -24,18 +27,25 @@ func (m *MockCreatePayment) Execute(ctx context.Context, pay domain.Payment) (st
 	return args.String(0), args.Error(1)
 }
 
-// MockCache is a mock for the memcache.Cache interface
-type MockCache struct {
+// MockIdempotencyStore is a mock for the idempotency.Store interface
+type MockIdempotencyStore struct {
 	mock.Mock
 }
 
-func (m *MockCache) SetNX(key string) error {
-	args := m.Called(key)
+func (m *MockIdempotencyStore) Begin(key, fingerprint string) (*idempotency.Record, error) {
+	args := m.Called(key, fingerprint)
+	record, _ := args.Get(0).(*idempotency.Record)
+	return record, args.Error(1)
+}
+
+func (m *MockIdempotencyStore) Complete(key, claim string, statusCode int, body []byte) error {
+	args := m.Called(key, claim, statusCode, string(body))
 	return args.Error(0)
 }
 
-func (m *MockCache) Delete(key string) {
-	m.Called(key)
+func (m *MockIdempotencyStore) Release(key, claim string) error {
+	args := m.Called(key, claim)
+	return args.Error(0)
 }
 
 func TestPaymentHandler_CreatePaymentHandler(t *testing.T) {
@@ -43,7 +53,7 @@ func TestPaymentHandler_CreatePaymentHandler(t *testing.T) {
 		name                 string
 		idempotentKey        string
 		requestBody          interface{}
-		setupMocks           func(createPayment *MockCreatePayment, cache *MockCache)
+		setupMocks           func(createPayment *MockCreatePayment, store *MockIdempotencyStore)
 		expectedStatusCode   int
 		expectedResponseBody string
 	}{
@@ -51,27 +61,51 @@ func TestPaymentHandler_CreatePaymentHandler(t *testing.T) {
 			name:                 "missing idempotent key",
 			idempotentKey:        "",
 			requestBody:          nil,
-			setupMocks:           func(createPayment *MockCreatePayment, cache *MockCache) {},
+			setupMocks:           func(createPayment *MockCreatePayment, store *MockIdempotencyStore) {},
 			expectedStatusCode:   http.StatusBadRequest,
 			expectedResponseBody: "missing idempotent key\n",
 		},
 		{
-			name:          "payment already processed",
+			name:          "payment already in progress",
 			idempotentKey: "test-key",
 			requestBody:   nil,
-			setupMocks: func(createPayment *MockCreatePayment, cache *MockCache) {
-				cache.On("SetNX", "payment.test-key").Return(errors.New("key already exists"))
+			setupMocks: func(createPayment *MockCreatePayment, store *MockIdempotencyStore) {
+				store.On("Begin", "payment.test-key", mock.Anything).Return(nil, idempotency.ErrRequestInProgress)
 			},
 			expectedStatusCode:   http.StatusConflict,
-			expectedResponseBody: "payment already processed or in progress\n",
+			expectedResponseBody: "payment already in progress\n",
+		},
+		{
+			name:          "idempotent key reused with a different body",
+			idempotentKey: "test-key",
+			requestBody:   nil,
+			setupMocks: func(createPayment *MockCreatePayment, store *MockIdempotencyStore) {
+				store.On("Begin", "payment.test-key", mock.Anything).Return(nil, idempotency.ErrFingerprintMismatch)
+			},
+			expectedStatusCode:   http.StatusUnprocessableEntity,
+			expectedResponseBody: "idempotent key already used with a different request\n",
+		},
+		{
+			name:          "payment already processed replays the original response",
+			idempotentKey: "test-key",
+			requestBody:   nil,
+			setupMocks: func(createPayment *MockCreatePayment, store *MockIdempotencyStore) {
+				store.On("Begin", "payment.test-key", mock.Anything).Return(&idempotency.Record{
+					Completed:  true,
+					StatusCode: http.StatusCreated,
+					Body:       []byte("{\"id\":\"payment-id-123\"}\n"),
+				}, nil)
+			},
+			expectedStatusCode:   http.StatusCreated,
+			expectedResponseBody: "{\"id\":\"payment-id-123\"}\n",
 		},
 		{
 			name:          "invalid request body",
 			idempotentKey: "test-key",
 			requestBody:   "invalid-json",
-			setupMocks: func(createPayment *MockCreatePayment, cache *MockCache) {
-				cache.On("SetNX", "payment.test-key").Return(nil)
-				cache.On("Delete", "payment.test-key").Return()
+			setupMocks: func(createPayment *MockCreatePayment, store *MockIdempotencyStore) {
+				store.On("Begin", "payment.test-key", mock.Anything).Return(&idempotency.Record{Claim: "claim-1"}, nil)
+				store.On("Complete", "payment.test-key", "claim-1", http.StatusBadRequest, "invalid request body\n").Return(nil)
 			},
 			expectedStatusCode:   http.StatusBadRequest,
 			expectedResponseBody: "invalid request body\n",
@@ -82,13 +116,13 @@ func TestPaymentHandler_CreatePaymentHandler(t *testing.T) {
 			requestBody: PaymentRequest{
 				WalletID:  "",
 				ServiceID: "service-456",
-				Amount:    100,
+				Amount:    "100",
 				Currency:  "USD",
 				Method:    "credit_card",
 			},
-			setupMocks: func(createPayment *MockCreatePayment, cache *MockCache) {
-				cache.On("SetNX", "payment.test-key").Return(nil)
-				cache.On("Delete", "payment.test-key").Return()
+			setupMocks: func(createPayment *MockCreatePayment, store *MockIdempotencyStore) {
+				store.On("Begin", "payment.test-key", mock.Anything).Return(&idempotency.Record{Claim: "claim-1"}, nil)
+				store.On("Complete", "payment.test-key", "claim-1", http.StatusBadRequest, "missing wallet_id\n").Return(nil)
 			},
 			expectedStatusCode:   http.StatusBadRequest,
 			expectedResponseBody: "missing wallet_id\n",
@@ -99,13 +133,13 @@ func TestPaymentHandler_CreatePaymentHandler(t *testing.T) {
 			requestBody: PaymentRequest{
 				WalletID:  "wallet-123",
 				ServiceID: "",
-				Amount:    100,
+				Amount:    "100",
 				Currency:  "USD",
 				Method:    "credit_card",
 			},
-			setupMocks: func(createPayment *MockCreatePayment, cache *MockCache) {
-				cache.On("SetNX", "payment.test-key").Return(nil)
-				cache.On("Delete", "payment.test-key").Return()
+			setupMocks: func(createPayment *MockCreatePayment, store *MockIdempotencyStore) {
+				store.On("Begin", "payment.test-key", mock.Anything).Return(&idempotency.Record{Claim: "claim-1"}, nil)
+				store.On("Complete", "payment.test-key", "claim-1", http.StatusBadRequest, "missing service_id\n").Return(nil)
 			},
 			expectedStatusCode:   http.StatusBadRequest,
 			expectedResponseBody: "missing service_id\n",
@@ -116,13 +150,13 @@ func TestPaymentHandler_CreatePaymentHandler(t *testing.T) {
 			requestBody: PaymentRequest{
 				WalletID:  "wallet-123",
 				ServiceID: "service-456",
-				Amount:    0,
+				Amount:    "0",
 				Currency:  "USD",
 				Method:    "credit_card",
 			},
-			setupMocks: func(createPayment *MockCreatePayment, cache *MockCache) {
-				cache.On("SetNX", "payment.test-key").Return(nil)
-				cache.On("Delete", "payment.test-key").Return()
+			setupMocks: func(createPayment *MockCreatePayment, store *MockIdempotencyStore) {
+				store.On("Begin", "payment.test-key", mock.Anything).Return(&idempotency.Record{Claim: "claim-1"}, nil)
+				store.On("Complete", "payment.test-key", "claim-1", http.StatusBadRequest, "invalid amount\n").Return(nil)
 			},
 			expectedStatusCode:   http.StatusBadRequest,
 			expectedResponseBody: "invalid amount\n",
@@ -133,47 +167,113 @@ func TestPaymentHandler_CreatePaymentHandler(t *testing.T) {
 			requestBody: PaymentRequest{
 				WalletID:  "wallet-123",
 				ServiceID: "service-456",
-				Amount:    -100,
+				Amount:    "-100",
 				Currency:  "USD",
 				Method:    "credit_card",
 			},
-			setupMocks: func(createPayment *MockCreatePayment, cache *MockCache) {
-				cache.On("SetNX", "payment.test-key").Return(nil)
-				cache.On("Delete", "payment.test-key").Return()
+			setupMocks: func(createPayment *MockCreatePayment, store *MockIdempotencyStore) {
+				store.On("Begin", "payment.test-key", mock.Anything).Return(&idempotency.Record{Claim: "claim-1"}, nil)
+				store.On("Complete", "payment.test-key", "claim-1", http.StatusBadRequest, "invalid amount\n").Return(nil)
 			},
 			expectedStatusCode:   http.StatusBadRequest,
 			expectedResponseBody: "invalid amount\n",
 		},
+		{
+			name:          "amount with more decimals than the currency allows",
+			idempotentKey: "test-key",
+			requestBody: PaymentRequest{
+				WalletID:  "wallet-123",
+				ServiceID: "service-456",
+				Amount:    "12.345",
+				Currency:  "USD",
+				Method:    "credit_card",
+			},
+			setupMocks: func(createPayment *MockCreatePayment, store *MockIdempotencyStore) {
+				store.On("Begin", "payment.test-key", mock.Anything).Return(&idempotency.Record{Claim: "claim-1"}, nil)
+				store.On("Complete", "payment.test-key", "claim-1", http.StatusBadRequest, "amount has more decimals than the currency allows: \"12.345\" in USD\n").Return(nil)
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "amount has more decimals than the currency allows: \"12.345\" in USD\n",
+		},
+		{
+			name:          "decimal string amount",
+			idempotentKey: "test-key",
+			requestBody:   `{"wallet_id":"wallet-123","service_id":"service-456","amount":"12.30","currency":"USD","method":"credit_card"}`,
+			setupMocks: func(createPayment *MockCreatePayment, store *MockIdempotencyStore) {
+				store.On("Begin", "payment.test-key", mock.Anything).Return(&idempotency.Record{Claim: "claim-1"}, nil)
+				createPayment.On("Execute", mock.Anything, mock.MatchedBy(func(p domain.Payment) bool {
+					return p.Amount == domain.NewMoney(1230, "USD")
+				})).Return("payment-id-123", nil)
+				store.On("Complete", "payment.test-key", "claim-1", http.StatusCreated, "{\"id\":\"payment-id-123\"}\n").Return(nil)
+			},
+			expectedStatusCode:   http.StatusCreated,
+			expectedResponseBody: "{\"id\":\"payment-id-123\"}\n",
+		},
 		{
 			name:          "missing currency in request body",
 			idempotentKey: "test-key",
 			requestBody: PaymentRequest{
 				WalletID:  "wallet-123",
 				ServiceID: "service-456",
-				Amount:    100,
+				Amount:    "100",
 				Currency:  "",
 				Method:    "credit_card",
 			},
-			setupMocks: func(createPayment *MockCreatePayment, cache *MockCache) {
-				cache.On("SetNX", "payment.test-key").Return(nil)
-				cache.On("Delete", "payment.test-key").Return()
+			setupMocks: func(createPayment *MockCreatePayment, store *MockIdempotencyStore) {
+				store.On("Begin", "payment.test-key", mock.Anything).Return(&idempotency.Record{Claim: "claim-1"}, nil)
+				store.On("Complete", "payment.test-key", "claim-1", http.StatusBadRequest, "missing currency\n").Return(nil)
 			},
 			expectedStatusCode:   http.StatusBadRequest,
 			expectedResponseBody: "missing currency\n",
 		},
+		{
+			name:          "unsupported currency in request body",
+			idempotentKey: "test-key",
+			requestBody: PaymentRequest{
+				WalletID:  "wallet-123",
+				ServiceID: "service-456",
+				Amount:    "100",
+				Currency:  "XYZ",
+				Method:    "credit_card",
+			},
+			setupMocks: func(createPayment *MockCreatePayment, store *MockIdempotencyStore) {
+				store.On("Begin", "payment.test-key", mock.Anything).Return(&idempotency.Record{Claim: "claim-1"}, nil)
+				store.On("Complete", "payment.test-key", "claim-1", http.StatusBadRequest, "unsupported currency \"XYZ\"\n").Return(nil)
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "unsupported currency \"XYZ\"\n",
+		},
+		{
+			name:          "no exchange rate to the wallet currency",
+			idempotentKey: "test-key",
+			requestBody: PaymentRequest{
+				WalletID:  "wallet-123",
+				ServiceID: "service-456",
+				Amount:    "100",
+				Currency:  "GBP",
+				Method:    "credit_card",
+			},
+			setupMocks: func(createPayment *MockCreatePayment, store *MockIdempotencyStore) {
+				store.On("Begin", "payment.test-key", mock.Anything).Return(&idempotency.Record{Claim: "claim-1"}, nil)
+				createPayment.On("Execute", mock.Anything, mock.AnythingOfType("domain.Payment")).Return("", domain.ErrRateNotFound)
+				store.On("Complete", "payment.test-key", "claim-1", http.StatusUnprocessableEntity, "exchange rate not found\n").Return(nil)
+			},
+			expectedStatusCode:   http.StatusUnprocessableEntity,
+			expectedResponseBody: "exchange rate not found\n",
+		},
 		{
 			name:          "missing method in request body",
 			idempotentKey: "test-key",
 			requestBody: PaymentRequest{
 				WalletID:  "wallet-123",
 				ServiceID: "service-456",
-				Amount:    100,
+				Amount:    "100",
 				Currency:  "USD",
 				Method:    "",
 			},
-			setupMocks: func(createPayment *MockCreatePayment, cache *MockCache) {
-				cache.On("SetNX", "payment.test-key").Return(nil)
-				cache.On("Delete", "payment.test-key").Return()
+			setupMocks: func(createPayment *MockCreatePayment, store *MockIdempotencyStore) {
+				store.On("Begin", "payment.test-key", mock.Anything).Return(&idempotency.Record{Claim: "claim-1"}, nil)
+				store.On("Complete", "payment.test-key", "claim-1", http.StatusBadRequest, "missing method\n").Return(nil)
 			},
 			expectedStatusCode:   http.StatusBadRequest,
 			expectedResponseBody: "missing method\n",
@@ -184,13 +284,13 @@ func TestPaymentHandler_CreatePaymentHandler(t *testing.T) {
 			requestBody: PaymentRequest{
 				WalletID:  "wallet-123",
 				ServiceID: "service-456",
-				Amount:    100,
+				Amount:    "100",
 				Currency:  "USD",
 				Method:    "credit_card",
 			},
-			setupMocks: func(createPayment *MockCreatePayment, cache *MockCache) {
-				cache.On("SetNX", "payment.test-key").Return(nil)
-				cache.On("Delete", "payment.test-key").Return()
+			setupMocks: func(createPayment *MockCreatePayment, store *MockIdempotencyStore) {
+				store.On("Begin", "payment.test-key", mock.Anything).Return(&idempotency.Record{Claim: "claim-1"}, nil)
+				store.On("Release", "payment.test-key", "claim-1").Return(nil)
 				createPayment.On("Execute", mock.Anything, mock.AnythingOfType("domain.Payment")).Return("", errors.New("internal server error"))
 			},
 			expectedStatusCode:   http.StatusInternalServerError,
@@ -202,14 +302,14 @@ func TestPaymentHandler_CreatePaymentHandler(t *testing.T) {
 			requestBody: PaymentRequest{
 				WalletID:  "wallet-123",
 				ServiceID: "service-456",
-				Amount:    100,
+				Amount:    "100",
 				Currency:  "USD",
 				Method:    "credit_card",
 			},
-			setupMocks: func(createPayment *MockCreatePayment, cache *MockCache) {
-				cache.On("SetNX", "payment.test-key").Return(nil)
+			setupMocks: func(createPayment *MockCreatePayment, store *MockIdempotencyStore) {
+				store.On("Begin", "payment.test-key", mock.Anything).Return(&idempotency.Record{Claim: "claim-1"}, nil)
 				createPayment.On("Execute", mock.Anything, mock.AnythingOfType("domain.Payment")).Return("payment-id-123", nil)
-				cache.On("Delete", "payment.test-key").Return()
+				store.On("Complete", "payment.test-key", "claim-1", http.StatusCreated, "{\"id\":\"payment-id-123\"}\n").Return(nil)
 			},
 			expectedStatusCode:   http.StatusCreated,
 			expectedResponseBody: "{\"id\":\"payment-id-123\"}\n",
@@ -219,10 +319,10 @@ func TestPaymentHandler_CreatePaymentHandler(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			createPaymentMock := new(MockCreatePayment)
-			cacheMock := new(MockCache)
-			tt.setupMocks(createPaymentMock, cacheMock)
+			storeMock := new(MockIdempotencyStore)
+			tt.setupMocks(createPaymentMock, storeMock)
 
-			handler := NewPaymentHandler(createPaymentMock, cacheMock)
+			handler := NewPaymentHandler(createPaymentMock, nil, nil, nil, storeMock)
 
 			var body []byte
 			if tt.requestBody != nil {
@@ -245,7 +345,269 @@ func TestPaymentHandler_CreatePaymentHandler(t *testing.T) {
 			assert.Equal(t, tt.expectedResponseBody, rr.Body.String())
 
 			createPaymentMock.AssertExpectations(t)
-			cacheMock.AssertExpectations(t)
+			storeMock.AssertExpectations(t)
+		})
+	}
+}
+
+// MockGetPayment is a mock for the getPaymentImpl interface
+type MockGetPayment struct {
+	mock.Mock
+}
+
+func (m *MockGetPayment) Execute(ctx context.Context, paymentId string) (domain.Payment, domain.SagaStep, error) {
+	args := m.Called(ctx, paymentId)
+	return args.Get(0).(domain.Payment), args.Get(1).(domain.SagaStep), args.Error(2)
+}
+
+// MockListPayments is a mock for the listPaymentsImpl interface
+type MockListPayments struct {
+	mock.Mock
+}
+
+func (m *MockListPayments) Execute(ctx context.Context, filter domain.PaymentFilter) (domain.PaymentPage, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(domain.PaymentPage), args.Error(1)
+}
+
+func TestPaymentHandler_GetPaymentHandler(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name                 string
+		setupMocks           func(getPayment *MockGetPayment)
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name: "payment not found",
+			setupMocks: func(getPayment *MockGetPayment) {
+				getPayment.On("Execute", mock.Anything, "payment-123").Return(domain.Payment{}, domain.SagaStep(""), domain.ErrPaymentNotFound)
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: "payment not found\n",
+		},
+		{
+			name: "repository fails",
+			setupMocks: func(getPayment *MockGetPayment) {
+				getPayment.On("Execute", mock.Anything, "payment-123").Return(domain.Payment{}, domain.SagaStep(""), errors.New("internal server error"))
+			},
+			expectedStatusCode:   http.StatusInternalServerError,
+			expectedResponseBody: "internal server error\n",
+		},
+		{
+			name: "payment with saga step",
+			setupMocks: func(getPayment *MockGetPayment) {
+				getPayment.On("Execute", mock.Anything, "payment-123").Return(domain.Payment{
+					ID:        "payment-123",
+					WalletID:  "wallet-123",
+					ServiceID: "service-456",
+					Amount:    domain.NewMoney(100_00, "USD"),
+					Method:    "credit_card",
+					Status:    domain.PaymentStatusPending,
+					Version:   1,
+					CreatedAt: createdAt,
+				}, domain.SagaStepAuthorizing, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":"payment-123","wallet_id":"wallet-123","service_id":"service-456","amount":"100.00","currency":"USD","method":"credit_card","status":"PENDING","saga_step":"AUTHORIZING","version":1,"created_at":"2025-01-02T03:04:05Z"}` + "\n",
+		},
+		{
+			name: "payment with history",
+			setupMocks: func(getPayment *MockGetPayment) {
+				getPayment.On("Execute", mock.Anything, "payment-123").Return(domain.Payment{
+					ID:        "payment-123",
+					WalletID:  "wallet-123",
+					ServiceID: "service-456",
+					Amount:    domain.NewMoney(100_00, "USD"),
+					Method:    "credit_card",
+					Status:    domain.PaymentStatusFundsHeld,
+					History: []domain.PaymentTransition{
+						{To: domain.PaymentStatusPending, At: createdAt},
+						{From: domain.PaymentStatusPending, To: domain.PaymentStatusFundsHeld, At: createdAt},
+					},
+					Version:   2,
+					CreatedAt: createdAt,
+				}, domain.SagaStepAuthorizing, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":"payment-123","wallet_id":"wallet-123","service_id":"service-456","amount":"100.00","currency":"USD","method":"credit_card","status":"FUNDS_HELD","saga_step":"AUTHORIZING","history":[{"to":"PENDING","at":"2025-01-02T03:04:05Z"},{"from":"PENDING","to":"FUNDS_HELD","at":"2025-01-02T03:04:05Z"}],"version":2,"created_at":"2025-01-02T03:04:05Z"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getPaymentMock := new(MockGetPayment)
+			tt.setupMocks(getPaymentMock)
+
+			mux := http.NewServeMux()
+			RegisterRoutes(mux, NewPaymentHandler(nil, getPaymentMock, nil, nil, nil), NewWalletHandler(nil), NewRefundHandler(nil, nil, nil), NewGatewayCallbackHandler(nil), http.NotFoundHandler())
+
+			req := httptest.NewRequest(http.MethodGet, "/payments/payment-123", nil)
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			assert.Equal(t, tt.expectedResponseBody, rr.Body.String())
+
+			getPaymentMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPaymentHandler_ListPaymentsHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		query                string
+		setupMocks           func(listPayments *MockListPayments)
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:                 "invalid status",
+			query:                "?status=unknown",
+			setupMocks:           func(listPayments *MockListPayments) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "invalid status\n",
+		},
+		{
+			name:                 "invalid from",
+			query:                "?from=yesterday",
+			setupMocks:           func(listPayments *MockListPayments) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "invalid from, expected RFC3339\n",
+		},
+		{
+			name:                 "from after to",
+			query:                "?from=2025-01-02T00:00:00Z&to=2025-01-01T00:00:00Z",
+			setupMocks:           func(listPayments *MockListPayments) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "from must be before to\n",
+		},
+		{
+			name:  "invalid cursor",
+			query: "?cursor=broken",
+			setupMocks: func(listPayments *MockListPayments) {
+				listPayments.On("Execute", mock.Anything, domain.PaymentFilter{Cursor: "broken"}).Return(domain.PaymentPage{}, domain.ErrInvalidPaymentCursor)
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "invalid payment cursor\n",
+		},
+		{
+			name:  "filtered page",
+			query: "?wallet_id=wallet-123&status=completed&from=2025-01-01T00:00:00Z&to=2025-01-02T00:00:00Z&limit=1",
+			setupMocks: func(listPayments *MockListPayments) {
+				listPayments.On("Execute", mock.Anything, domain.PaymentFilter{
+					WalletID: "wallet-123",
+					Status:   domain.PaymentStatusCompleted,
+					From:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+					To:       time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+					Limit:    1,
+				}).Return(domain.PaymentPage{
+					Payments:   []domain.Payment{{ID: "payment-123", WalletID: "wallet-123", Status: domain.PaymentStatusCompleted}},
+					NextCursor: "next",
+				}, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"payments":[{"id":"payment-123","wallet_id":"wallet-123","service_id":"","amount":"0.00","currency":"","method":"","status":"COMPLETED","version":0,"created_at":"0001-01-01T00:00:00Z"}],"next_cursor":"next"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listPaymentsMock := new(MockListPayments)
+			tt.setupMocks(listPaymentsMock)
+
+			handler := NewPaymentHandler(nil, nil, listPaymentsMock, nil, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/payments"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handler.ListPaymentsHandler(rr, req)
+
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			assert.Equal(t, tt.expectedResponseBody, rr.Body.String())
+
+			listPaymentsMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPaymentHandler_CreatePaymentHandler_IdempotentRetries(t *testing.T) {
+	createPaymentMock := new(MockCreatePayment)
+	createPaymentMock.On("Execute", mock.Anything, mock.AnythingOfType("domain.Payment")).Return("payment-id-123", nil).Once()
+
+	handler := NewPaymentHandler(createPaymentMock, nil, nil, nil, idempotency.NewStore(database.OpenInMemory(), time.Minute))
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/payments", bytes.NewReader([]byte(body)))
+		req.Header.Set("X-Idempotent-Key", "retry-key")
+		rr := httptest.NewRecorder()
+		handler.CreatePaymentHandler(rr, req)
+		return rr
+	}
+
+	body := `{"wallet_id":"wallet-123","service_id":"service-456","amount":"100.00","currency":"USD","method":"credit_card"}`
+
+	first := send(body)
+	assert.Equal(t, http.StatusCreated, first.Code)
+
+	retry := send(" " + body + "\n")
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+
+	different := send(`{"wallet_id":"wallet-123","service_id":"service-456","amount":200,"currency":"USD","method":"credit_card"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, different.Code)
+
+	createPaymentMock.AssertExpectations(t)
+}
+
+type MockCancelPayment struct {
+	mock.Mock
+}
+
+func (m *MockCancelPayment) Execute(ctx context.Context, paymentId string) (domain.Payment, domain.SagaStep, error) {
+	args := m.Called(ctx, paymentId)
+	return args.Get(0).(domain.Payment), args.Get(1).(domain.SagaStep), args.Error(2)
+}
+
+func TestPaymentHandler_CancelPaymentHandler(t *testing.T) {
+	tests := []struct {
+		name                 string
+		err                  error
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{name: "cancellation requested", expectedStatusCode: http.StatusAccepted},
+		{name: "payment not found", err: domain.ErrPaymentNotFound, expectedStatusCode: http.StatusNotFound, expectedResponseBody: "payment not found\n"},
+		{name: "payment already debited", err: domain.ErrPaymentAlreadyDebited, expectedStatusCode: http.StatusConflict, expectedResponseBody: "payment was already debited\n"},
+		{name: "payment not cancelable", err: domain.ErrPaymentNotCancelable, expectedStatusCode: http.StatusConflict, expectedResponseBody: "payment can no longer be canceled\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cancelPaymentMock := new(MockCancelPayment)
+			pay := domain.Payment{ID: "payment-123", Amount: domain.NewMoney(100_00, "USD"), Status: domain.PaymentStatusPending}
+			cancelPaymentMock.On("Execute", mock.Anything, "payment-123").Return(pay, domain.SagaStepAuthorizing, tt.err).Once()
+
+			mux := http.NewServeMux()
+			RegisterRoutes(mux, NewPaymentHandler(nil, nil, nil, cancelPaymentMock, nil), NewWalletHandler(nil), NewRefundHandler(nil, nil, nil), NewGatewayCallbackHandler(nil), http.NotFoundHandler())
+
+			req := httptest.NewRequest(http.MethodPost, "/payments/payment-123/cancel", nil)
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			if tt.err != nil {
+				assert.Equal(t, tt.expectedResponseBody, rr.Body.String())
+			} else {
+				var res PaymentResponse
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+				assert.Equal(t, "PENDING", res.Status)
+				assert.Equal(t, "AUTHORIZING", res.SagaStep)
+			}
+
+			cancelPaymentMock.AssertExpectations(t)
 		})
 	}
 }
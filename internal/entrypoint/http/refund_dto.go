@@ -0,0 +1,47 @@
+package http
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+// RefundRequest takes the amount to refund in the payment currency. Without
+// an amount, whatever is left of the payment is refunded. The payment is only
+// read from the body on POST /refunds.
+type RefundRequest struct {
+	PaymentID string      `json:"payment_id,omitempty"`
+	Amount    json.Number `json:"amount,omitempty"`
+}
+
+type RefundResponse struct {
+	ID        string `json:"id"`
+	PaymentID string `json:"payment_id"`
+	WalletID  string `json:"wallet_id"`
+	Amount    string `json:"amount"`
+	Currency  string `json:"currency"`
+	// WalletAmount is what the wallet gets back, in its currency.
+	WalletAmount   string     `json:"wallet_amount"`
+	WalletCurrency string     `json:"wallet_currency"`
+	Status         string     `json:"status"`
+	Reason         string     `json:"reason,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
+}
+
+func NewRefundResponse(r domain.Refund) RefundResponse {
+	return RefundResponse{
+		ID:             r.ID,
+		PaymentID:      r.PaymentID,
+		WalletID:       r.WalletID,
+		Amount:         r.Amount.Decimal(),
+		Currency:       r.Amount.Currency,
+		WalletAmount:   r.WalletAmount.Decimal(),
+		WalletCurrency: r.WalletAmount.Currency,
+		Status:         string(r.Status),
+		Reason:         r.Reason,
+		CreatedAt:      r.CreatedAt,
+		UpdatedAt:      r.UpdatedAt,
+	}
+}
@@ -0,0 +1,111 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/idempotency"
+)
+
+type createRefundImpl interface {
+	Execute(ctx context.Context, paymentId, amount string) (domain.Refund, error)
+}
+
+type getRefundImpl interface {
+	Execute(ctx context.Context, refundId string) (domain.Refund, error)
+}
+
+// RefundHandler holds the dependencies for the refund handlers.
+type RefundHandler struct {
+	createRefund createRefundImpl
+	getRefund    getRefundImpl
+	idempotency  idempotency.Store
+}
+
+func NewRefundHandler(
+	createRefund createRefundImpl,
+	getRefund getRefundImpl,
+	idempotency idempotency.Store,
+) *RefundHandler {
+	return &RefundHandler{
+		createRefund: createRefund,
+		getRefund:    getRefund,
+		idempotency:  idempotency,
+	}
+}
+
+// CreateRefundHandler starts the refund of a payment, taken from the path on
+// /payments/{id}/refunds and from the body on /refunds. The refund saga runs
+// in the background, so the response is 202 with the pending refund.
+func (h *RefundHandler) CreateRefundHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	idempotent(w, r, h.idempotency, "refund", h.createRefundFromBody)
+}
+
+func (h *RefundHandler) createRefundFromBody(w http.ResponseWriter, r *http.Request, body []byte) {
+	var req RefundRequest
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	paymentId := r.PathValue("id")
+	if paymentId == "" {
+		paymentId = req.PaymentID
+	}
+	if paymentId == "" {
+		http.Error(w, "payment_id is required", http.StatusBadRequest)
+		return
+	}
+
+	refund, err := h.createRefund.Execute(r.Context(), paymentId, req.Amount.String())
+	switch {
+	case errors.Is(err, domain.ErrPaymentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.Is(err, domain.ErrPaymentNotRefundable):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, domain.ErrRefundExceedsPayment):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case errors.Is(err, domain.ErrInvalidMoney), errors.Is(err, domain.ErrTooManyDecimals), errors.Is(err, domain.ErrInvalidAmount):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(NewRefundResponse(refund)); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		log.Printf("could not encode response: %v", err)
+	}
+}
+
+func (h *RefundHandler) GetRefundHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	refund, err := h.getRefund.Execute(r.Context(), r.PathValue("id"))
+	if errors.Is(err, domain.ErrRefundNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(NewRefundResponse(refund)); err != nil {
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		log.Printf("could not encode response: %v", err)
+	}
+}
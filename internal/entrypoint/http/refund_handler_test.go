@@ -0,0 +1,246 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/mmarias/golearn/internal/infraestructure/database"
+	"github.com/mmarias/golearn/internal/infraestructure/idempotency"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockCreateRefund struct {
+	mock.Mock
+}
+
+func (m *MockCreateRefund) Execute(ctx context.Context, paymentId, amount string) (domain.Refund, error) {
+	args := m.Called(ctx, paymentId, amount)
+	return args.Get(0).(domain.Refund), args.Error(1)
+}
+
+func TestRefundHandler_CreateRefundHandler(t *testing.T) {
+	refund := domain.Refund{
+		ID:           "refund-1",
+		PaymentID:    "payment-123",
+		WalletID:     "wallet-456",
+		Amount:       domain.NewMoney(25_00, "EUR"),
+		WalletAmount: domain.NewMoney(27_00, "USD"),
+		Status:       domain.RefundStatusPending,
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		setupMocks func(createRefund *MockCreateRefund)
+		// err is returned by the use case when there is no setupMocks.
+		err                error
+		expectedStatusCode int
+	}{
+		{
+			name: "partial refund",
+			body: `{"amount":"25.00"}`,
+			setupMocks: func(createRefund *MockCreateRefund) {
+				createRefund.On("Execute", mock.Anything, "payment-123", "25.00").Return(refund, nil).Once()
+			},
+			expectedStatusCode: http.StatusAccepted,
+		},
+		{
+			name: "full refund without a body",
+			setupMocks: func(createRefund *MockCreateRefund) {
+				createRefund.On("Execute", mock.Anything, "payment-123", "").Return(refund, nil).Once()
+			},
+			expectedStatusCode: http.StatusAccepted,
+		},
+		{
+			name:               "invalid body",
+			body:               `{"amount":`,
+			setupMocks:         func(createRefund *MockCreateRefund) {},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{name: "payment not found", err: domain.ErrPaymentNotFound, expectedStatusCode: http.StatusNotFound},
+		{name: "payment not refundable", err: domain.ErrPaymentNotRefundable, expectedStatusCode: http.StatusConflict},
+		{name: "exceeds the payment", err: domain.ErrRefundExceedsPayment, expectedStatusCode: http.StatusUnprocessableEntity},
+		{name: "too many decimals", err: domain.ErrTooManyDecimals, expectedStatusCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createRefundMock := new(MockCreateRefund)
+			if tt.setupMocks != nil {
+				tt.setupMocks(createRefundMock)
+			} else {
+				createRefundMock.On("Execute", mock.Anything, "payment-123", "1.00").Return(domain.Refund{}, fmt.Errorf("refunding: %w", tt.err)).Once()
+				tt.body = `{"amount":"1.00"}`
+			}
+
+			mux := http.NewServeMux()
+			RegisterRoutes(mux, NewPaymentHandler(nil, nil, nil, nil, nil), NewWalletHandler(nil), NewRefundHandler(createRefundMock, nil, idempotency.NewStore(database.OpenInMemory(), time.Minute)), NewGatewayCallbackHandler(nil), http.NotFoundHandler())
+
+			req := httptest.NewRequest(http.MethodPost, "/payments/payment-123/refunds", bytes.NewBufferString(tt.body))
+			req.Header.Set("X-Idempotent-Key", "key-1")
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			if rr.Code == http.StatusAccepted {
+				var res RefundResponse
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+				assert.Equal(t, "refund-1", res.ID)
+				assert.Equal(t, "25.00", res.Amount)
+				assert.Equal(t, "27.00", res.WalletAmount)
+				assert.Equal(t, "USD", res.WalletCurrency)
+				assert.Equal(t, "PENDING", res.Status)
+			}
+
+			createRefundMock.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRefundHandler_CreateRefundHandler_ReplaysRetries(t *testing.T) {
+	createRefundMock := new(MockCreateRefund)
+	createRefundMock.On("Execute", mock.Anything, "payment-123", "").Return(domain.Refund{ID: "refund-1"}, nil).Once()
+
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, NewPaymentHandler(nil, nil, nil, nil, nil), NewWalletHandler(nil), NewRefundHandler(createRefundMock, nil, idempotency.NewStore(database.OpenInMemory(), time.Minute)), NewGatewayCallbackHandler(nil), http.NotFoundHandler())
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/payments/payment-123/refunds", nil)
+		req.Header.Set("X-Idempotent-Key", "key-1")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+	}
+
+	createRefundMock.AssertExpectations(t)
+}
+
+func TestRefundHandler_CreateRefundHandler_KeyReusedForAnotherPayment(t *testing.T) {
+	createRefundMock := new(MockCreateRefund)
+	createRefundMock.On("Execute", mock.Anything, "payment-123", "4.00").Return(domain.Refund{ID: "refund-1", PaymentID: "payment-123"}, nil).Once()
+
+	mux := http.NewServeMux()
+	RegisterRoutes(mux, NewPaymentHandler(nil, nil, nil, nil, nil), NewWalletHandler(nil), NewRefundHandler(createRefundMock, nil, idempotency.NewStore(database.OpenInMemory(), time.Minute)), NewGatewayCallbackHandler(nil), http.NotFoundHandler())
+
+	codes := map[string]int{}
+	for _, paymentId := range []string{"payment-123", "payment-456"} {
+		req := httptest.NewRequest(http.MethodPost, "/payments/"+paymentId+"/refunds", bytes.NewBufferString(`{"amount":"4.00"}`))
+		req.Header.Set("X-Idempotent-Key", "key-1")
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		codes[paymentId] = rr.Code
+		assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+	}
+
+	assert.Equal(t, map[string]int{"payment-123": http.StatusAccepted, "payment-456": http.StatusUnprocessableEntity}, codes)
+	createRefundMock.AssertExpectations(t)
+}
+
+func TestRefundHandler_CreateRefundHandler_FromBody(t *testing.T) {
+	tests := []struct {
+		name               string
+		body               string
+		setupMocks         func(createRefund *MockCreateRefund)
+		expectedStatusCode int
+	}{
+		{
+			name: "payment in the body",
+			body: `{"payment_id":"payment-123","amount":"25.00"}`,
+			setupMocks: func(createRefund *MockCreateRefund) {
+				createRefund.On("Execute", mock.Anything, "payment-123", "25.00").Return(domain.Refund{ID: "refund-1"}, nil).Once()
+			},
+			expectedStatusCode: http.StatusAccepted,
+		},
+		{
+			name:               "missing payment",
+			body:               `{"amount":"25.00"}`,
+			setupMocks:         func(createRefund *MockCreateRefund) {},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			createRefundMock := new(MockCreateRefund)
+			tt.setupMocks(createRefundMock)
+
+			mux := http.NewServeMux()
+			RegisterRoutes(mux, NewPaymentHandler(nil, nil, nil, nil, nil), NewWalletHandler(nil), NewRefundHandler(createRefundMock, nil, idempotency.NewStore(database.OpenInMemory(), time.Minute)), NewGatewayCallbackHandler(nil), http.NotFoundHandler())
+
+			req := httptest.NewRequest(http.MethodPost, "/refunds", bytes.NewBufferString(tt.body))
+			req.Header.Set("X-Idempotent-Key", "key-1")
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			createRefundMock.AssertExpectations(t)
+		})
+	}
+}
+
+type MockGetRefund struct {
+	mock.Mock
+}
+
+func (m *MockGetRefund) Execute(ctx context.Context, refundId string) (domain.Refund, error) {
+	args := m.Called(ctx, refundId)
+	return args.Get(0).(domain.Refund), args.Error(1)
+}
+
+func TestRefundHandler_GetRefundHandler(t *testing.T) {
+	tests := []struct {
+		name               string
+		refund             domain.Refund
+		err                error
+		expectedStatusCode int
+	}{
+		{
+			name: "found",
+			refund: domain.Refund{
+				ID:           "refund-1",
+				PaymentID:    "payment-123",
+				Amount:       domain.NewMoney(25_00, "EUR"),
+				WalletAmount: domain.NewMoney(27_00, "USD"),
+				Status:       domain.RefundStatusFailed,
+				Reason:       "declined",
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{name: "not found", err: domain.ErrRefundNotFound, expectedStatusCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getRefundMock := new(MockGetRefund)
+			getRefundMock.On("Execute", mock.Anything, "refund-1").Return(tt.refund, tt.err).Once()
+
+			mux := http.NewServeMux()
+			RegisterRoutes(mux, NewPaymentHandler(nil, nil, nil, nil, nil), NewWalletHandler(nil), NewRefundHandler(nil, getRefundMock, nil), NewGatewayCallbackHandler(nil), http.NotFoundHandler())
+
+			req := httptest.NewRequest(http.MethodGet, "/refunds/refund-1", nil)
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			if rr.Code == http.StatusOK {
+				var res RefundResponse
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &res))
+				assert.Equal(t, "refund-1", res.ID)
+				assert.Equal(t, "FAILED", res.Status)
+				assert.Equal(t, "declined", res.Reason)
+			}
+			getRefundMock.AssertExpectations(t)
+		})
+	}
+}
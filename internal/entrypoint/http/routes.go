@@ -4,6 +4,22 @@ import (
 	"net/http"
 )
 
-func RegisterRoutes(mux *http.ServeMux, paymentHandler *PaymentHandler) {
+func RegisterRoutes(mux *http.ServeMux, paymentHandler *PaymentHandler, walletHandler *WalletHandler, refundHandler *RefundHandler, gatewayCallbackHandler *GatewayCallbackHandler, metricsHandler http.Handler) {
 	mux.HandleFunc("POST /payments", paymentHandler.CreatePaymentHandler)
+	mux.HandleFunc("GET /payments", paymentHandler.ListPaymentsHandler)
+	mux.HandleFunc("GET /payments/{id}", paymentHandler.GetPaymentHandler)
+	mux.HandleFunc("POST /payments/{id}/cancel", paymentHandler.CancelPaymentHandler)
+	mux.HandleFunc("POST /payments/{id}/refunds", refundHandler.CreateRefundHandler)
+
+	mux.HandleFunc("POST /refunds", refundHandler.CreateRefundHandler)
+	mux.HandleFunc("GET /refunds/{id}", refundHandler.GetRefundHandler)
+
+	mux.HandleFunc("POST /accounts/{id}/holds", walletHandler.CreateHoldHandler)
+	mux.HandleFunc("PATCH /holds/{id}/release", walletHandler.ReleaseHoldHandler)
+	mux.HandleFunc("POST /transactions", walletHandler.CreateTransactionHandler)
+	mux.HandleFunc("PATCH /transactions/revert/{id}", walletHandler.RevertTransactionHandler)
+
+	mux.HandleFunc("POST /gateway/callbacks/{provider}", gatewayCallbackHandler.CallbackHandler)
+
+	mux.Handle("GET /metrics", metricsHandler)
 }
@@ -11,8 +11,11 @@ import (
 func TestRegisterRoutes(t *testing.T) {
 	mux := http.NewServeMux()
 	paymentHandler := &PaymentHandler{} // Using a dummy handler
+	walletHandler := &WalletHandler{}
+	refundHandler := &RefundHandler{}
+	gatewayCallbackHandler := &GatewayCallbackHandler{}
 
-	RegisterRoutes(mux, paymentHandler)
+	RegisterRoutes(mux, paymentHandler, walletHandler, refundHandler, gatewayCallbackHandler, http.NotFoundHandler())
 
 	// Test that the route is registered
 	req := httptest.NewRequest(http.MethodPost, "/payments", nil)
@@ -24,4 +27,22 @@ func TestRegisterRoutes(t *testing.T) {
 	// A more sophisticated test could involve a mock handler that sets a flag.
 	// For now, we'll just check that it doesn't return 404.
 	assert.NotEqual(t, http.StatusNotFound, rr.Code, "should have registered the /payments route")
+
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/payments"},
+		{http.MethodGet, "/payments/payment-123"},
+		{http.MethodPost, "/payments/payment-123/cancel"},
+		{http.MethodPost, "/payments/payment-123/refunds"},
+		{http.MethodPost, "/refunds"},
+		{http.MethodGet, "/refunds/refund-1"},
+		{http.MethodPost, "/accounts/1/holds"},
+		{http.MethodPatch, "/holds/hold-123/release"},
+		{http.MethodPost, "/transactions"},
+		{http.MethodPatch, "/transactions/revert/tx-123"},
+		{http.MethodPost, "/gateway/callbacks/simulated"},
+		{http.MethodGet, "/metrics"},
+	} {
+		_, pattern := mux.Handler(httptest.NewRequest(route.method, route.path, nil))
+		assert.NotEmpty(t, pattern, "should have registered %s %s", route.method, route.path)
+	}
 }
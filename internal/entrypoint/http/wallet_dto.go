@@ -0,0 +1,94 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+// HoldRequest takes the amount as a decimal in the currency of the account.
+type HoldRequest struct {
+	Amount json.Number `json:"amount"`
+	Reason string      `json:"reason"`
+}
+
+func (t *HoldRequest) Validate() error {
+	if t.Amount == "" {
+		return fmt.Errorf("invalid amount")
+	}
+
+	return nil
+}
+
+type HoldResponse struct {
+	ID        string     `json:"id"`
+	AccountID string     `json:"account_id"`
+	Amount    string     `json:"amount"`
+	Currency  string     `json:"currency"`
+	Reason    string     `json:"reason,omitempty"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+func NewHoldResponse(h domain.Hold) HoldResponse {
+	return HoldResponse{
+		ID:        h.ID,
+		AccountID: h.WalletID,
+		Amount:    h.Amount.Decimal(),
+		Currency:  h.Amount.Currency,
+		Reason:    h.Reason,
+		Status:    string(h.Status),
+		CreatedAt: h.CreatedAt,
+		UpdatedAt: h.UpdatedAt,
+	}
+}
+
+// TransactionRequest takes the amount as a decimal in the currency of the origin account.
+type TransactionRequest struct {
+	OriginAccountID      string      `json:"origin_account_id"`
+	DestinationAccountID string      `json:"destination_account_id"`
+	Amount               json.Number `json:"amount"`
+}
+
+func (t *TransactionRequest) Validate() error {
+	if t.OriginAccountID == "" {
+		return fmt.Errorf("missing origin_account_id")
+	}
+
+	if t.DestinationAccountID == "" {
+		return fmt.Errorf("missing destination_account_id")
+	}
+
+	if t.Amount == "" {
+		return fmt.Errorf("invalid amount")
+	}
+
+	return nil
+}
+
+type TransactionResponse struct {
+	ID                   string     `json:"id"`
+	OriginAccountID      string     `json:"origin_account_id"`
+	DestinationAccountID string     `json:"destination_account_id"`
+	Amount               string     `json:"amount"`
+	Currency             string     `json:"currency"`
+	Status               string     `json:"status"`
+	CreatedAt            time.Time  `json:"created_at"`
+	RevertedAt           *time.Time `json:"reverted_at,omitempty"`
+}
+
+func NewTransactionResponse(t domain.Transaction) TransactionResponse {
+	return TransactionResponse{
+		ID:                   t.ID,
+		OriginAccountID:      t.OriginWalletID,
+		DestinationAccountID: t.DestinationWalletID,
+		Amount:               t.Amount.Decimal(),
+		Currency:             t.Amount.Currency,
+		Status:               string(t.Status),
+		CreatedAt:            t.CreatedAt,
+		RevertedAt:           t.RevertedAt,
+	}
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+type walletImpl interface {
+	HoldFunds(ctx context.Context, walletId string, amount string, reason string) (domain.Hold, error)
+	Release(ctx context.Context, holdId string) (domain.Hold, error)
+	Transfer(ctx context.Context, originWalletId, destinationWalletId string, amount string) (domain.Transaction, error)
+	Revert(ctx context.Context, transactionId string) (domain.Transaction, error)
+}
+
+// WalletHandler exposes the accounts (wallets), holds and transactions.
+type WalletHandler struct {
+	wallets walletImpl
+}
+
+func NewWalletHandler(wallets walletImpl) *WalletHandler {
+	return &WalletHandler{
+		wallets: wallets,
+	}
+}
+
+func (h *WalletHandler) CreateHoldHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req HoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hold, err := h.wallets.HoldFunds(r.Context(), r.PathValue("id"), req.Amount.String(), req.Reason)
+	if err != nil {
+		http.Error(w, err.Error(), walletErrorStatus(err))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, NewHoldResponse(hold))
+}
+
+func (h *WalletHandler) ReleaseHoldHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	hold, err := h.wallets.Release(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), walletErrorStatus(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewHoldResponse(hold))
+}
+
+func (h *WalletHandler) CreateTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req TransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	transaction, err := h.wallets.Transfer(r.Context(), req.OriginAccountID, req.DestinationAccountID, req.Amount.String())
+	if err != nil {
+		http.Error(w, err.Error(), walletErrorStatus(err))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, NewTransactionResponse(transaction))
+}
+
+func (h *WalletHandler) RevertTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	transaction, err := h.wallets.Revert(r.Context(), r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), walletErrorStatus(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, NewTransactionResponse(transaction))
+}
+
+func walletErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrWalletNotFound),
+		errors.Is(err, domain.ErrHoldNotFound),
+		errors.Is(err, domain.ErrTransactionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrInvalidAmount),
+		errors.Is(err, domain.ErrInvalidMoney),
+		errors.Is(err, domain.ErrTooManyDecimals),
+		errors.Is(err, domain.ErrCurrencyMismatch),
+		errors.Is(err, domain.ErrSameWallet):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrInsufficientFunds):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, domain.ErrHoldNotActive),
+		errors.Is(err, domain.ErrTransactionReverted),
+		errors.Is(err, domain.ErrWalletVersionConflict):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("could not encode response: %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockWallet is a mock for the walletImpl interface
+type MockWallet struct {
+	mock.Mock
+}
+
+func (m *MockWallet) HoldFunds(ctx context.Context, walletId string, amount string, reason string) (domain.Hold, error) {
+	args := m.Called(walletId, amount, reason)
+	return args.Get(0).(domain.Hold), args.Error(1)
+}
+
+func (m *MockWallet) Release(ctx context.Context, holdId string) (domain.Hold, error) {
+	args := m.Called(holdId)
+	return args.Get(0).(domain.Hold), args.Error(1)
+}
+
+func (m *MockWallet) Transfer(ctx context.Context, originWalletId, destinationWalletId string, amount string) (domain.Transaction, error) {
+	args := m.Called(originWalletId, destinationWalletId, amount)
+	return args.Get(0).(domain.Transaction), args.Error(1)
+}
+
+func (m *MockWallet) Revert(ctx context.Context, transactionId string) (domain.Transaction, error) {
+	args := m.Called(transactionId)
+	return args.Get(0).(domain.Transaction), args.Error(1)
+}
+
+func TestWalletHandler(t *testing.T) {
+	createdAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	hold := domain.Hold{ID: "hold-1", WalletID: "1", Amount: domain.NewMoney(400_00, "USD"), Reason: "test", Status: domain.HoldStatusActive, CreatedAt: createdAt}
+	transaction := domain.Transaction{ID: "tx-1", OriginWalletID: "1", DestinationWalletID: "2", Amount: domain.NewMoney(50_00, "USD"), Status: domain.TransactionStatusCompleted, CreatedAt: createdAt}
+
+	tests := []struct {
+		name                 string
+		method               string
+		path                 string
+		body                 string
+		setupMocks           func(wallet *MockWallet)
+		expectedStatusCode   int
+		expectedResponseBody string
+	}{
+		{
+			name:   "create hold",
+			method: http.MethodPost,
+			path:   "/accounts/1/holds",
+			body:   `{"amount":400,"reason":"test"}`,
+			setupMocks: func(wallet *MockWallet) {
+				wallet.On("HoldFunds", "1", "400", "test").Return(hold, nil)
+			},
+			expectedStatusCode:   http.StatusCreated,
+			expectedResponseBody: `{"id":"hold-1","account_id":"1","amount":"400.00","currency":"USD","reason":"test","status":"ACTIVE","created_at":"2025-01-02T03:04:05Z"}` + "\n",
+		},
+		{
+			name:                 "create hold without amount",
+			method:               http.MethodPost,
+			path:                 "/accounts/1/holds",
+			body:                 `{"reason":"test"}`,
+			setupMocks:           func(wallet *MockWallet) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "invalid amount\n",
+		},
+		{
+			name:   "create hold with more decimals than the currency allows",
+			method: http.MethodPost,
+			path:   "/accounts/1/holds",
+			body:   `{"amount":"4.001"}`,
+			setupMocks: func(wallet *MockWallet) {
+				wallet.On("HoldFunds", "1", "4.001", "").Return(domain.Hold{}, domain.ErrTooManyDecimals)
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "amount has more decimals than the currency allows\n",
+		},
+		{
+			name:   "create hold without funds",
+			method: http.MethodPost,
+			path:   "/accounts/1/holds",
+			body:   `{"amount":4000}`,
+			setupMocks: func(wallet *MockWallet) {
+				wallet.On("HoldFunds", "1", "4000", "").Return(domain.Hold{}, domain.ErrInsufficientFunds)
+			},
+			expectedStatusCode:   http.StatusUnprocessableEntity,
+			expectedResponseBody: "insufficient funds\n",
+		},
+		{
+			name:   "release hold",
+			method: http.MethodPatch,
+			path:   "/holds/hold-1/release",
+			setupMocks: func(wallet *MockWallet) {
+				released := hold
+				released.Status = domain.HoldStatusReleased
+				wallet.On("Release", "hold-1").Return(released, nil)
+			},
+			expectedStatusCode:   http.StatusOK,
+			expectedResponseBody: `{"id":"hold-1","account_id":"1","amount":"400.00","currency":"USD","reason":"test","status":"RELEASED","created_at":"2025-01-02T03:04:05Z"}` + "\n",
+		},
+		{
+			name:   "release unknown hold",
+			method: http.MethodPatch,
+			path:   "/holds/hold-2/release",
+			setupMocks: func(wallet *MockWallet) {
+				wallet.On("Release", "hold-2").Return(domain.Hold{}, domain.ErrHoldNotFound)
+			},
+			expectedStatusCode:   http.StatusNotFound,
+			expectedResponseBody: "hold not found\n",
+		},
+		{
+			name:   "create transaction",
+			method: http.MethodPost,
+			path:   "/transactions",
+			body:   `{"origin_account_id":"1","destination_account_id":"2","amount":50}`,
+			setupMocks: func(wallet *MockWallet) {
+				wallet.On("Transfer", "1", "2", "50").Return(transaction, nil)
+			},
+			expectedStatusCode:   http.StatusCreated,
+			expectedResponseBody: `{"id":"tx-1","origin_account_id":"1","destination_account_id":"2","amount":"50.00","currency":"USD","status":"COMPLETED","created_at":"2025-01-02T03:04:05Z"}` + "\n",
+		},
+		{
+			name:                 "create transaction without origin",
+			method:               http.MethodPost,
+			path:                 "/transactions",
+			body:                 `{"destination_account_id":"2","amount":50}`,
+			setupMocks:           func(wallet *MockWallet) {},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "missing origin_account_id\n",
+		},
+		{
+			name:   "create transaction to the same account",
+			method: http.MethodPost,
+			path:   "/transactions",
+			body:   `{"origin_account_id":"2","destination_account_id":"2","amount":500}`,
+			setupMocks: func(wallet *MockWallet) {
+				wallet.On("Transfer", "2", "2", "500").Return(domain.Transaction{}, domain.ErrSameWallet)
+			},
+			expectedStatusCode:   http.StatusBadRequest,
+			expectedResponseBody: "origin and destination accounts must be different\n",
+		},
+		{
+			name:   "revert transaction twice",
+			method: http.MethodPatch,
+			path:   "/transactions/revert/tx-1",
+			setupMocks: func(wallet *MockWallet) {
+				wallet.On("Revert", "tx-1").Return(domain.Transaction{}, domain.ErrTransactionReverted)
+			},
+			expectedStatusCode:   http.StatusConflict,
+			expectedResponseBody: "transaction already reverted\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			walletMock := new(MockWallet)
+			tt.setupMocks(walletMock)
+
+			mux := http.NewServeMux()
+			RegisterRoutes(mux, NewPaymentHandler(nil, nil, nil, nil, nil), NewWalletHandler(walletMock), NewRefundHandler(nil, nil, nil), NewGatewayCallbackHandler(nil), http.NotFoundHandler())
+
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			assert.Equal(t, tt.expectedResponseBody, rr.Body.String())
+
+			walletMock.AssertExpectations(t)
+		})
+	}
+}
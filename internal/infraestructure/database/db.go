@@ -0,0 +1,236 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ErrReadOnlyTx is returned when a write is attempted inside a View transaction.
+var ErrReadOnlyTx = errors.New("database: write on read-only transaction")
+
+// DB is a small embedded key/value store in the spirit of BoltDB.
+// Records are grouped in buckets and every committed Update is written
+// atomically to a single JSON file, so a restart recovers the last state.
+// An empty path keeps the data in memory only.
+//
+// A commit rewrites the whole file, so its cost grows with everything the
+// store holds. Data written on every message, like the processed keys, belongs
+// in a store of its own, and buckets that only grow have to be pruned.
+type DB struct {
+	path    string
+	mu      sync.RWMutex
+	buckets map[string]map[string]json.RawMessage
+}
+
+// Open loads (or creates) the store persisted at path.
+func Open(path string) (*DB, error) {
+	db := &DB{
+		path:    path,
+		buckets: make(map[string]map[string]json.RawMessage),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("database: create dir: %w", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return db, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database: read %s: %w", path, err)
+	}
+
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &db.buckets); err != nil {
+			return nil, fmt.Errorf("database: decode %s: %w", path, err)
+		}
+	}
+
+	return db, nil
+}
+
+// OpenInMemory returns a store that is never persisted to disk.
+func OpenInMemory() *DB {
+	return &DB{
+		buckets: make(map[string]map[string]json.RawMessage),
+	}
+}
+
+// View runs fn inside a read-only transaction.
+func (db *DB) View(fn func(tx *Tx) error) error {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return fn(&Tx{db: db})
+}
+
+// Update runs fn inside a read-write transaction. Writes are only applied
+// when fn returns nil and the new state was persisted successfully.
+func (db *DB) Update(fn func(tx *Tx) error) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tx := &Tx{
+		db:       db,
+		writable: true,
+		pending:  make(map[string]map[string]json.RawMessage),
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return db.commit(tx.pending)
+}
+
+func (db *DB) commit(pending map[string]map[string]json.RawMessage) error {
+	if len(pending) == 0 {
+		return nil
+	}
+
+	next := make(map[string]map[string]json.RawMessage, len(db.buckets))
+	for name, bucket := range db.buckets {
+		next[name] = bucket
+	}
+
+	for name, changes := range pending {
+		bucket := make(map[string]json.RawMessage, len(db.buckets[name])+len(changes))
+		for k, v := range db.buckets[name] {
+			bucket[k] = v
+		}
+		for k, v := range changes {
+			if v == nil {
+				delete(bucket, k)
+				continue
+			}
+			bucket[k] = v
+		}
+		next[name] = bucket
+	}
+
+	if err := db.persist(next); err != nil {
+		return err
+	}
+
+	db.buckets = next
+	return nil
+}
+
+func (db *DB) persist(buckets map[string]map[string]json.RawMessage) error {
+	if db.path == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(buckets)
+	if err != nil {
+		return fmt.Errorf("database: encode: %w", err)
+	}
+
+	tmp := db.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return fmt.Errorf("database: write %s: %w", tmp, err)
+	}
+
+	if err := os.Rename(tmp, db.path); err != nil {
+		return fmt.Errorf("database: rename %s: %w", tmp, err)
+	}
+
+	return nil
+}
+
+// Tx is a transaction over the store. Writes are staged until the
+// surrounding Update commits.
+type Tx struct {
+	db       *DB
+	writable bool
+	pending  map[string]map[string]json.RawMessage
+}
+
+// Get decodes the record stored under key into v. It reports whether the key exists.
+func (tx *Tx) Get(bucket, key string, v any) (bool, error) {
+	raw, ok := tx.lookup(bucket, key)
+	if !ok {
+		return false, nil
+	}
+
+	if err := json.Unmarshal(raw, v); err != nil {
+		return true, fmt.Errorf("database: decode %s/%s: %w", bucket, key, err)
+	}
+
+	return true, nil
+}
+
+// Put stores v under key.
+func (tx *Tx) Put(bucket, key string, v any) error {
+	if !tx.writable {
+		return ErrReadOnlyTx
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("database: encode %s/%s: %w", bucket, key, err)
+	}
+
+	tx.stage(bucket, key, b)
+	return nil
+}
+
+// Delete removes key from bucket.
+func (tx *Tx) Delete(bucket, key string) error {
+	if !tx.writable {
+		return ErrReadOnlyTx
+	}
+
+	tx.stage(bucket, key, nil)
+	return nil
+}
+
+// ForEach calls fn for every record of bucket in key order, including the
+// writes staged in this transaction.
+func (tx *Tx) ForEach(bucket string, fn func(key string, raw json.RawMessage) error) error {
+	keys := make([]string, 0, len(tx.db.buckets[bucket])+len(tx.pending[bucket]))
+	for k := range tx.db.buckets[bucket] {
+		if _, staged := tx.pending[bucket][k]; !staged {
+			keys = append(keys, k)
+		}
+	}
+	for k, v := range tx.pending[bucket] {
+		if v != nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		raw, _ := tx.lookup(bucket, k)
+		if err := fn(k, raw); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (tx *Tx) lookup(bucket, key string) (json.RawMessage, bool) {
+	if changes, ok := tx.pending[bucket]; ok {
+		if v, staged := changes[key]; staged {
+			return v, v != nil
+		}
+	}
+
+	v, ok := tx.db.buckets[bucket][key]
+	return v, ok
+}
+
+func (tx *Tx) stage(bucket, key string, v json.RawMessage) {
+	if tx.pending[bucket] == nil {
+		tx.pending[bucket] = make(map[string]json.RawMessage)
+	}
+	tx.pending[bucket][key] = v
+}
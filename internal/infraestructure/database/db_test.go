@@ -0,0 +1,88 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type record struct {
+	Name string
+}
+
+func TestDB_UpdateIsPersisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+
+	db, err := Open(path)
+	require.NoError(t, err)
+
+	err = db.Update(func(tx *Tx) error {
+		return tx.Put("records", "1", record{Name: "first"})
+	})
+	require.NoError(t, err)
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+
+	var got record
+	err = reopened.View(func(tx *Tx) error {
+		found, err := tx.Get("records", "1", &got)
+		assert.True(t, found)
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "first", got.Name)
+}
+
+func TestDB_FailedUpdateIsDiscarded(t *testing.T) {
+	db := OpenInMemory()
+
+	err := db.Update(func(tx *Tx) error {
+		if err := tx.Put("records", "1", record{Name: "first"}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	assert.Error(t, err)
+
+	_ = db.View(func(tx *Tx) error {
+		found, _ := tx.Get("records", "1", &record{})
+		assert.False(t, found)
+		return nil
+	})
+}
+
+func TestDB_ForEachSeesStagedWrites(t *testing.T) {
+	db := OpenInMemory()
+
+	require.NoError(t, db.Update(func(tx *Tx) error {
+		_ = tx.Put("records", "a", record{Name: "a"})
+		return tx.Put("records", "b", record{Name: "b"})
+	}))
+
+	var keys []string
+	require.NoError(t, db.Update(func(tx *Tx) error {
+		_ = tx.Delete("records", "a")
+		_ = tx.Put("records", "c", record{Name: "c"})
+		return tx.ForEach("records", func(key string, _ json.RawMessage) error {
+			keys = append(keys, key)
+			return nil
+		})
+	}))
+
+	assert.Equal(t, []string{"b", "c"}, keys)
+}
+
+func TestDB_ViewIsReadOnly(t *testing.T) {
+	db := OpenInMemory()
+
+	err := db.View(func(tx *Tx) error {
+		return tx.Put("records", "1", record{})
+	})
+
+	assert.ErrorIs(t, err, ErrReadOnlyTx)
+}
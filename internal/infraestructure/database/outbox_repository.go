@@ -0,0 +1,93 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+const outboxBucket = "outbox"
+
+var errStopIteration = errors.New("stop iteration")
+
+type outboxRepository struct {
+	db *DB
+}
+
+func NewOutboxRepository(db *DB) *outboxRepository {
+	return &outboxRepository{
+		db: db,
+	}
+}
+
+func (r *outboxRepository) Add(messages ...domain.OutboxMessage) error {
+	return r.db.Update(func(tx *Tx) error {
+		return putOutbox(tx, messages)
+	})
+}
+
+func (r *outboxRepository) ListPending(limit int) ([]domain.OutboxMessage, error) {
+	messages := []domain.OutboxMessage{}
+
+	err := r.db.View(func(tx *Tx) error {
+		return tx.ForEach(outboxBucket, func(_ string, raw json.RawMessage) error {
+			if limit > 0 && len(messages) == limit {
+				return errStopIteration
+			}
+
+			var msg domain.OutboxMessage
+			if err := json.Unmarshal(raw, &msg); err != nil {
+				return err
+			}
+			messages = append(messages, msg)
+			return nil
+		})
+	})
+	if err != nil && !errors.Is(err, errStopIteration) {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (r *outboxRepository) MarkPublished(id string) error {
+	return r.db.Update(func(tx *Tx) error {
+		found, err := tx.Get(outboxBucket, id, &domain.OutboxMessage{})
+		if err != nil {
+			return err
+		}
+		if !found {
+			return domain.ErrOutboxMessageNotFound
+		}
+		return tx.Delete(outboxBucket, id)
+	})
+}
+
+func (r *outboxRepository) MarkFailed(id string, cause error) error {
+	return r.db.Update(func(tx *Tx) error {
+		var msg domain.OutboxMessage
+
+		found, err := tx.Get(outboxBucket, id, &msg)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return domain.ErrOutboxMessageNotFound
+		}
+
+		msg.Attempts++
+		msg.LastError = cause.Error()
+		return tx.Put(outboxBucket, id, msg)
+	})
+}
+
+// putOutbox stages messages in tx, so they commit together with the caller's changes.
+func putOutbox(tx *Tx, messages []domain.OutboxMessage) error {
+	for _, msg := range messages {
+		if err := tx.Put(outboxBucket, msg.ID, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutboxRepository_WrittenWithPayment(t *testing.T) {
+	db := OpenInMemory()
+	payments := NewPaymentRepository(db)
+	outbox := NewOutboxRepository(db)
+
+	first := domain.NewOutboxMessage(domain.TopicPaymentCreated, []byte("1"))
+	second := domain.NewOutboxMessage(domain.TopicPaymentCreated, []byte("2"))
+	require.NoError(t, payments.Create(domain.Payment{ID: "p-1"}, first))
+	require.NoError(t, payments.Create(domain.Payment{ID: "p-2"}, second))
+
+	// A rejected payment does not leave its message behind.
+	rejected := domain.NewOutboxMessage(domain.TopicPaymentCreated, []byte("3"))
+	require.ErrorIs(t, payments.Create(domain.Payment{ID: "p-1"}, rejected), domain.ErrPaymentAlreadyExists)
+
+	pending, err := outbox.ListPending(10)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, first.ID, pending[0].ID)
+	assert.Equal(t, second.ID, pending[1].ID)
+
+	limited, err := outbox.ListPending(1)
+	require.NoError(t, err)
+	assert.Len(t, limited, 1)
+
+	require.NoError(t, outbox.MarkFailed(first.ID, errors.New("bus down")))
+	require.NoError(t, outbox.MarkPublished(second.ID))
+
+	pending, err = outbox.ListPending(10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, 1, pending[0].Attempts)
+	assert.Equal(t, "bus down", pending[0].LastError)
+
+	assert.ErrorIs(t, outbox.MarkPublished(second.ID), domain.ErrOutboxMessageNotFound)
+}
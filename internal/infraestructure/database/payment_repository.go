@@ -1,15 +1,199 @@
 package database
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/mmarias/golearn/internal/domain"
 )
 
-type paymentRepository struct{}
+const paymentBucket = "payments"
+
+type paymentRepository struct {
+	db *DB
+}
+
+func NewPaymentRepository(db *DB) *paymentRepository {
+	return &paymentRepository{
+		db: db,
+	}
+}
+
+func (r *paymentRepository) Create(t domain.Payment, outbox ...domain.OutboxMessage) error {
+	return r.db.Update(func(tx *Tx) error {
+		found, err := tx.Get(paymentBucket, t.ID, &domain.Payment{})
+		if err != nil {
+			return err
+		}
+		if found {
+			return domain.ErrPaymentAlreadyExists
+		}
+
+		t.Version = 1
+		if err := tx.Put(paymentBucket, t.ID, t); err != nil {
+			return err
+		}
 
-func NewPaymentRepository() *paymentRepository {
-	return &paymentRepository{}
+		return putOutbox(tx, outbox)
+	})
 }
 
-func (r *paymentRepository) Create(t domain.Payment) error {
-	return nil
+func (r *paymentRepository) GetByID(id string) (domain.Payment, error) {
+	var payment domain.Payment
+
+	err := r.db.View(func(tx *Tx) error {
+		found, err := tx.Get(paymentBucket, id, &payment)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return domain.ErrPaymentNotFound
+		}
+		return nil
+	})
+
+	return payment, err
+}
+
+func (r *paymentRepository) UpdateStatus(id string, status domain.PaymentStatus, expectedVersion int) error {
+	return r.db.Update(func(tx *Tx) error {
+		var payment domain.Payment
+
+		found, err := tx.Get(paymentBucket, id, &payment)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return domain.ErrPaymentNotFound
+		}
+		if payment.Version != expectedVersion {
+			return fmt.Errorf("%w: expected version %d, got %d", domain.ErrPaymentVersionConflict, expectedVersion, payment.Version)
+		}
+
+		if err := payment.Transition(status); err != nil {
+			return err
+		}
+		payment.SetUpdatedAt()
+		payment.Version++
+
+		return tx.Put(paymentBucket, id, payment)
+	})
+}
+
+// ListByWallet returns the payments of a wallet, newest first.
+func (r *paymentRepository) ListByWallet(walletId string) ([]domain.Payment, error) {
+	return r.scan(func(p domain.Payment) bool {
+		return p.WalletID == walletId
+	})
+}
+
+func (r *paymentRepository) Search(filter domain.PaymentFilter) (domain.PaymentPage, error) {
+	var after *paymentCursor
+	if filter.Cursor != "" {
+		c, err := decodePaymentCursor(filter.Cursor)
+		if err != nil {
+			return domain.PaymentPage{}, err
+		}
+		after = &c
+	}
+
+	payments, err := r.scan(func(p domain.Payment) bool {
+		switch {
+		case filter.WalletID != "" && p.WalletID != filter.WalletID:
+			return false
+		case filter.Status != "" && p.Status != filter.Status:
+			return false
+		case !filter.From.IsZero() && p.CreatedAt.Before(filter.From):
+			return false
+		case !filter.To.IsZero() && !p.CreatedAt.Before(filter.To):
+			return false
+		case after != nil && !after.precedes(p):
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return domain.PaymentPage{}, err
+	}
+
+	page := domain.PaymentPage{Payments: payments}
+	if filter.Limit > 0 && len(payments) > filter.Limit {
+		page.Payments = payments[:filter.Limit]
+		last := page.Payments[filter.Limit-1]
+		page.NextCursor = paymentCursor{CreatedAt: last.CreatedAt, ID: last.ID}.encode()
+	}
+
+	return page, nil
+}
+
+// scan returns the payments accepted by match ordered by creation date, newest first.
+func (r *paymentRepository) scan(match func(domain.Payment) bool) ([]domain.Payment, error) {
+	payments := []domain.Payment{}
+
+	err := r.db.View(func(tx *Tx) error {
+		return tx.ForEach(paymentBucket, func(_ string, raw json.RawMessage) error {
+			var payment domain.Payment
+			if err := json.Unmarshal(raw, &payment); err != nil {
+				return err
+			}
+			if match(payment) {
+				payments = append(payments, payment)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(payments, func(i, j int) bool {
+		if payments[i].CreatedAt.Equal(payments[j].CreatedAt) {
+			return payments[i].ID > payments[j].ID
+		}
+		return payments[i].CreatedAt.After(payments[j].CreatedAt)
+	})
+
+	return payments, nil
+}
+
+// paymentCursor points at the last payment of a page.
+type paymentCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+func (c paymentCursor) encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// precedes reports whether p comes after the cursor in newest-first order.
+func (c paymentCursor) precedes(p domain.Payment) bool {
+	if p.CreatedAt.Equal(c.CreatedAt) {
+		return p.ID < c.ID
+	}
+	return p.CreatedAt.Before(c.CreatedAt)
+}
+
+func decodePaymentCursor(s string) (paymentCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return paymentCursor{}, domain.ErrInvalidPaymentCursor
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok || id == "" {
+		return paymentCursor{}, domain.ErrInvalidPaymentCursor
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return paymentCursor{}, domain.ErrInvalidPaymentCursor
+	}
+
+	return paymentCursor{CreatedAt: t, ID: id}, nil
 }
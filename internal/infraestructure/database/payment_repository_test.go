@@ -0,0 +1,115 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaymentRepository_CreateAndGet(t *testing.T) {
+	repo := NewPaymentRepository(OpenInMemory())
+
+	require.NoError(t, repo.Create(domain.Payment{ID: "p-1", WalletID: "w-1", Status: domain.PaymentStatusPending}))
+	assert.ErrorIs(t, repo.Create(domain.Payment{ID: "p-1"}), domain.ErrPaymentAlreadyExists)
+
+	got, err := repo.GetByID("p-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Version)
+
+	_, err = repo.GetByID("missing")
+	assert.ErrorIs(t, err, domain.ErrPaymentNotFound)
+}
+
+func TestPaymentRepository_UpdateStatusOptimisticLock(t *testing.T) {
+	repo := NewPaymentRepository(OpenInMemory())
+	require.NoError(t, repo.Create(domain.Payment{ID: "p-1", Status: domain.PaymentStatusPending}))
+
+	require.NoError(t, repo.UpdateStatus("p-1", domain.PaymentStatusFundsHeld, 1))
+
+	err := repo.UpdateStatus("p-1", domain.PaymentStatusFailed, 1)
+	assert.ErrorIs(t, err, domain.ErrPaymentVersionConflict)
+
+	got, err := repo.GetByID("p-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.PaymentStatusFundsHeld, got.Status)
+	assert.Equal(t, 2, got.Version)
+	assert.NotNil(t, got.UpdatedAt)
+}
+
+func TestPaymentRepository_UpdateStatusRecordsHistory(t *testing.T) {
+	repo := NewPaymentRepository(OpenInMemory())
+	require.NoError(t, repo.Create(domain.Payment{ID: "p-1", Status: domain.PaymentStatusPending}))
+
+	require.NoError(t, repo.UpdateStatus("p-1", domain.PaymentStatusFundsHeld, 1))
+	err := repo.UpdateStatus("p-1", domain.PaymentStatusCompleted, 2)
+	assert.ErrorIs(t, err, domain.ErrIllegalPaymentTransition)
+
+	got, err := repo.GetByID("p-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.PaymentStatusFundsHeld, got.Status)
+	assert.Equal(t, 2, got.Version)
+	require.Len(t, got.History, 1)
+	assert.Equal(t, domain.PaymentStatusPending, got.History[0].From)
+	assert.Equal(t, domain.PaymentStatusFundsHeld, got.History[0].To)
+}
+
+func TestPaymentRepository_ListByWallet(t *testing.T) {
+	repo := NewPaymentRepository(OpenInMemory())
+	now := time.Now().UTC()
+
+	require.NoError(t, repo.Create(domain.Payment{ID: "p-1", WalletID: "w-1", CreatedAt: now.Add(-time.Minute)}))
+	require.NoError(t, repo.Create(domain.Payment{ID: "p-2", WalletID: "w-1", CreatedAt: now}))
+	require.NoError(t, repo.Create(domain.Payment{ID: "p-3", WalletID: "w-2", CreatedAt: now}))
+
+	payments, err := repo.ListByWallet("w-1")
+	require.NoError(t, err)
+	require.Len(t, payments, 2)
+	assert.Equal(t, "p-2", payments[0].ID)
+	assert.Equal(t, "p-1", payments[1].ID)
+}
+
+func TestPaymentRepository_SearchPaginates(t *testing.T) {
+	repo := NewPaymentRepository(OpenInMemory())
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, id := range []string{"p-1", "p-2", "p-3", "p-4"} {
+		require.NoError(t, repo.Create(domain.Payment{
+			ID:        id,
+			WalletID:  "w-1",
+			Status:    domain.PaymentStatusCompleted,
+			CreatedAt: base.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+	require.NoError(t, repo.Create(domain.Payment{ID: "p-5", WalletID: "w-1", Status: domain.PaymentStatusFailed, CreatedAt: base}))
+
+	filter := domain.PaymentFilter{WalletID: "w-1", Status: domain.PaymentStatusCompleted, Limit: 3}
+
+	first, err := repo.Search(filter)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"p-4", "p-3", "p-2"}, paymentIDs(first.Payments))
+	require.NotEmpty(t, first.NextCursor)
+
+	filter.Cursor = first.NextCursor
+	second, err := repo.Search(filter)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"p-1"}, paymentIDs(second.Payments))
+	assert.Empty(t, second.NextCursor)
+
+	ranged, err := repo.Search(domain.PaymentFilter{From: base.Add(time.Hour), To: base.Add(3 * time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"p-3", "p-2"}, paymentIDs(ranged.Payments))
+
+	_, err = repo.Search(domain.PaymentFilter{Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, domain.ErrInvalidPaymentCursor)
+}
+
+func paymentIDs(payments []domain.Payment) []string {
+	ids := make([]string, 0, len(payments))
+	for _, p := range payments {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+const processedBucket = "processed"
+
+// processedRecord is a claimed key. Claims left in progress by an earlier
+// process, which stopped before completing or releasing them, are free again.
+type processedRecord struct {
+	Owner     string    `json:"owner"`
+	Done      bool      `json:"done"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type processedMessageRepository struct {
+	db    *DB
+	ttl   time.Duration
+	owner string
+	now   func() time.Time
+
+	// lastPrune is only used inside Update, which is serialized.
+	lastPrune time.Time
+}
+
+// NewProcessedMessageRepository keeps the processed keys for ttl.
+func NewProcessedMessageRepository(db *DB, ttl time.Duration) *processedMessageRepository {
+	return &processedMessageRepository{
+		db:    db,
+		ttl:   ttl,
+		owner: uuid.NewString(),
+		now:   time.Now,
+	}
+}
+
+func (r *processedMessageRepository) Claim(key string) error {
+	return r.db.Update(func(tx *Tx) error {
+		now := r.now()
+		if err := r.prune(tx, now); err != nil {
+			return err
+		}
+
+		var record processedRecord
+		found, err := tx.Get(processedBucket, key, &record)
+		if err != nil {
+			return err
+		}
+		if found && now.Before(record.ExpiresAt) && (record.Done || record.Owner == r.owner) {
+			return domain.ErrAlreadyProcessed
+		}
+
+		return tx.Put(processedBucket, key, processedRecord{Owner: r.owner, ExpiresAt: now.Add(r.ttl)})
+	})
+}
+
+func (r *processedMessageRepository) Complete(key string) error {
+	return r.db.Update(func(tx *Tx) error {
+		return tx.Put(processedBucket, key, processedRecord{Owner: r.owner, Done: true, ExpiresAt: r.now().Add(r.ttl)})
+	})
+}
+
+func (r *processedMessageRepository) Release(key string) error {
+	return r.db.Update(func(tx *Tx) error {
+		return tx.Delete(processedBucket, key)
+	})
+}
+
+// prune drops the expired keys, at most once a minute.
+func (r *processedMessageRepository) prune(tx *Tx, now time.Time) error {
+	if now.Sub(r.lastPrune) < time.Minute {
+		return nil
+	}
+	r.lastPrune = now
+
+	var expired []string
+	err := tx.ForEach(processedBucket, func(key string, raw json.RawMessage) error {
+		var record processedRecord
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return err
+		}
+		if !now.Before(record.ExpiresAt) {
+			expired = append(expired, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range expired {
+		if err := tx.Delete(processedBucket, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessedMessageRepository_ClaimIsAtomic(t *testing.T) {
+	repo := NewProcessedMessageRepository(OpenInMemory(), time.Minute)
+
+	var claimed atomic.Int32
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if repo.Claim("k") == nil {
+				claimed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), claimed.Load())
+}
+
+func TestProcessedMessageRepository_Lifecycle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golearn.db")
+	db, err := Open(path)
+	require.NoError(t, err)
+	repo := NewProcessedMessageRepository(db, time.Minute)
+
+	// A released claim is free again.
+	require.NoError(t, repo.Claim("released"))
+	require.NoError(t, repo.Release("released"))
+	require.NoError(t, repo.Claim("released"))
+
+	require.NoError(t, repo.Claim("done"))
+	require.NoError(t, repo.Complete("done"))
+	require.NoError(t, repo.Claim("crashed"))
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	restarted := NewProcessedMessageRepository(reopened, time.Minute)
+
+	// Processed keys survive a restart, claims of a stopped process do not.
+	assert.ErrorIs(t, restarted.Claim("done"), domain.ErrAlreadyProcessed)
+	assert.NoError(t, restarted.Claim("crashed"))
+	assert.ErrorIs(t, restarted.Claim("crashed"), domain.ErrAlreadyProcessed)
+
+	// Keys are forgotten after the TTL.
+	restarted.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	assert.NoError(t, restarted.Claim("done"))
+}
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+const refundBucket = "refunds"
+
+type refundRepository struct {
+	db *DB
+}
+
+// NewRefundRepository stores refunds in the same db as the refundable
+// payments, which it reads to check a refund fits in its payment.
+func NewRefundRepository(db *DB) *refundRepository {
+	return &refundRepository{
+		db: db,
+	}
+}
+
+func (r *refundRepository) Create(refund domain.Refund, outbox ...domain.OutboxMessage) error {
+	return r.db.Update(func(tx *Tx) error {
+		found, err := tx.Get(refundBucket, refund.ID, &domain.Refund{})
+		if err != nil {
+			return err
+		}
+		if found {
+			return domain.ErrRefundAlreadyExists
+		}
+
+		// Checked in the same transaction, so concurrent refunds of a payment
+		// cannot add up to more than its amount.
+		payment, err := getRefundablePayment(tx, refund.PaymentID)
+		if err != nil {
+			return err
+		}
+		refunds, err := listRefunds(tx, refund.PaymentID)
+		if err != nil {
+			return err
+		}
+		left, _ := domain.Refundable(payment, refunds)
+		if exceeds, _ := left.LessThan(refund.Amount); exceeds {
+			return fmt.Errorf("%w: %s left", domain.ErrRefundExceedsPayment, left)
+		}
+
+		if err := tx.Put(refundBucket, refund.ID, refund); err != nil {
+			return err
+		}
+
+		return putOutbox(tx, outbox)
+	})
+}
+
+func (r *refundRepository) GetByID(id string) (domain.Refund, error) {
+	var refund domain.Refund
+
+	err := r.db.View(func(tx *Tx) error {
+		found, err := tx.Get(refundBucket, id, &refund)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return domain.ErrRefundNotFound
+		}
+		return nil
+	})
+
+	return refund, err
+}
+
+func (r *refundRepository) ListByPayment(paymentId string) ([]domain.Refund, error) {
+	var refunds []domain.Refund
+
+	err := r.db.View(func(tx *Tx) error {
+		var err error
+		refunds, err = listRefunds(tx, paymentId)
+		return err
+	})
+
+	return refunds, err
+}
+
+func (r *refundRepository) UpdateStatus(id string, status domain.RefundStatus, reason string) error {
+	return r.db.Update(func(tx *Tx) error {
+		var refund domain.Refund
+
+		found, err := tx.Get(refundBucket, id, &refund)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return domain.ErrRefundNotFound
+		}
+
+		refund.SetStatus(status, reason)
+		return tx.Put(refundBucket, id, refund)
+	})
+}
+
+// listRefunds returns the refunds of a payment, oldest first.
+func listRefunds(tx *Tx, paymentId string) ([]domain.Refund, error) {
+	refunds := []domain.Refund{}
+
+	err := tx.ForEach(refundBucket, func(_ string, raw json.RawMessage) error {
+		var refund domain.Refund
+		if err := json.Unmarshal(raw, &refund); err != nil {
+			return err
+		}
+		if refund.PaymentID == paymentId {
+			refunds = append(refunds, refund)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(refunds, func(i, j int) bool {
+		return refunds[i].CreatedAt.Before(refunds[j].CreatedAt)
+	})
+
+	return refunds, nil
+}
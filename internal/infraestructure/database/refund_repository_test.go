@@ -0,0 +1,82 @@
+package database
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func completedPayment(t *testing.T, db *DB) domain.RefundablePayment {
+	payment := domain.RefundablePayment{
+		ID:           "p-1",
+		WalletID:     "w-1",
+		Amount:       domain.NewMoney(100_00, "USD"),
+		WalletAmount: domain.NewMoney(100_00, "USD"),
+		Status:       domain.PaymentStatusCompleted,
+	}
+	require.NoError(t, NewRefundablePaymentRepository(db).Add(payment))
+	return payment
+}
+
+func TestRefundRepository_CreateAndUpdate(t *testing.T) {
+	db := OpenInMemory()
+	payment := completedPayment(t, db)
+	refunds := NewRefundRepository(db)
+
+	refund, err := domain.NewRefund(payment, nil, domain.NewMoney(30_00, "USD"))
+	require.NoError(t, err)
+	msg := domain.NewOutboxMessage(domain.TopicRefundCreated, []byte("{}"))
+	require.NoError(t, refunds.Create(refund, msg))
+	assert.ErrorIs(t, refunds.Create(refund), domain.ErrRefundAlreadyExists)
+
+	pending, err := NewOutboxRepository(db).ListPending(10)
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+
+	require.NoError(t, refunds.UpdateStatus(refund.ID, domain.RefundStatusFailed, "gateway down"))
+	got, err := refunds.GetByID(refund.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RefundStatusFailed, got.Status)
+	assert.Equal(t, "gateway down", got.Reason)
+
+	listed, err := refunds.ListByPayment(payment.ID)
+	require.NoError(t, err)
+	assert.Len(t, listed, 1)
+
+	_, err = refunds.GetByID("missing")
+	assert.ErrorIs(t, err, domain.ErrRefundNotFound)
+}
+
+func TestRefundRepository_ConcurrentRefundsNeverExceedThePayment(t *testing.T) {
+	db := OpenInMemory()
+	payment := completedPayment(t, db)
+	refunds := NewRefundRepository(db)
+
+	var created, rejected atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Every refund is built before the others are stored.
+			refund, err := domain.NewRefund(payment, nil, domain.NewMoney(40_00, "USD"))
+			require.NoError(t, err)
+
+			err = refunds.Create(refund)
+			switch {
+			case err == nil:
+				created.Add(1)
+			case assert.ErrorIs(t, err, domain.ErrRefundExceedsPayment):
+				rejected.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(2), created.Load())
+	assert.Equal(t, int32(3), rejected.Load())
+}
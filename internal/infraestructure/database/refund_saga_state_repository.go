@@ -0,0 +1,67 @@
+package database
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+const refundSagaStateBucket = "refund_saga_states"
+
+type refundSagaStateRepository struct {
+	db *DB
+}
+
+func NewRefundSagaStateRepository(db *DB) *refundSagaStateRepository {
+	return &refundSagaStateRepository{
+		db: db,
+	}
+}
+
+// NewInMemoryRefundSagaStateRepository keeps refund saga states in memory only.
+func NewInMemoryRefundSagaStateRepository() *refundSagaStateRepository {
+	return NewRefundSagaStateRepository(OpenInMemory())
+}
+
+func (r *refundSagaStateRepository) Save(state domain.RefundSagaState) error {
+	return r.db.Update(func(tx *Tx) error {
+		return tx.Put(refundSagaStateBucket, state.RefundID, state)
+	})
+}
+
+func (r *refundSagaStateRepository) GetByRefundID(refundId string) (domain.RefundSagaState, error) {
+	var state domain.RefundSagaState
+
+	err := r.db.View(func(tx *Tx) error {
+		found, err := tx.Get(refundSagaStateBucket, refundId, &state)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return domain.ErrRefundSagaStateNotFound
+		}
+		return nil
+	})
+
+	return state, err
+}
+
+func (r *refundSagaStateRepository) ListExpired(now time.Time) ([]domain.RefundSagaState, error) {
+	var states []domain.RefundSagaState
+
+	err := r.db.View(func(tx *Tx) error {
+		return tx.ForEach(refundSagaStateBucket, func(_ string, raw json.RawMessage) error {
+			var state domain.RefundSagaState
+			if err := json.Unmarshal(raw, &state); err != nil {
+				return err
+			}
+			if state.Expired(now) {
+				states = append(states, state)
+			}
+			return nil
+		})
+	})
+
+	return states, err
+}
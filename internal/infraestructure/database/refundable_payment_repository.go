@@ -0,0 +1,66 @@
+package database
+
+import (
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+const refundablePaymentBucket = "refundable_payments"
+
+type refundablePaymentRepository struct {
+	db *DB
+}
+
+// NewRefundablePaymentRepository stores the payments the refunds service
+// knows, apart from the payments of the payment service.
+func NewRefundablePaymentRepository(db *DB) *refundablePaymentRepository {
+	return &refundablePaymentRepository{
+		db: db,
+	}
+}
+
+func (r *refundablePaymentRepository) Add(payment domain.RefundablePayment) error {
+	return r.db.Update(func(tx *Tx) error {
+		found, err := tx.Get(refundablePaymentBucket, payment.ID, &domain.RefundablePayment{})
+		if err != nil || found {
+			return err
+		}
+		return tx.Put(refundablePaymentBucket, payment.ID, payment)
+	})
+}
+
+func (r *refundablePaymentRepository) GetByID(id string) (domain.RefundablePayment, error) {
+	var payment domain.RefundablePayment
+
+	err := r.db.View(func(tx *Tx) error {
+		var err error
+		payment, err = getRefundablePayment(tx, id)
+		return err
+	})
+
+	return payment, err
+}
+
+func (r *refundablePaymentRepository) UpdateStatus(id string, status domain.PaymentStatus) error {
+	return r.db.Update(func(tx *Tx) error {
+		payment, err := getRefundablePayment(tx, id)
+		if err != nil {
+			return err
+		}
+
+		payment.Status = status
+		return tx.Put(refundablePaymentBucket, id, payment)
+	})
+}
+
+func getRefundablePayment(tx *Tx, id string) (domain.RefundablePayment, error) {
+	var payment domain.RefundablePayment
+
+	found, err := tx.Get(refundablePaymentBucket, id, &payment)
+	if err != nil {
+		return domain.RefundablePayment{}, err
+	}
+	if !found {
+		return domain.RefundablePayment{}, domain.ErrPaymentNotFound
+	}
+	return payment, nil
+}
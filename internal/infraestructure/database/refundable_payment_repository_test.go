@@ -0,0 +1,34 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefundablePaymentRepository(t *testing.T) {
+	payments := NewRefundablePaymentRepository(OpenInMemory())
+	payment := domain.RefundablePayment{
+		ID:           "p-1",
+		WalletID:     "w-1",
+		Amount:       domain.NewMoney(100_00, "USD"),
+		WalletAmount: domain.NewMoney(100_00, "USD"),
+		Status:       domain.PaymentStatusPending,
+	}
+
+	require.NoError(t, payments.Add(payment))
+	require.NoError(t, payments.UpdateStatus("p-1", domain.PaymentStatusCompleted))
+	// A redelivered payment.created does not undo the update.
+	require.NoError(t, payments.Add(payment))
+
+	got, err := payments.GetByID("p-1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.PaymentStatusCompleted, got.Status)
+	assert.Equal(t, payment.Amount, got.Amount)
+
+	_, err = payments.GetByID("missing")
+	assert.ErrorIs(t, err, domain.ErrPaymentNotFound)
+	assert.ErrorIs(t, payments.UpdateStatus("missing", domain.PaymentStatusCompleted), domain.ErrPaymentNotFound)
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+const sagaStateBucket = "saga_states"
+
+type sagaStateRepository struct {
+	db *DB
+}
+
+// NewSagaStateRepository stores saga states in db, so they survive restarts
+// when db was opened from a file.
+func NewSagaStateRepository(db *DB) *sagaStateRepository {
+	return &sagaStateRepository{
+		db: db,
+	}
+}
+
+// NewInMemorySagaStateRepository keeps saga states in memory only.
+func NewInMemorySagaStateRepository() *sagaStateRepository {
+	return NewSagaStateRepository(OpenInMemory())
+}
+
+func (r *sagaStateRepository) Save(state domain.SagaState) error {
+	return r.db.Update(func(tx *Tx) error {
+		return tx.Put(sagaStateBucket, state.PaymentID, state)
+	})
+}
+
+func (r *sagaStateRepository) GetByPaymentID(paymentId string) (domain.SagaState, error) {
+	var state domain.SagaState
+
+	err := r.db.View(func(tx *Tx) error {
+		found, err := tx.Get(sagaStateBucket, paymentId, &state)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return domain.ErrSagaStateNotFound
+		}
+		return nil
+	})
+
+	return state, err
+}
+
+func (r *sagaStateRepository) ListExpired(now time.Time) ([]domain.SagaState, error) {
+	var states []domain.SagaState
+
+	err := r.db.View(func(tx *Tx) error {
+		return tx.ForEach(sagaStateBucket, func(_ string, raw json.RawMessage) error {
+			var state domain.SagaState
+			if err := json.Unmarshal(raw, &state); err != nil {
+				return err
+			}
+			if state.Expired(now) {
+				states = append(states, state)
+			}
+			return nil
+		})
+	})
+
+	return states, err
+}
@@ -0,0 +1,25 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSagaStateRepository_ListExpired(t *testing.T) {
+	repo := NewInMemorySagaStateRepository()
+	now := time.Now().UTC()
+	past, future := now.Add(-time.Second), now.Add(time.Second)
+
+	require.NoError(t, repo.Save(domain.SagaState{PaymentID: "expired", SagaProgress: domain.SagaProgress{Deadline: &past}}))
+	require.NoError(t, repo.Save(domain.SagaState{PaymentID: "in-time", SagaProgress: domain.SagaProgress{Deadline: &future}}))
+	require.NoError(t, repo.Save(domain.SagaState{PaymentID: "no-deadline"}))
+
+	states, err := repo.ListExpired(now)
+	require.NoError(t, err)
+	require.Len(t, states, 1)
+	assert.Equal(t, "expired", states[0].PaymentID)
+}
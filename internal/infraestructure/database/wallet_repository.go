@@ -0,0 +1,197 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+const (
+	walletBucket            = "wallets"
+	holdBucket              = "holds"
+	ledgerEntryBucket       = "ledger_entries"
+	ledgerTransactionBucket = "ledger_transactions"
+	transactionBucket       = "transactions"
+)
+
+type walletRepository struct {
+	db *DB
+}
+
+func NewWalletRepository(db *DB) *walletRepository {
+	return &walletRepository{
+		db: db,
+	}
+}
+
+// NewInMemoryWalletRepository keeps wallets in memory only.
+func NewInMemoryWalletRepository() *walletRepository {
+	return NewWalletRepository(OpenInMemory())
+}
+
+func (r *walletRepository) Create(w domain.Wallet) error {
+	return r.db.Update(func(tx *Tx) error {
+		found, err := tx.Get(walletBucket, w.ID, &domain.Wallet{})
+		if err != nil {
+			return err
+		}
+		if found {
+			return domain.ErrWalletAlreadyExists
+		}
+
+		w.Version = 1
+		return tx.Put(walletBucket, w.ID, w)
+	})
+}
+
+func (r *walletRepository) GetByID(id string) (domain.Wallet, error) {
+	var wallet domain.Wallet
+
+	err := r.db.View(func(tx *Tx) error {
+		found, err := tx.Get(walletBucket, id, &wallet)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return domain.ErrWalletNotFound
+		}
+		return nil
+	})
+
+	return wallet, err
+}
+
+func (r *walletRepository) GetHold(id string) (domain.Hold, error) {
+	var hold domain.Hold
+
+	err := r.db.View(func(tx *Tx) error {
+		found, err := tx.Get(holdBucket, id, &hold)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return domain.ErrHoldNotFound
+		}
+		return nil
+	})
+
+	return hold, err
+}
+
+func (r *walletRepository) GetTransaction(id string) (domain.Transaction, error) {
+	var transaction domain.Transaction
+
+	err := r.db.View(func(tx *Tx) error {
+		found, err := tx.Get(transactionBucket, id, &transaction)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return domain.ErrTransactionNotFound
+		}
+		return nil
+	})
+
+	return transaction, err
+}
+
+func (r *walletRepository) HasLedgerTransaction(id string) (bool, error) {
+	var found bool
+
+	err := r.db.View(func(tx *Tx) error {
+		var err error
+		found, err = tx.Get(ledgerTransactionBucket, id, new(bool))
+		return err
+	})
+
+	return found, err
+}
+
+func (r *walletRepository) ListLedgerEntries(walletId string) ([]domain.LedgerEntry, error) {
+	accounts := map[string]bool{
+		domain.LedgerAccountAvailable(walletId): true,
+		domain.LedgerAccountHeld(walletId):      true,
+	}
+	entries := []domain.LedgerEntry{}
+
+	err := r.db.View(func(tx *Tx) error {
+		return tx.ForEach(ledgerEntryBucket, func(_ string, raw json.RawMessage) error {
+			var entry domain.LedgerEntry
+			if err := json.Unmarshal(raw, &entry); err != nil {
+				return err
+			}
+			if accounts[entry.Account] {
+				entries = append(entries, entry)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (r *walletRepository) Save(change domain.WalletChange) error {
+	return r.db.Update(func(tx *Tx) error {
+		for _, wallet := range change.Wallets {
+			var stored domain.Wallet
+
+			found, err := tx.Get(walletBucket, wallet.ID, &stored)
+			if err != nil {
+				return err
+			}
+			if !found {
+				return domain.ErrWalletNotFound
+			}
+			if stored.Version != wallet.Version {
+				return fmt.Errorf("%w: %s expected version %d, got %d", domain.ErrWalletVersionConflict, wallet.ID, wallet.Version, stored.Version)
+			}
+
+			wallet.SetUpdatedAt()
+			wallet.Version++
+			if err := tx.Put(walletBucket, wallet.ID, wallet); err != nil {
+				return err
+			}
+		}
+
+		if change.Hold != nil {
+			if err := tx.Put(holdBucket, change.Hold.ID, change.Hold); err != nil {
+				return err
+			}
+		}
+
+		if change.Transaction != nil {
+			if err := tx.Put(transactionBucket, change.Transaction.ID, change.Transaction); err != nil {
+				return err
+			}
+		}
+
+		// A transaction id is written once, which makes replayed operations fail
+		// instead of moving the money twice.
+		recorded := map[string]bool{}
+		for _, entry := range change.Entries {
+			if !recorded[entry.TransactionID] {
+				exists, err := tx.Get(ledgerTransactionBucket, entry.TransactionID, new(bool))
+				if err != nil {
+					return err
+				}
+				if exists {
+					return fmt.Errorf("%w: %s", domain.ErrLedgerTransactionExists, entry.TransactionID)
+				}
+				if err := tx.Put(ledgerTransactionBucket, entry.TransactionID, true); err != nil {
+					return err
+				}
+				recorded[entry.TransactionID] = true
+			}
+
+			if err := tx.Put(ledgerEntryBucket, entry.ID, entry); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -2,55 +2,213 @@ package eventbus
 
 import (
 	"context"
+	"encoding/json"
+	"hash/fnv"
 	"log"
 	"sync"
 )
 
 // HandlerFunc is the type for functions that handle events.
-type HandlerFunc func(ctx context.Context, message []byte)
+// Returning an error tells the bus the message was not processed.
+type HandlerFunc func(ctx context.Context, message []byte) error
 
 type Client interface {
 	Publish(ctx context.Context, topic string, message []byte) error
-	Subscribe(topic string, handler HandlerFunc)
+	// Subscribe registers handler on topic. The subscriber name identifies the
+	// consumer, so buses that track progress can do it per subscriber.
+	Subscribe(topic, subscriber string, handler HandlerFunc)
 }
 
 // MemoryBus is an in-memory implementation of an event bus for demonstration purposes.
 // It implements the publisher.Client interface.
 type MemoryBus struct {
-	handlers map[string][]HandlerFunc
+	handlers map[string][]subscription
 	mu       sync.RWMutex
+
+	// workers is set in ordering mode, see WithOrdering.
+	workers []*worker
+	wg      sync.WaitGroup
+}
+
+type subscription struct {
+	subscriber string
+	handler    HandlerFunc
+}
+
+// Option configures a MemoryBus.
+type Option func(*MemoryBus)
+
+// WithOrdering delivers the messages of a message group (the
+// metadata.message_group_id of the event, like SQS FIFO) to each subscriber one
+// at a time and in publish order. Groups are spread over a pool of workers, so
+// different groups are still handled in parallel. Messages without a group are
+// delivered concurrently as usual.
+func WithOrdering(workers int) Option {
+	return func(b *MemoryBus) {
+		if workers <= 0 {
+			workers = 1
+		}
+		b.workers = make([]*worker, workers)
+		for i := range b.workers {
+			b.workers[i] = newWorker()
+		}
+	}
 }
 
 // New creates a new instance of MemoryBus.
-func New() *MemoryBus {
-	return &MemoryBus{
-		handlers: make(map[string][]HandlerFunc),
+func New(opts ...Option) *MemoryBus {
+	b := &MemoryBus{
+		handlers: make(map[string][]subscription),
+	}
+	for _, opt := range opts {
+		opt(b)
 	}
+
+	for _, w := range b.workers {
+		b.wg.Add(1)
+		go func(w *worker) {
+			defer b.wg.Done()
+			w.run()
+		}(w)
+	}
+
+	return b
 }
 
 // Publish sends a message to all registered handlers for a given topic.
 // This method makes MemoryBus implement the publisher.Client interface.
+// Delivery is fire and forget: handler errors are only logged.
 func (b *MemoryBus) Publish(ctx context.Context, topic string, message []byte) error {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
-	if handlers, ok := b.handlers[topic]; ok {
-		log.Printf("[MemoryBus] Publishing event to topic '%s'", topic)
-		for _, handler := range handlers {
-			// In a real scenario, you'd likely run this in a goroutine.
-			// For simplicity, we run it synchronously.
-			go handler(ctx, message)
-		}
-	} else {
+	subs, ok := b.handlers[topic]
+	if !ok {
 		log.Printf("[MemoryBus] No handlers registered for topic '%s'", topic)
+		return nil
+	}
+
+	log.Printf("[MemoryBus] Publishing event to topic '%s'", topic)
+
+	group := ""
+	if len(b.workers) > 0 {
+		group = messageGroupID(message)
+	}
+
+	for _, sub := range subs {
+		d := delivery{ctx: ctx, topic: topic, sub: sub, message: message}
+		if group == "" {
+			go d.run()
+			continue
+		}
+		b.workerFor(sub.subscriber, group).enqueue(d)
 	}
 	return nil
 }
 
 // Subscribe registers a handler function for a given topic.
-func (b *MemoryBus) Subscribe(topic string, handler HandlerFunc) {
+func (b *MemoryBus) Subscribe(topic, subscriber string, handler HandlerFunc) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	log.Printf("[MemoryBus] Subscribing a new handler to topic '%s'", topic)
-	b.handlers[topic] = append(b.handlers[topic], handler)
+	log.Printf("[MemoryBus] Subscribing '%s' to topic '%s'", subscriber, topic)
+	b.handlers[topic] = append(b.handlers[topic], subscription{subscriber: subscriber, handler: handler})
+}
+
+// Close waits for the queued ordered deliveries and stops the workers.
+func (b *MemoryBus) Close() error {
+	for _, w := range b.workers {
+		w.close()
+	}
+	b.wg.Wait()
+	return nil
+}
+
+// workerFor pins a subscriber and group to one worker, which keeps their order.
+func (b *MemoryBus) workerFor(subscriber, group string) *worker {
+	h := fnv.New32a()
+	h.Write([]byte(subscriber))
+	h.Write([]byte{0})
+	h.Write([]byte(group))
+	return b.workers[h.Sum32()%uint32(len(b.workers))]
+}
+
+func messageGroupID(message []byte) string {
+	var event struct {
+		Metadata struct {
+			MessageGroupID string `json:"message_group_id"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(message, &event); err != nil {
+		return ""
+	}
+	return event.Metadata.MessageGroupID
+}
+
+type delivery struct {
+	ctx     context.Context
+	topic   string
+	sub     subscription
+	message []byte
+}
+
+func (d delivery) run() {
+	if err := d.sub.handler(d.ctx, d.message); err != nil {
+		log.Printf("[MemoryBus] Handler of '%s' failed on topic '%s': %v", d.sub.subscriber, d.topic, err)
+	}
+}
+
+// worker runs its deliveries sequentially. The queue is unbounded so a handler
+// publishing to its own worker never blocks.
+type worker struct {
+	mu     sync.Mutex
+	queue  []delivery
+	closed bool
+	notify chan struct{}
+}
+
+func newWorker() *worker {
+	return &worker{
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (w *worker) enqueue(d delivery) {
+	w.mu.Lock()
+	w.queue = append(w.queue, d)
+	w.mu.Unlock()
+	w.signal()
+}
+
+func (w *worker) close() {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+	w.signal()
+}
+
+func (w *worker) signal() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (w *worker) run() {
+	for {
+		w.mu.Lock()
+		if len(w.queue) == 0 {
+			closed := w.closed
+			w.mu.Unlock()
+			if closed {
+				return
+			}
+			<-w.notify
+			continue
+		}
+		d := w.queue[0]
+		w.queue = w.queue[1:]
+		w.mu.Unlock()
+
+		d.run()
+	}
 }
@@ -0,0 +1,120 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func groupMessage(group string, n int) []byte {
+	return []byte(fmt.Sprintf(`{"metadata":{"message_group_id":%q},"n":%d}`, group, n))
+}
+
+func TestMemoryBus_OrderingDeliversGroupsInOrder(t *testing.T) {
+	bus := New(WithOrdering(4))
+
+	var mu sync.Mutex
+	got := map[string][]string{}
+	var wg sync.WaitGroup
+
+	bus.Subscribe("topic", "sub", func(ctx context.Context, msg []byte) error {
+		defer wg.Done()
+		group := messageGroupID(msg)
+
+		mu.Lock()
+		n := len(got[group])
+		mu.Unlock()
+		// Uneven handling times reorder the messages unless delivery is serialized.
+		time.Sleep(time.Duration(n%3) * time.Millisecond)
+
+		mu.Lock()
+		got[group] = append(got[group], string(msg))
+		mu.Unlock()
+		return nil
+	})
+
+	want := map[string][]string{}
+	for n := 0; n < 20; n++ {
+		for _, group := range []string{"p-1", "p-2", "p-3"} {
+			wg.Add(1)
+			msg := groupMessage(group, n)
+			want[group] = append(want[group], string(msg))
+			require.NoError(t, bus.Publish(context.Background(), "topic", msg))
+		}
+	}
+
+	wg.Wait()
+	require.NoError(t, bus.Close())
+
+	assert.Equal(t, want, got)
+}
+
+func TestMemoryBus_OrderingRunsGroupsInParallel(t *testing.T) {
+	bus := New(WithOrdering(8))
+	defer bus.Close()
+
+	release := make(chan struct{})
+	done := make(chan string, 2)
+
+	bus.Subscribe("topic", "sub", func(ctx context.Context, msg []byte) error {
+		group := messageGroupID(msg)
+		if group == "slow" {
+			<-release
+		}
+		done <- group
+		return nil
+	})
+
+	require.NoError(t, bus.Publish(context.Background(), "topic", groupMessage("slow", 0)))
+
+	// Find a group on a different worker than the blocked one.
+	fast := ""
+	for i := 0; fast == ""; i++ {
+		if g := fmt.Sprintf("fast-%d", i); bus.workerFor("sub", g) != bus.workerFor("sub", "slow") {
+			fast = g
+		}
+	}
+	require.NoError(t, bus.Publish(context.Background(), "topic", groupMessage(fast, 0)))
+
+	select {
+	case group := <-done:
+		assert.Equal(t, fast, group)
+	case <-time.After(time.Second):
+		t.Fatal("a blocked group delayed another group")
+	}
+
+	close(release)
+	assert.Equal(t, "slow", <-done)
+}
+
+func TestMemoryBus_WithoutGroupDeliversConcurrently(t *testing.T) {
+	bus := New(WithOrdering(1))
+	defer bus.Close()
+
+	release := make(chan struct{})
+	done := make(chan struct{}, 2)
+
+	bus.Subscribe("topic", "sub", func(ctx context.Context, msg []byte) error {
+		if string(msg) == "blocking" {
+			<-release
+		}
+		done <- struct{}{}
+		return nil
+	})
+
+	require.NoError(t, bus.Publish(context.Background(), "topic", []byte("blocking")))
+	require.NoError(t, bus.Publish(context.Background(), "topic", []byte("other")))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("message without group waited for another one")
+	}
+	close(release)
+	<-done
+}
@@ -0,0 +1,234 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	deadLetterTopicPrefix = "dlq."
+	retryTopicPrefix      = "retry."
+)
+
+var ErrDeadLetterNotFound = errors.New("eventbus: dead letter not found")
+
+// DeadLetter is a message a subscriber could not process, captured with enough
+// context to inspect it and replay it to that subscriber.
+type DeadLetter struct {
+	ID             string    `json:"id"`
+	Topic          string    `json:"topic"`
+	Subscriber     string    `json:"subscriber"`
+	Payload        []byte    `json:"payload"`
+	Error          string    `json:"error"`
+	Attempts       int       `json:"attempts"`
+	PublishedAt    time.Time `json:"published_at"`
+	FirstAttemptAt time.Time `json:"first_attempt_at"`
+	LastAttemptAt  time.Time `json:"last_attempt_at"`
+}
+
+// DeadLetterTopic is the topic holding the dead letters of one subscription.
+func DeadLetterTopic(topic, subscriber string) string {
+	return deadLetterTopicPrefix + subscriber + "." + topic
+}
+
+// RetryTopic is the topic redelivering messages of topic to subscriber alone,
+// which a LogBus subscription consumes along with topic.
+func RetryTopic(topic, subscriber string) string {
+	return retryTopicPrefix + subscriber + "." + topic
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not recoverable by redelivery (e.g. a payload that
+// cannot be decoded), so the bus dead-letters the message right away.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// DeadLetterQueue inspects and resolves the dead letters stored by a LogBus.
+// Resolved letters are recorded in a separate append-only file instead of
+// rewriting the dead letter logs, so it is safe to use while the bus runs in
+// another process.
+type DeadLetterQueue struct {
+	bus *LogBus
+}
+
+type resolution struct {
+	ID         string    `json:"id"`
+	Action     string    `json:"action"`
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+func NewDeadLetterQueue(bus *LogBus) *DeadLetterQueue {
+	return &DeadLetterQueue{
+		bus: bus,
+	}
+}
+
+// Topics returns the dead letter topics present on disk.
+func (q *DeadLetterQueue) Topics() ([]string, error) {
+	entries, err := os.ReadDir(topicsDir(q.bus.cfg.Dir))
+	if err != nil {
+		return nil, err
+	}
+
+	var topics []string
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), ".log")
+		if strings.HasPrefix(name, deadLetterTopicPrefix) {
+			topics = append(topics, name)
+		}
+	}
+
+	sort.Strings(topics)
+	return topics, nil
+}
+
+// List returns the unresolved dead letters of a dead letter topic, or of all of
+// them when topic is empty, oldest first.
+func (q *DeadLetterQueue) List(topic string) ([]DeadLetter, error) {
+	topics := []string{topic}
+	if topic == "" {
+		var err error
+		if topics, err = q.Topics(); err != nil {
+			return nil, err
+		}
+	}
+
+	resolved, err := q.resolved()
+	if err != nil {
+		return nil, err
+	}
+
+	letters := []DeadLetter{}
+	for _, t := range topics {
+		var offset int64
+		for {
+			record, next, err := readRecord(topicPath(q.bus.cfg.Dir, t), offset)
+			if err != nil {
+				break
+			}
+			offset = next
+
+			var letter DeadLetter
+			if err := json.Unmarshal(record.Message, &letter); err != nil {
+				continue
+			}
+			if _, ok := resolved[letter.ID]; !ok {
+				letters = append(letters, letter)
+			}
+		}
+	}
+
+	sort.SliceStable(letters, func(i, j int) bool {
+		return letters[i].LastAttemptAt.Before(letters[j].LastAttemptAt)
+	})
+
+	return letters, nil
+}
+
+func (q *DeadLetterQueue) Get(id string) (DeadLetter, error) {
+	letters, err := q.List("")
+	if err != nil {
+		return DeadLetter{}, err
+	}
+
+	for _, letter := range letters {
+		if letter.ID == id {
+			return letter, nil
+		}
+	}
+
+	return DeadLetter{}, ErrDeadLetterNotFound
+}
+
+// Replay publishes the original payload on the retry topic of the subscriber
+// that failed, so the other subscribers of its topic do not get it again, and
+// resolves the letter.
+func (q *DeadLetterQueue) Replay(ctx context.Context, id string) error {
+	letter, err := q.Get(id)
+	if err != nil {
+		return err
+	}
+
+	if err := q.bus.Publish(ctx, RetryTopic(letter.Topic, letter.Subscriber), letter.Payload); err != nil {
+		return err
+	}
+
+	return q.resolve(id, "replayed")
+}
+
+// Purge drops the letter without replaying it.
+func (q *DeadLetterQueue) Purge(id string) error {
+	if _, err := q.Get(id); err != nil {
+		return err
+	}
+
+	return q.resolve(id, "purged")
+}
+
+func (q *DeadLetterQueue) resolve(id, action string) error {
+	line, err := json.Marshal(resolution{ID: id, Action: action, ResolvedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(resolutionsPath(q.bus.cfg.Dir), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("eventbus: open dead letter resolutions: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func (q *DeadLetterQueue) resolved() (map[string]resolution, error) {
+	resolved := map[string]resolution{}
+
+	f, err := os.Open(resolutionsPath(q.bus.cfg.Dir))
+	if errors.Is(err, os.ErrNotExist) {
+		return resolved, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r resolution
+		if err := json.Unmarshal(scanner.Bytes(), &r); err == nil {
+			resolved[r.ID] = r
+		}
+	}
+
+	return resolved, scanner.Err()
+}
+
+func resolutionsPath(dir string) string {
+	return filepath.Join(dir, "dlq_resolutions.log")
+}
@@ -0,0 +1,131 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogBus_DeadLettersExhaustedMessages(t *testing.T) {
+	bus := newTestLogBus(t, t.TempDir())
+	defer bus.Close()
+
+	bus.Subscribe("topic", "sub", func(ctx context.Context, msg []byte) error {
+		return errors.New("always failing")
+	})
+	require.NoError(t, bus.Publish(context.Background(), "topic", []byte(`{"n":1}`)))
+
+	dlq := NewDeadLetterQueue(bus)
+
+	var letters []DeadLetter
+	assert.Eventually(t, func() bool {
+		letters, _ = dlq.List(DeadLetterTopic("topic", "sub"))
+		return len(letters) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	letter := letters[0]
+	assert.Equal(t, "topic", letter.Topic)
+	assert.Equal(t, "sub", letter.Subscriber)
+	assert.Equal(t, `{"n":1}`, string(letter.Payload))
+	assert.Equal(t, "always failing", letter.Error)
+	assert.Equal(t, 3, letter.Attempts)
+	assert.False(t, letter.LastAttemptAt.Before(letter.FirstAttemptAt))
+}
+
+func TestLogBus_PermanentErrorsSkipRetries(t *testing.T) {
+	bus := newTestLogBus(t, t.TempDir())
+	defer bus.Close()
+
+	var attempts received
+	bus.Subscribe("topic", "sub", func(ctx context.Context, msg []byte) error {
+		attempts.add(msg)
+		return Permanent(errors.New("cannot decode"))
+	})
+	require.NoError(t, bus.Publish(context.Background(), "topic", []byte("garbage")))
+
+	dlq := NewDeadLetterQueue(bus)
+	assert.Eventually(t, func() bool {
+		letters, _ := dlq.List("")
+		return len(letters) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	assert.Len(t, attempts.get(), 1)
+}
+
+func TestDeadLetterQueue_ReplayAndPurge(t *testing.T) {
+	bus := newTestLogBus(t, t.TempDir())
+	defer bus.Close()
+
+	var fail atomic.Bool
+	fail.Store(true)
+	var got received
+	bus.Subscribe("topic", "sub", func(ctx context.Context, msg []byte) error {
+		got.add(msg)
+		if fail.Load() {
+			return Permanent(errors.New("not yet"))
+		}
+		return nil
+	})
+	require.NoError(t, bus.Publish(context.Background(), "topic", []byte("1")))
+	require.NoError(t, bus.Publish(context.Background(), "topic", []byte("2")))
+
+	dlq := NewDeadLetterQueue(bus)
+	var letters []DeadLetter
+	assert.Eventually(t, func() bool {
+		letters, _ = dlq.List("")
+		return len(letters) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	fail.Store(false)
+	require.NoError(t, dlq.Replay(context.Background(), letters[0].ID))
+	require.NoError(t, dlq.Purge(letters[1].ID))
+
+	assert.Eventually(t, func() bool { return len(got.get()) == 3 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, string(letters[0].Payload), got.get()[2])
+
+	remaining, err := dlq.List("")
+	require.NoError(t, err)
+	assert.Empty(t, remaining)
+
+	assert.ErrorIs(t, dlq.Purge(letters[1].ID), ErrDeadLetterNotFound)
+}
+
+func TestDeadLetterQueue_ReplayOnlyReachesTheFailedSubscriber(t *testing.T) {
+	bus := newTestLogBus(t, t.TempDir())
+	defer bus.Close()
+
+	var fail atomic.Bool
+	fail.Store(true)
+	var failing, other received
+	bus.Subscribe("topic", "failing", func(ctx context.Context, msg []byte) error {
+		failing.add(msg)
+		if fail.Load() {
+			return Permanent(errors.New("not yet"))
+		}
+		return nil
+	})
+	bus.Subscribe("topic", "other", func(ctx context.Context, msg []byte) error {
+		other.add(msg)
+		return nil
+	})
+	require.NoError(t, bus.Publish(context.Background(), "topic", []byte("1")))
+
+	dlq := NewDeadLetterQueue(bus)
+	var letters []DeadLetter
+	assert.Eventually(t, func() bool {
+		letters, _ = dlq.List("")
+		return len(letters) == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "topic", letters[0].Topic)
+
+	fail.Store(false)
+	require.NoError(t, dlq.Replay(context.Background(), letters[0].ID))
+
+	assert.Eventually(t, func() bool { return len(failing.get()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Len(t, other.get(), 1)
+}
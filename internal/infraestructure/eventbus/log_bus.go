@@ -0,0 +1,395 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrBusClosed = errors.New("eventbus: bus is closed")
+
+// LogBusConfig tunes a LogBus. Zero values fall back to the defaults.
+type LogBusConfig struct {
+	// Dir holds one append-only log per topic and the committed offsets.
+	Dir string
+	// PollInterval is how often an idle subscriber looks for records appended
+	// by other processes.
+	PollInterval time.Duration
+	// MaxAttempts is how many times a message is delivered before dead-lettering it.
+	MaxAttempts int
+	// RetryBackoff is the delay before the first redelivery; it doubles on every
+	// attempt up to MaxBackoff.
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+}
+
+func (c LogBusConfig) withDefaults() LogBusConfig {
+	if c.PollInterval <= 0 {
+		c.PollInterval = 100 * time.Millisecond
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 5
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = 100 * time.Millisecond
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 5 * time.Second
+	}
+	return c
+}
+
+// LogBus is a durable, at-least-once event bus backed by files on disk.
+// Every topic is an append-only log; each subscriber reads it sequentially and
+// commits its offset once the handler succeeds, so a restarted process resumes
+// where it stopped. Failed messages are redelivered with exponential backoff and,
+// once the attempts run out, moved to the dead letter topic of the subscription.
+type LogBus struct {
+	cfg    LogBusConfig
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	closed bool
+	logs   map[string]*os.File
+	subs   map[string][]*logSubscription
+}
+
+type logRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	Message   []byte    `json:"message"`
+}
+
+type logSubscription struct {
+	topic      string
+	subscriber string
+	// origin is the topic the subscriber subscribed to, which differs from
+	// topic on its retry topic.
+	origin  string
+	handler HandlerFunc
+	notify  chan struct{}
+}
+
+// NewLogBus opens (or creates) a bus stored under cfg.Dir.
+func NewLogBus(cfg LogBusConfig) (*LogBus, error) {
+	cfg = cfg.withDefaults()
+
+	for _, dir := range []string{topicsDir(cfg.Dir), offsetsDir(cfg.Dir)} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("eventbus: create %s: %w", dir, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &LogBus{
+		cfg:    cfg,
+		ctx:    ctx,
+		cancel: cancel,
+		logs:   make(map[string]*os.File),
+		subs:   make(map[string][]*logSubscription),
+	}, nil
+}
+
+// Publish appends message to the topic log. The message is durable once Publish returns.
+func (b *LogBus) Publish(ctx context.Context, topic string, message []byte) error {
+	line, err := json.Marshal(logRecord{Timestamp: time.Now().UTC(), Message: message})
+	if err != nil {
+		return fmt.Errorf("eventbus: encode record: %w", err)
+	}
+	line = append(line, '\n')
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrBusClosed
+	}
+
+	f, err := b.openLog(topic)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(line); err != nil {
+		return fmt.Errorf("eventbus: append to %s: %w", topic, err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("eventbus: sync %s: %w", topic, err)
+	}
+
+	log.Printf("[LogBus] Published event to topic '%s'", topic)
+	for _, sub := range b.subs[topic] {
+		select {
+		case sub.notify <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Subscribe starts delivering the topic log to handler from the last offset
+// committed by subscriber, or from the beginning for a new subscriber. The
+// subscriber also gets the messages published on its retry topic, see
+// RetryTopic.
+func (b *LogBus) Subscribe(topic, subscriber string, handler HandlerFunc) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		log.Printf("[LogBus] Ignoring subscription of '%s' to '%s': bus is closed", subscriber, topic)
+		return
+	}
+
+	log.Printf("[LogBus] Subscribing '%s' to topic '%s'", subscriber, topic)
+
+	for _, t := range []string{topic, RetryTopic(topic, subscriber)} {
+		sub := &logSubscription{
+			topic:      t,
+			subscriber: subscriber,
+			origin:     topic,
+			handler:    handler,
+			notify:     make(chan struct{}, 1),
+		}
+		b.subs[t] = append(b.subs[t], sub)
+
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			b.consume(sub)
+		}()
+	}
+}
+
+// Close stops every subscriber and waits for in-flight handlers to return.
+func (b *LogBus) Close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	b.cancel()
+	b.wg.Wait()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var errs []error
+	for _, f := range b.logs {
+		errs = append(errs, f.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (b *LogBus) consume(sub *logSubscription) {
+	offset, err := b.readOffset(sub)
+	if err != nil {
+		log.Printf("ERROR: [LogBus] could not read offset of '%s' on '%s': %v", sub.subscriber, sub.topic, err)
+		return
+	}
+
+	ticker := time.NewTicker(b.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		record, next, err := readRecord(topicPath(b.cfg.Dir, sub.topic), offset)
+		switch {
+		case errors.Is(err, io.EOF):
+			select {
+			case <-b.ctx.Done():
+				return
+			case <-sub.notify:
+			case <-ticker.C:
+			}
+			continue
+		case err != nil:
+			log.Printf("ERROR: [LogBus] skipping unreadable record at offset %d of '%s': %v", offset, sub.topic, err)
+		default:
+			if !b.deliver(sub, record) {
+				return
+			}
+		}
+
+		offset = next
+		if err := b.commitOffset(sub, offset); err != nil {
+			log.Printf("ERROR: [LogBus] could not commit offset of '%s' on '%s': %v", sub.subscriber, sub.topic, err)
+		}
+	}
+}
+
+// deliver runs the handler until it succeeds or the attempts are exhausted, in
+// which case the message goes to the subscription dead letter topic.
+// It returns false when the bus is closing and the message must not be committed.
+func (b *LogBus) deliver(sub *logSubscription, record logRecord) bool {
+	firstAttemptAt := time.Now().UTC()
+
+	for attempt := 1; ; attempt++ {
+		err := sub.handler(b.ctx, record.Message)
+		if err == nil {
+			return true
+		}
+
+		if b.ctx.Err() != nil {
+			return false
+		}
+
+		if IsPermanent(err) || attempt >= b.cfg.MaxAttempts {
+			log.Printf("ERROR: [LogBus] '%s' gave up on a message of '%s' after %d attempts: %v", sub.subscriber, sub.topic, attempt, err)
+			return b.deadLetter(sub, DeadLetter{
+				ID:             uuid.NewString(),
+				Topic:          sub.origin,
+				Subscriber:     sub.subscriber,
+				Payload:        record.Message,
+				Error:          err.Error(),
+				Attempts:       attempt,
+				PublishedAt:    record.Timestamp,
+				FirstAttemptAt: firstAttemptAt,
+				LastAttemptAt:  time.Now().UTC(),
+			})
+		}
+
+		delay := b.backoff(attempt)
+		log.Printf("WARN: [LogBus] '%s' failed to handle a message of '%s' (attempt %d), retrying in %s: %v", sub.subscriber, sub.topic, attempt, delay, err)
+
+		select {
+		case <-b.ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+	}
+}
+
+// deadLetter stores letter on the dead letter topic of the subscription,
+// retrying until it is durable or the bus closes.
+func (b *LogBus) deadLetter(sub *logSubscription, letter DeadLetter) bool {
+	msg, err := json.Marshal(letter)
+	if err != nil {
+		log.Printf("CRITICAL: [LogBus] could not encode dead letter of '%s' on '%s': %v", sub.subscriber, sub.topic, err)
+		return true
+	}
+
+	topic := DeadLetterTopic(sub.origin, sub.subscriber)
+	for attempt := 1; ; attempt++ {
+		err := b.Publish(b.ctx, topic, msg)
+		if err == nil {
+			log.Printf("[LogBus] Message of '%s' moved to '%s' as %s", sub.topic, topic, letter.ID)
+			return true
+		}
+
+		log.Printf("CRITICAL: [LogBus] could not dead-letter a message of '%s' for '%s': %v", sub.topic, sub.subscriber, err)
+
+		select {
+		case <-b.ctx.Done():
+			return false
+		case <-time.After(b.backoff(attempt)):
+		}
+	}
+}
+
+func (b *LogBus) backoff(attempt int) time.Duration {
+	delay := float64(b.cfg.RetryBackoff) * math.Pow(2, float64(attempt-1))
+	if delay > float64(b.cfg.MaxBackoff) {
+		return b.cfg.MaxBackoff
+	}
+	return time.Duration(delay)
+}
+
+func (b *LogBus) openLog(topic string) (*os.File, error) {
+	if f, ok := b.logs[topic]; ok {
+		return f, nil
+	}
+
+	f, err := os.OpenFile(topicPath(b.cfg.Dir, topic), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("eventbus: open log of %s: %w", topic, err)
+	}
+
+	b.logs[topic] = f
+	return f, nil
+}
+
+func (b *LogBus) readOffset(sub *logSubscription) (int64, error) {
+	raw, err := os.ReadFile(offsetPath(b.cfg.Dir, sub.subscriber, sub.topic))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(strings.TrimSpace(string(raw)), 10, 64)
+}
+
+func (b *LogBus) commitOffset(sub *logSubscription, offset int64) error {
+	path := offsetPath(b.cfg.Dir, sub.subscriber, sub.topic)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(offset, 10)), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readRecord reads the record starting at offset and returns the offset of the
+// next one. It returns io.EOF when no complete record is available yet.
+func readRecord(path string, offset int64) (logRecord, int64, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return logRecord{}, offset, io.EOF
+	}
+	if err != nil {
+		return logRecord{}, offset, err
+	}
+	defer f.Close()
+
+	line, err := bufio.NewReader(io.NewSectionReader(f, offset, math.MaxInt64-offset)).ReadBytes('\n')
+	if err != nil {
+		// A line without its newline is still being written.
+		return logRecord{}, offset, io.EOF
+	}
+
+	next := offset + int64(len(line))
+
+	var record logRecord
+	if err := json.Unmarshal(line, &record); err != nil {
+		return logRecord{}, next, err
+	}
+
+	return record, next, nil
+}
+
+func topicsDir(dir string) string {
+	return filepath.Join(dir, "topics")
+}
+
+func offsetsDir(dir string) string {
+	return filepath.Join(dir, "offsets")
+}
+
+func topicPath(dir, topic string) string {
+	return filepath.Join(topicsDir(dir), topic+".log")
+}
+
+func offsetPath(dir, subscriber, topic string) string {
+	return filepath.Join(offsetsDir(dir), subscriber, topic+".offset")
+}
@@ -0,0 +1,120 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type received struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (r *received) add(msg []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, string(msg))
+}
+
+func (r *received) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.msgs...)
+}
+
+func newTestLogBus(t *testing.T, dir string) *LogBus {
+	bus, err := NewLogBus(LogBusConfig{
+		Dir:          dir,
+		PollInterval: 10 * time.Millisecond,
+		MaxAttempts:  3,
+		RetryBackoff: time.Millisecond,
+	})
+	require.NoError(t, err)
+	return bus
+}
+
+func TestLogBus_DeliversInOrder(t *testing.T) {
+	bus := newTestLogBus(t, t.TempDir())
+	defer bus.Close()
+
+	var got received
+	bus.Subscribe("topic", "sub", func(ctx context.Context, msg []byte) error {
+		got.add(msg)
+		return nil
+	})
+
+	for _, msg := range []string{"1", "2", "3"} {
+		require.NoError(t, bus.Publish(context.Background(), "topic", []byte(msg)))
+	}
+
+	assert.Eventually(t, func() bool { return len(got.get()) == 3 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"1", "2", "3"}, got.get())
+}
+
+func TestLogBus_RedeliversFailedMessages(t *testing.T) {
+	bus := newTestLogBus(t, t.TempDir())
+	defer bus.Close()
+
+	var attempts received
+	bus.Subscribe("topic", "sub", func(ctx context.Context, msg []byte) error {
+		attempts.add(msg)
+		if len(attempts.get()) < 3 {
+			return errors.New("temporary failure")
+		}
+		return nil
+	})
+
+	require.NoError(t, bus.Publish(context.Background(), "topic", []byte("1")))
+
+	assert.Eventually(t, func() bool { return len(attempts.get()) == 3 }, time.Second, 5*time.Millisecond)
+}
+
+func TestLogBus_ResumesFromCommittedOffset(t *testing.T) {
+	dir := t.TempDir()
+
+	bus := newTestLogBus(t, dir)
+	var first received
+	bus.Subscribe("topic", "sub", func(ctx context.Context, msg []byte) error {
+		first.add(msg)
+		return nil
+	})
+	require.NoError(t, bus.Publish(context.Background(), "topic", []byte("1")))
+	assert.Eventually(t, func() bool { return len(first.get()) == 1 }, time.Second, 5*time.Millisecond)
+
+	require.NoError(t, bus.Close())
+
+	// Published while the subscriber is down.
+	other := newTestLogBus(t, dir)
+	require.NoError(t, other.Publish(context.Background(), "topic", []byte("2")))
+	require.NoError(t, other.Close())
+
+	restarted := newTestLogBus(t, dir)
+	defer restarted.Close()
+
+	var second, fresh received
+	restarted.Subscribe("topic", "sub", func(ctx context.Context, msg []byte) error {
+		second.add(msg)
+		return nil
+	})
+	restarted.Subscribe("topic", "new-sub", func(ctx context.Context, msg []byte) error {
+		fresh.add(msg)
+		return nil
+	})
+
+	assert.Eventually(t, func() bool { return len(second.get()) == 1 && len(fresh.get()) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, []string{"2"}, second.get())
+	assert.Equal(t, []string{"1", "2"}, fresh.get())
+}
+
+func TestLogBus_PublishAfterClose(t *testing.T) {
+	bus := newTestLogBus(t, t.TempDir())
+	require.NoError(t, bus.Close())
+
+	assert.ErrorIs(t, bus.Publish(context.Background(), "topic", []byte("1")), ErrBusClosed)
+}
@@ -0,0 +1,64 @@
+package fx
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/mmarias/golearn/internal/domain"
+)
+
+// StaticProviderName identifies the rates of a static file in FX conversions.
+const StaticProviderName = "static"
+
+// staticRateFile is the format of a static rates file:
+//
+//	{"rates": [{"from": "EUR", "to": "USD", "rate": "1.08"}]}
+type staticRateFile struct {
+	Rates []struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+		Rate string `json:"rate"`
+	} `json:"rates"`
+}
+
+type staticRateProvider struct {
+	rates map[string]domain.FXRate
+}
+
+// NewStaticRateProvider quotes the fixed rates listed in the file at path. Only
+// the listed pairs are quoted; the inverse of a pair is not derived.
+func NewStaticRateProvider(path string) (*staticRateProvider, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read fx rates: %w", err)
+	}
+
+	var file staticRateFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("decode fx rates %s: %w", path, err)
+	}
+
+	p := &staticRateProvider{rates: map[string]domain.FXRate{}}
+	for _, r := range file.Rates {
+		rate, err := domain.NewFXRate(r.From, r.To, r.Rate, StaticProviderName)
+		if err != nil {
+			return nil, fmt.Errorf("fx rates %s: %w", path, err)
+		}
+		p.rates[pairKey(r.From, r.To)] = rate
+	}
+
+	return p, nil
+}
+
+func (p *staticRateProvider) Rate(from, to string) (domain.FXRate, error) {
+	rate, ok := p.rates[pairKey(from, to)]
+	if !ok {
+		return domain.FXRate{}, fmt.Errorf("%w: %s/%s", domain.ErrRateNotFound, from, to)
+	}
+	return rate, nil
+}
+
+func pairKey(from, to string) string {
+	return from + "/" + to
+}
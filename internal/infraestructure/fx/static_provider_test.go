@@ -0,0 +1,56 @@
+package fx
+
+import (
+	"testing"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticRateProvider_Rate(t *testing.T) {
+	p, err := NewStaticRateProvider("testdata/rates.json")
+	require.NoError(t, err)
+
+	rate, err := p.Rate("EUR", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, domain.FXRate{From: "EUR", To: "USD", Rate: "1.08", Provider: StaticProviderName}, rate)
+
+	_, err = p.Rate("USD", "EUR")
+	assert.ErrorIs(t, err, domain.ErrRateNotFound)
+}
+
+func TestStaticRateProvider_Convert(t *testing.T) {
+	p, err := NewStaticRateProvider("testdata/rates.json")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		amount   domain.Money
+		to       string
+		expected domain.Money
+	}{
+		{name: "two decimals to two decimals", amount: domain.NewMoney(1000, "EUR"), to: "USD", expected: domain.NewMoney(1080, "USD")},
+		{name: "rounds to the minor unit", amount: domain.NewMoney(1, "EUR"), to: "USD", expected: domain.NewMoney(1, "USD")},
+		{name: "to a currency without decimals", amount: domain.NewMoney(1050, "USD"), to: "JPY", expected: domain.NewMoney(1588, "JPY")},
+		{name: "to a currency with three decimals", amount: domain.NewMoney(10000, "USD"), to: "KWD", expected: domain.NewMoney(30710, "KWD")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversion, err := domain.Convert(p, tt.amount, tt.to)
+			require.NoError(t, err)
+			assert.Equal(t, tt.amount, conversion.From)
+			assert.Equal(t, tt.expected, conversion.To)
+			assert.Equal(t, StaticProviderName, conversion.Provider)
+		})
+	}
+}
+
+func TestNewStaticRateProvider_RejectsUnknownCurrencies(t *testing.T) {
+	_, err := NewStaticRateProvider("testdata/invalid_currency.json")
+	assert.ErrorIs(t, err, domain.ErrUnknownCurrency)
+
+	_, err = NewStaticRateProvider("testdata/missing.json")
+	assert.Error(t, err)
+}
@@ -0,0 +1,168 @@
+package idempotency
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mmarias/golearn/internal/infraestructure/database"
+)
+
+const bucket = "idempotency"
+
+// inProgressTTL bounds how long a key stays locked when the request that claimed
+// it never completes (e.g. the process died), so clients can retry.
+const inProgressTTL = 30 * time.Second
+
+var (
+	ErrRequestInProgress   = errors.New("request with this idempotency key is in progress")
+	ErrFingerprintMismatch = errors.New("idempotency key reused with a different request")
+	// ErrClaimExpired is returned by Complete and Release when the claim expired
+	// and was dropped or taken by a retry: the response is not stored, and the
+	// request may run, or have run, twice.
+	ErrClaimExpired = errors.New("idempotency claim expired before the request completed")
+)
+
+// Record is what the store keeps for an idempotency key.
+type Record struct {
+	Fingerprint string `json:"fingerprint"`
+	// Claim identifies the request holding the key while it is in progress.
+	Claim      string    `json:"claim"`
+	Completed  bool      `json:"completed"`
+	StatusCode int       `json:"status_code"`
+	Body       []byte    `json:"body"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type Store interface {
+	// Begin claims key for a request identified by fingerprint and returns the
+	// claim, whose Claim the caller passes to Complete or Release. If the key
+	// already holds a completed response for the same fingerprint, that record
+	// is returned so the caller can replay it.
+	Begin(key, fingerprint string) (*Record, error)
+	// Complete stores the final response of claim for key during the store window.
+	Complete(key, claim string, statusCode int, body []byte) error
+	// Release drops claim on key so the request can be retried.
+	Release(key, claim string) error
+}
+
+type store struct {
+	db     *database.DB
+	window time.Duration
+	now    func() time.Time
+
+	// lastPrune is only used inside Update, which is serialized.
+	lastPrune time.Time
+}
+
+// NewStore returns a store that remembers responses in db for window.
+func NewStore(db *database.DB, window time.Duration) *store {
+	return &store{
+		db:     db,
+		window: window,
+		now:    time.Now,
+	}
+}
+
+func (s *store) Begin(key, fingerprint string) (*Record, error) {
+	var claimed *Record
+	err := s.db.Update(func(tx *database.Tx) error {
+		now := s.now()
+		if err := s.prune(tx, now); err != nil {
+			return err
+		}
+
+		var record Record
+		found, err := tx.Get(bucket, key, &record)
+		if err != nil {
+			return err
+		}
+		if found && now.Before(record.ExpiresAt) {
+			if record.Fingerprint != fingerprint {
+				return ErrFingerprintMismatch
+			}
+			if !record.Completed {
+				return ErrRequestInProgress
+			}
+			claimed = &record
+			return nil
+		}
+
+		record = Record{
+			Fingerprint: fingerprint,
+			Claim:       uuid.NewString(),
+			ExpiresAt:   now.Add(min(inProgressTTL, s.window)),
+		}
+		claimed = &record
+		return tx.Put(bucket, key, record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+func (s *store) Complete(key, claim string, statusCode int, body []byte) error {
+	return s.db.Update(func(tx *database.Tx) error {
+		var record Record
+		found, err := tx.Get(bucket, key, &record)
+		if err != nil {
+			return err
+		}
+		// An expired claim nobody took over or pruned is still ours to complete.
+		if !found || record.Claim != claim {
+			return ErrClaimExpired
+		}
+
+		record.Completed = true
+		record.StatusCode = statusCode
+		record.Body = body
+		record.ExpiresAt = s.now().Add(s.window)
+		return tx.Put(bucket, key, record)
+	})
+}
+
+func (s *store) Release(key, claim string) error {
+	return s.db.Update(func(tx *database.Tx) error {
+		var record Record
+		found, err := tx.Get(bucket, key, &record)
+		if err != nil || !found {
+			return err
+		}
+		if record.Claim != claim {
+			return ErrClaimExpired
+		}
+		return tx.Delete(bucket, key)
+	})
+}
+
+// prune drops the expired keys, at most once a minute.
+func (s *store) prune(tx *database.Tx, now time.Time) error {
+	if now.Sub(s.lastPrune) < time.Minute {
+		return nil
+	}
+	s.lastPrune = now
+
+	var expired []string
+	err := tx.ForEach(bucket, func(key string, raw json.RawMessage) error {
+		var record Record
+		if err := json.Unmarshal(raw, &record); err != nil {
+			return err
+		}
+		if !now.Before(record.ExpiresAt) {
+			expired = append(expired, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range expired {
+		if err := tx.Delete(bucket, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
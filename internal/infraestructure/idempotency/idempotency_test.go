@@ -0,0 +1,101 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mmarias/golearn/internal/infraestructure/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	s := NewStore(database.OpenInMemory(), time.Minute)
+
+	claim, err := s.Begin("key", "fp-1")
+	require.NoError(t, err)
+	assert.False(t, claim.Completed)
+	assert.NotEmpty(t, claim.Claim)
+
+	_, err = s.Begin("key", "fp-1")
+	assert.ErrorIs(t, err, ErrRequestInProgress)
+
+	_, err = s.Begin("key", "fp-2")
+	assert.ErrorIs(t, err, ErrFingerprintMismatch)
+
+	require.NoError(t, s.Complete("key", claim.Claim, 201, []byte(`{"id":"1"}`)))
+
+	record, err := s.Begin("key", "fp-1")
+	require.NoError(t, err)
+	assert.True(t, record.Completed)
+	assert.Equal(t, 201, record.StatusCode)
+	assert.Equal(t, `{"id":"1"}`, string(record.Body))
+
+	_, err = s.Begin("key", "fp-2")
+	assert.ErrorIs(t, err, ErrFingerprintMismatch)
+}
+
+func TestStore_Release(t *testing.T) {
+	s := NewStore(database.OpenInMemory(), time.Minute)
+
+	claim, err := s.Begin("key", "fp-1")
+	require.NoError(t, err)
+
+	require.NoError(t, s.Release("key", claim.Claim))
+
+	record, err := s.Begin("key", "fp-2")
+	require.NoError(t, err)
+	assert.False(t, record.Completed)
+}
+
+func TestStore_PersistsInTheDatabase(t *testing.T) {
+	db := database.OpenInMemory()
+
+	claim, err := NewStore(db, time.Minute).Begin("key", "fp-1")
+	require.NoError(t, err)
+	require.NoError(t, NewStore(db, time.Minute).Complete("key", claim.Claim, 201, []byte("done")))
+
+	record, err := NewStore(db, time.Minute).Begin("key", "fp-1")
+	require.NoError(t, err)
+	assert.True(t, record.Completed)
+	assert.Equal(t, "done", string(record.Body))
+}
+
+func TestStore_CompleteExpiredClaim(t *testing.T) {
+	now := time.Now()
+	s := NewStore(database.OpenInMemory(), time.Hour)
+	s.now = func() time.Time { return now }
+
+	late, err := s.Begin("late", "fp-1")
+	require.NoError(t, err)
+	expired, err := s.Begin("taken", "fp-1")
+	require.NoError(t, err)
+	pruned, err := s.Begin("pruned", "fp-1")
+	require.NoError(t, err)
+
+	now = now.Add(inProgressTTL)
+	retry, err := s.Begin("taken", "fp-1")
+	require.NoError(t, err)
+
+	t.Run("not taken over", func(t *testing.T) {
+		require.NoError(t, s.Complete("late", late.Claim, 201, []byte("late")))
+	})
+
+	t.Run("taken over by a retry", func(t *testing.T) {
+		assert.ErrorIs(t, s.Complete("taken", expired.Claim, 201, []byte("first")), ErrClaimExpired)
+		assert.ErrorIs(t, s.Release("taken", expired.Claim), ErrClaimExpired)
+
+		require.NoError(t, s.Complete("taken", retry.Claim, 201, []byte("retry")))
+		record, err := s.Begin("taken", "fp-1")
+		require.NoError(t, err)
+		assert.Equal(t, "retry", string(record.Body))
+	})
+
+	t.Run("pruned", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		_, err := s.Begin("other", "fp-1")
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, s.Complete("pruned", pruned.Claim, 201, []byte("pruned")), ErrClaimExpired)
+	})
+}
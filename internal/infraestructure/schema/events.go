@@ -0,0 +1,182 @@
+package schema
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+//go:embed schemas/*.json
+var schemaFiles embed.FS
+
+// schemaBaseURL resolves the references between the embedded schemas.
+const schemaBaseURL = "mem:///schemas/"
+
+// walletCommandEventTypes are the events carrying a domain.WalletCommandEvent
+// with an amount: the orchestrator commands to the wallet and the gateway, and
+// the wallet answers. Voids carry no amount from version 2 on.
+var walletCommandEventTypes = []string{
+	domain.HoldFundsEventType,
+	domain.ReleaseFundsEventType,
+	domain.DebitFundsEventType,
+	domain.AuthorizeGatewayEventType,
+	domain.CaptureAuthorizationEventType,
+	domain.RefundAuthorizationEventType,
+	domain.TopicWalletFunds,
+	domain.TopicWalletHoldFundsFailed,
+	domain.TopicWalletDebitFunds,
+	domain.TopicWalletDebitFailed,
+	domain.TopicWalletFundsReleased,
+}
+
+// eventSchemas maps every event published in the system to its schema file.
+var eventSchemas = []struct {
+	file       string
+	version    string
+	eventTypes []string
+}{
+	{file: "wallet_command.v1.json", version: "1", eventTypes: append([]string{domain.VoidAuthorizationEventType}, walletCommandEventTypes...)},
+	{file: "wallet_command.v2.json", version: "2", eventTypes: walletCommandEventTypes},
+	{file: "void_authorization.v2.json", version: "2", eventTypes: []string{domain.VoidAuthorizationEventType}},
+	{
+		file:    "refund_command.v1.json",
+		version: "1",
+		eventTypes: []string{
+			domain.TopicRefundCreated,
+			domain.RefundGatewayEventType,
+			domain.CreditFundsEventType,
+			domain.RefundUpdateStatusEventType,
+			domain.TopicGatewayRefunded,
+			domain.TopicGatewayRefundFailed,
+			domain.TopicWalletFundsCredited,
+			domain.TopicWalletCreditFailed,
+			domain.TopicRefundCompleted,
+			domain.TopicRefundRequestedV1,
+			domain.TopicPaymentRefundedV1,
+		},
+	},
+	{
+		file:    "payment_status.v1.json",
+		version: "1",
+		eventTypes: []string{
+			domain.TopicPaymentCancelRequested,
+			domain.PaymentUpdateStatusEventType,
+			domain.TopicPaymentCompleted,
+			domain.TopicPaymentFailed,
+			domain.TopicPaymentCanceled,
+		},
+	},
+	{file: "payment_created.v1.json", version: "1", eventTypes: []string{domain.TopicPaymentCreated}},
+	{file: "notify_user.v1.json", version: "1", eventTypes: []string{domain.NotifyUserEventType}},
+	{
+		file:    "gateway_authorization.v1.json",
+		version: "1",
+		eventTypes: []string{
+			domain.TopicGatewayAuthorized,
+			domain.TopicGatewayAuthorizationFailed,
+			domain.TopicGatewayCaptured,
+			domain.TopicGatewayCaptureFailed,
+			domain.TopicGatewayAuthorizationRefunded,
+			domain.TopicGatewayAuthorizationRefundFailed,
+		},
+	},
+	{file: "saga_timed_out.v1.json", version: "1", eventTypes: []string{domain.SagaTimedOutEventType}},
+	{
+		file:    "metric.v1.json",
+		version: "1",
+		eventTypes: []string{
+			domain.MetricPaymentSuccess,
+			domain.MetricPaymentFailure,
+			domain.MetricPaymentCanceled,
+			domain.MetricPaymentCancelRejected,
+			domain.MetricRefundSuccess,
+			domain.MetricRefundFailure,
+			domain.MetricSagaStepTimeout,
+			domain.MetricWalletHoldFundsFailure,
+			domain.MetricWalletDebitFailure,
+			domain.MetricWalletCreditFailure,
+			domain.MetricGatewayUnauthorize,
+		},
+	},
+}
+
+// NewEventRegistry compiles the schemas of every event exchanged between the
+// services and registers the upcasters of their older versions.
+func NewEventRegistry() (*Registry, error) {
+	compiler := jsonschema.NewCompiler()
+	files, err := fs.Glob(schemaFiles, "schemas/*.json")
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		b, err := schemaFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(b))
+		if err != nil {
+			return nil, fmt.Errorf("schema %s: %w", file, err)
+		}
+		if err := compiler.AddResource(schemaBaseURL+file[len("schemas/"):], doc); err != nil {
+			return nil, err
+		}
+	}
+
+	registry := NewRegistry()
+	for _, s := range eventSchemas {
+		compiled, err := compiler.Compile(schemaBaseURL + s.file)
+		if err != nil {
+			return nil, fmt.Errorf("schema %s: %w", s.file, err)
+		}
+		for _, eventType := range s.eventTypes {
+			registry.Register(Key{EventType: eventType, Version: s.version}, compiled)
+		}
+	}
+
+	for _, eventType := range append([]string{domain.VoidAuthorizationEventType}, walletCommandEventTypes...) {
+		registry.RegisterUpcaster(Key{EventType: eventType, Version: "1"}, domain.WalletCommandEventVersion, upcastWalletCommandV1)
+	}
+	return registry, nil
+}
+
+// upcastWalletCommandV1 moves the float amount and the separate currency of a
+// version 1 wallet command into a Money amount.
+func upcastWalletCommandV1(event map[string]any) error {
+	payload, ok := event["payload"].(map[string]any)
+	if !ok {
+		return errors.New("missing payload")
+	}
+	amount, _ := payload["amount"].(json.Number)
+	currency, _ := payload["currency"].(string)
+
+	c, err := domain.LookupCurrency(currency)
+	if err != nil {
+		return err
+	}
+	// JSON numbers may have an exponent, e.g. 1.23e2, which ParseMoney does
+	// not read. They are read exactly and written with the decimals of the
+	// currency, unless it cannot hold them.
+	decimal, ok := new(big.Rat).SetString(amount.String())
+	if !ok {
+		return fmt.Errorf("%w: %q", domain.ErrInvalidMoney, amount)
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(c.Exponent)), nil)
+	if !new(big.Rat).Mul(decimal, new(big.Rat).SetInt(scale)).IsInt() {
+		return fmt.Errorf("%w: %q in %s", domain.ErrTooManyDecimals, amount, currency)
+	}
+
+	money, err := domain.ParseMoney(decimal.FloatString(c.Exponent), currency)
+	if err != nil {
+		return err
+	}
+	payload["amount"] = map[string]any{"amount": money.Decimal(), "currency": money.Currency}
+	delete(payload, "currency")
+	return nil
+}
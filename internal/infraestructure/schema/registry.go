@@ -0,0 +1,124 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+var (
+	// ErrUnknownSchema is returned for events whose type and version have no schema.
+	ErrUnknownSchema = errors.New("no schema for event")
+	// ErrInvalidEvent is returned for events that do not match their schema.
+	ErrInvalidEvent = errors.New("event does not match its schema")
+)
+
+// Key identifies the schema of an event.
+type Key struct {
+	EventType string
+	Version   string
+}
+
+func (k Key) String() string {
+	return k.EventType + " v" + k.Version
+}
+
+// Upcaster converts an event, decoded with json.Number numbers, to the next
+// version of its schema. It does not need to set the new event_version.
+type Upcaster func(event map[string]any) error
+
+type upcaster struct {
+	to string
+	fn Upcaster
+}
+
+// Registry keeps the JSON Schema of every event by type and version, and the
+// upcasters moving old versions forward.
+type Registry struct {
+	schemas   map[Key]*jsonschema.Schema
+	upcasters map[Key]upcaster
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		schemas:   map[Key]*jsonschema.Schema{},
+		upcasters: map[Key]upcaster{},
+	}
+}
+
+func (r *Registry) Register(key Key, schema *jsonschema.Schema) {
+	r.schemas[key] = schema
+}
+
+// RegisterUpcaster makes Upcast convert from events to version to.
+func (r *Registry) RegisterUpcaster(from Key, to string, fn Upcaster) {
+	r.upcasters[from] = upcaster{to: to, fn: fn}
+}
+
+// Validate checks msg against the schema of its event type and version.
+func (r *Registry) Validate(msg []byte) error {
+	event, key, err := decode(msg)
+	if err != nil {
+		return err
+	}
+	return r.validate(event, key)
+}
+
+// Upcast converts msg to the latest version of its event type, applying the
+// upcasters one version at a time, and validates the result. Events already
+// at their latest version are only validated and returned as they are.
+func (r *Registry) Upcast(msg []byte) ([]byte, error) {
+	event, key, err := decode(msg)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.validate(event, key); err != nil {
+		return nil, err
+	}
+
+	upcasted := false
+	for u, ok := r.upcasters[key]; ok; u, ok = r.upcasters[key] {
+		if err := u.fn(event); err != nil {
+			return nil, fmt.Errorf("%w: upcasting %s to v%s: %v", ErrInvalidEvent, key, u.to, err)
+		}
+		key.Version = u.to
+		event["event_version"] = u.to
+		if err := r.validate(event, key); err != nil {
+			return nil, err
+		}
+		upcasted = true
+	}
+
+	if !upcasted {
+		return msg, nil
+	}
+	return json.Marshal(event)
+}
+
+func (r *Registry) validate(event map[string]any, key Key) error {
+	schema, ok := r.schemas[key]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownSchema, key)
+	}
+	if err := schema.Validate(event); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidEvent, key, err)
+	}
+	return nil
+}
+
+func decode(msg []byte) (map[string]any, Key, error) {
+	decoder := json.NewDecoder(bytes.NewReader(msg))
+	decoder.UseNumber()
+
+	var event map[string]any
+	if err := decoder.Decode(&event); err != nil {
+		return nil, Key{}, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+
+	eventType, _ := event["event_type"].(string)
+	version, _ := event["event_version"].(string)
+	return event, Key{EventType: eventType, Version: version}, nil
+}
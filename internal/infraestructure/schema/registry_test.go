@@ -0,0 +1,236 @@
+package schema
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmarias/golearn/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRegistry(t *testing.T) *Registry {
+	t.Helper()
+	registry, err := NewEventRegistry()
+	require.NoError(t, err)
+	return registry
+}
+
+func marshal(t *testing.T, event any) []byte {
+	t.Helper()
+	b, err := json.Marshal(event)
+	require.NoError(t, err)
+	return b
+}
+
+func command(eventType, version string) domain.CommandEvent {
+	return domain.CommandEvent{EventType: eventType, EventVersion: version}
+}
+
+func TestRegistry_Validate(t *testing.T) {
+	registry := newRegistry(t)
+	usd := domain.NewMoney(12_30, "USD")
+
+	tests := []struct {
+		name          string
+		msg           []byte
+		expectedError error
+	}{
+		{
+			name: "wallet command",
+			msg: marshal(t, domain.WalletCommandEvent{
+				CommandEvent:              command(domain.HoldFundsEventType, domain.WalletCommandEventVersion),
+				WalletCommandEventPayload: domain.WalletCommandEventPayload{PaymentID: "p-1", WalletID: "w-1", Amount: usd},
+			}),
+		},
+		{
+			name: "payment created",
+			msg: marshal(t, domain.PaymentCreatedEvent{
+				CommandEvent:               command(domain.TopicPaymentCreated, "1"),
+				PaymentCreatedEventPayload: domain.PaymentCreatedEventPayload{PaymentID: "p-1", WalletID: "w-1", Amount: usd, WalletAmount: usd},
+			}),
+		},
+		{
+			name: "refund answer",
+			msg: marshal(t, domain.RefundCommandEvent{
+				CommandEvent:              command(domain.TopicGatewayRefunded, "1"),
+				RefundCommandEventPayload: domain.RefundCommandEventPayload{RefundID: "r-1", PaymentID: "p-1", Amount: usd},
+			}),
+		},
+		{
+			name: "gateway answer",
+			msg: marshal(t, domain.GatewayAuthorizationFailedEvent{
+				CommandEvent: command(domain.TopicGatewayAuthorizationFailed, "1"),
+				PaymentID:    "p-1",
+				Reason:       "declined",
+			}),
+		},
+		{
+			name: "saga timeout",
+			msg: marshal(t, domain.SagaTimedOutEvent{
+				CommandEvent:             command(domain.SagaTimedOutEventType, "1"),
+				SagaTimedOutEventPayload: domain.SagaTimedOutEventPayload{Saga: "payment", SagaID: "p-1", Step: "AUTHORIZING", Deadline: time.Now()},
+			}),
+		},
+		{
+			name: "void without amount",
+			msg: marshal(t, domain.WalletCommandEvent{
+				CommandEvent:              command(domain.VoidAuthorizationEventType, domain.WalletCommandEventVersion),
+				WalletCommandEventPayload: domain.WalletCommandEventPayload{PaymentID: "p-1", WalletID: "w-1", Method: "card"},
+			}),
+		},
+		{
+			name: "wallet command without amount",
+			msg: marshal(t, domain.WalletCommandEvent{
+				CommandEvent:              command(domain.DebitFundsEventType, domain.WalletCommandEventVersion),
+				WalletCommandEventPayload: domain.WalletCommandEventPayload{PaymentID: "p-1", WalletID: "w-1"},
+			}),
+			expectedError: ErrInvalidEvent,
+		},
+		{
+			name: "amount without currency",
+			msg: []byte(`{"event_type": "gateway.authorized", "event_version": "1", "payment_id": "p-1",
+				"amount": {"amount": "0", "currency": ""}}`),
+			expectedError: ErrInvalidEvent,
+		},
+		{
+			name: "missing required field",
+			msg: marshal(t, domain.PaymentUpdateStatusEvent{
+				CommandEvent:                    command(domain.PaymentUpdateStatusEventType, "1"),
+				PaymentUpdateStatusEventPayload: domain.PaymentUpdateStatusEventPayload{Status: domain.PaymentStatusFailed},
+			}),
+			expectedError: ErrInvalidEvent,
+		},
+		{
+			name: "version does not match the payload",
+			msg: marshal(t, domain.WalletCommandEvent{
+				CommandEvent:              command(domain.HoldFundsEventType, "1"),
+				WalletCommandEventPayload: domain.WalletCommandEventPayload{PaymentID: "p-1", Amount: usd},
+			}),
+			expectedError: ErrInvalidEvent,
+		},
+		{
+			name:          "unknown version",
+			msg:           marshal(t, domain.NotifyUserEvent{CommandEvent: command(domain.NotifyUserEventType, "7")}),
+			expectedError: ErrUnknownSchema,
+		},
+		{
+			name:          "not json",
+			msg:           []byte("not json"),
+			expectedError: ErrInvalidEvent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := registry.Validate(tt.msg)
+
+			if tt.expectedError == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.expectedError)
+		})
+	}
+}
+
+func TestRegistry_UpcastsWalletCommandV1(t *testing.T) {
+	registry := newRegistry(t)
+	v1 := []byte(`{
+		"event_type": "hold_funds",
+		"event_version": "1",
+		"metadata": {"message_deduplication_id": "hold_funds.p-1"},
+		"payload": {"wallet_id": "w-1", "payment_id": "p-1", "amount": 12.3, "currency": "USD", "token": ""}
+	}`)
+
+	upcasted, err := registry.Upcast(v1)
+	require.NoError(t, err)
+
+	var event domain.WalletCommandEvent
+	require.NoError(t, json.Unmarshal(upcasted, &event))
+	assert.Equal(t, domain.WalletCommandEventVersion, event.EventVersion)
+	assert.Equal(t, "hold_funds.p-1", event.MessageDeduplicationId)
+	assert.Equal(t, domain.NewMoney(12_30, "USD"), event.Amount)
+
+	var raw struct {
+		Payload map[string]any `json:"payload"`
+	}
+	require.NoError(t, json.Unmarshal(upcasted, &raw))
+	assert.NotContains(t, raw.Payload, "currency")
+}
+
+func TestRegistry_UpcastsWalletCommandV1WithExponent(t *testing.T) {
+	registry := newRegistry(t)
+	v1 := []byte(`{"event_type": "debit_funds", "event_version": "1", "payload": {"payment_id": "p-1", "amount": 1.23e2, "currency": "USD"}}`)
+
+	upcasted, err := registry.Upcast(v1)
+	require.NoError(t, err)
+
+	var event domain.WalletCommandEvent
+	require.NoError(t, json.Unmarshal(upcasted, &event))
+	assert.Equal(t, domain.NewMoney(123_00, "USD"), event.Amount)
+}
+
+func TestRegistry_UpcastKeepsLatestVersion(t *testing.T) {
+	registry := newRegistry(t)
+	v2 := marshal(t, domain.WalletCommandEvent{
+		CommandEvent:              command(domain.DebitFundsEventType, domain.WalletCommandEventVersion),
+		WalletCommandEventPayload: domain.WalletCommandEventPayload{PaymentID: "p-1", Amount: domain.NewMoney(5_00, "EUR")},
+	})
+
+	upcasted, err := registry.Upcast(v2)
+
+	require.NoError(t, err)
+	assert.Equal(t, v2, upcasted)
+}
+
+func TestRegistry_UpcastRejectsAmountTheCurrencyCannotHold(t *testing.T) {
+	registry := newRegistry(t)
+	v1 := []byte(`{"event_type": "debit_funds", "event_version": "1", "payload": {"payment_id": "p-1", "amount": 12.345, "currency": "USD"}}`)
+
+	_, err := registry.Upcast(v1)
+
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+}
+
+// TestRegistry_ValidatesEveryMetric reads the metric constants from the
+// domain source, so a metric added there without a schema fails here.
+func TestRegistry_ValidatesEveryMetric(t *testing.T) {
+	registry := newRegistry(t)
+
+	file, err := parser.ParseFile(token.NewFileSet(), "../../domain/metrics.go", nil, 0)
+	require.NoError(t, err)
+
+	var metrics []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok {
+			return true
+		}
+		for i, name := range spec.Names {
+			if !strings.HasPrefix(name.Name, "Metric") || strings.HasPrefix(name.Name, "MetricLabel") {
+				continue
+			}
+			lit, ok := spec.Values[i].(*ast.BasicLit)
+			require.True(t, ok, name.Name)
+			metric, err := strconv.Unquote(lit.Value)
+			require.NoError(t, err)
+			metrics = append(metrics, metric)
+		}
+		return true
+	})
+	require.NotEmpty(t, metrics)
+
+	for _, metric := range metrics {
+		t.Run(metric, func(t *testing.T) {
+			event := domain.NewMetricEvent(metric, "p-1", map[string]string{domain.MetricLabelReason: "declined"}, 1)
+			assert.NoError(t, registry.Validate(marshal(t, event)))
+		})
+	}
+}